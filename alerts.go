@@ -0,0 +1,638 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"current-weather-server/data"
+	"current-weather-server/logging"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// The secret used to sign webhooks for rules registered without their own secret
+var alertWebhookSecret = ""
+
+// How many times a webhook is attempted before it's given up on
+var alertMaxAttempts = 5
+
+// The most alert rules that can be registered at the same time.  Each one keeps its location watched.
+var alertMaxRules = 100
+
+// The token operators send as "Authorization: Bearer <token>" to use the alert endpoints (empty=none)
+var alertToken = ""
+
+// The hosts webhooks can be sent to (empty=any host)
+var alertWebhookHosts = map[string]bool{}
+
+// Whether webhooks can be sent to loopback, link-local, and private addresses
+var alertAllowPrivateWebhooks = false
+
+const (
+	// The delay before the first retry.  It doubles after every failed attempt.
+	alertRetryDelay = 2 * time.Second
+	// The number of webhook deliveries kept in the delivery log
+	alertDeliveryLogSize = 500
+	// The largest alert rule accepted
+	maxAlertRuleBytes = 16 * 1024
+	// The header holding the hex HMAC-SHA256 of the webhook body
+	alertSignatureHeader = "X-Weather-Signature"
+)
+
+var webhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	// Redirects aren't followed so a webhook can't be sent on to a host that isn't allowed
+	CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	Transport:     &http.Transport{DialContext: (&net.Dialer{Timeout: 10 * time.Second, Control: webhookDialControl}).DialContext},
+}
+
+// The fields an alert rule can be based on.  The names match the
+// json names in SimplifiedWeather where the field exists there.
+var alertFields = map[string]func(*data.CurrentWeatherData) float64{
+	"temp":              func(d *data.CurrentWeatherData) float64 { return d.Main.Temp },
+	"tempFeelsLike":     func(d *data.CurrentWeatherData) float64 { return d.Main.FeelsLike },
+	"humidityPercent":   func(d *data.CurrentWeatherData) float64 { return d.Main.Humidity },
+	"cloudinessPercent": func(d *data.CurrentWeatherData) float64 { return d.Clouds.All },
+	"pressure":          func(d *data.CurrentWeatherData) float64 { return d.Main.Pressure },
+	"windSpeed":         func(d *data.CurrentWeatherData) float64 { return d.Wind.Speed },
+	"windGust":          func(d *data.CurrentWeatherData) float64 { return d.Wind.Gust },
+	"rain1h":            func(d *data.CurrentWeatherData) float64 { return d.Rain.H },
+}
+
+// alertFieldUnit returns the unit an alert field is evaluated in
+func alertFieldUnit(field string, units data.UnitSystem) string {
+	switch field {
+	case "temp", "tempFeelsLike":
+		return string(units.Temperature)
+	case "humidityPercent", "cloudinessPercent":
+		return "%"
+	case "pressure":
+		return string(units.Pressure)
+	case "windSpeed", "windGust":
+		return string(units.Speed)
+	case "rain1h":
+		return string(units.Precipitation)
+	}
+	return ""
+}
+
+// alertRule is registered through /api/alerts.  The rule is active while
+// "field comparator threshold" is true.  The threshold is Value or, for
+// temperature fields, the top of the subjective temperature band named by SubjectiveTemp
+// in the rule's comfort profile (comfortProfile or coldCoolWarm).
+// Once active, the rule isn't cleared until the field has moved back past the
+// threshold by more than Hysteresis, so readings hovering around the threshold
+// don't cause a stream of webhooks.
+type alertRule struct {
+	Id        string   `json:"id"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	queryOptions
+	Field          string   `json:"field"`
+	Comparator     string   `json:"comparator"`
+	Value          *float64 `json:"value,omitempty"`
+	SubjectiveTemp string   `json:"subjectiveTemp,omitempty"`
+	Hysteresis     float64  `json:"hysteresis"`
+	WebhookUrl     string   `json:"webhookUrl,omitempty"` // only listed for operators
+	Secret         string   `json:"secret,omitempty"`
+
+	// Maintained by the server
+	UnitSystem    data.UnitSystem `json:"unitSystem"`
+	Threshold     float64         `json:"threshold"`
+	Active        bool            `json:"active"`
+	LastValue     *float64        `json:"lastValue,omitempty"`
+	LastEvaluated string          `json:"lastEvaluated,omitempty"`
+
+	query weatherQuery // the validated location and options
+	stop  chan struct{}
+}
+
+// alertEvent is the body of a webhook
+type alertEvent struct {
+	RuleId             string                  `json:"ruleId"`
+	Event              string                  `json:"event"` // triggered or cleared
+	Field              string                  `json:"field"`
+	Comparator         string                  `json:"comparator"`
+	Threshold          float64                 `json:"threshold"`
+	Value              float64                 `json:"value"`
+	Units              string                  `json:"units"`
+	Unit               string                  `json:"unit"` // the unit of threshold and value
+	DataCollectionTime string                  `json:"dataCollectionTime"`
+	Weather            *data.SimplifiedWeather `json:"weather"`
+}
+
+// alertDelivery is one attempt to deliver a webhook
+type alertDelivery struct {
+	RuleId     string `json:"ruleId"`
+	Event      string `json:"event"`
+	WebhookUrl string `json:"webhookUrl,omitempty"` // only listed for operators
+	Attempt    int    `json:"attempt"`
+	Time       string `json:"time"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Delivered  bool   `json:"delivered"`
+}
+
+type alertManager struct {
+	mutex      sync.Mutex
+	rules      map[string]*alertRule
+	nextId     uint64
+	deliveries []alertDelivery
+}
+
+var alerts = &alertManager{rules: map[string]*alertRule{}}
+
+// validateAlertRule checks a newly registered rule and works out its threshold
+func validateAlertRule(rule *alertRule) error {
+	if rule.Longitude == nil {
+		return errors.New("missing longitude")
+	}
+
+	if rule.Latitude == nil {
+		return errors.New("missing latitude")
+	}
+
+	query, err, _ := newWeatherQuery(*rule.Latitude, *rule.Longitude, rule.queryOptions)
+
+	if err != nil {
+		return err
+	}
+
+	if rule.Units == "" {
+		rule.Units = "metric"
+	}
+
+	rule.UnitSystem = query.Units
+	rule.query = *query
+
+	if _, found := alertFields[rule.Field]; !found {
+		fields := make([]string, 0, len(alertFields))
+		for field := range alertFields {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		return fmt.Errorf("Invalid field: %v (valid fields are %v)", rule.Field, fields)
+	}
+
+	switch rule.Comparator {
+	case "<", "<=", ">", ">=":
+	default:
+		return fmt.Errorf("Invalid comparator: %v (must be <, <=, >, or >=)", rule.Comparator)
+	}
+
+	if rule.SubjectiveTemp != "" {
+		if rule.Value != nil {
+			return errors.New("Only one of value and subjectiveTemp can be given")
+		}
+
+		if rule.Field != "temp" && rule.Field != "tempFeelsLike" {
+			return fmt.Errorf("subjectiveTemp can only be used with temperature fields, not %v", rule.Field)
+		}
+
+		rule.Threshold, err = rule.query.Comfort.Threshold(rule.UnitSystem.Temperature, rule.SubjectiveTemp)
+
+		if err != nil {
+			return err
+		}
+	} else if rule.Value != nil {
+		rule.Threshold = *rule.Value
+	} else {
+		return errors.New("missing value")
+	}
+
+	if rule.Hysteresis < 0 {
+		return fmt.Errorf("Invalid hysteresis value: %v", rule.Hysteresis)
+	}
+
+	return validateWebhookUrl(rule.WebhookUrl)
+}
+
+// validateWebhookUrl checks a webhook goes to an http or https url on one of the -alertWebhookHosts.
+// Loopback, link-local, and private addresses are refused unless -alertAllowPrivateWebhooks.  Host
+// names are checked against the addresses they resolve to when the webhook is sent (see webhookDialControl).
+func validateWebhookUrl(str string) error {
+	webhookUrl, err := url.Parse(str)
+
+	if err != nil || (webhookUrl.Scheme != "http" && webhookUrl.Scheme != "https") || webhookUrl.Hostname() == "" {
+		return fmt.Errorf("Invalid webhookUrl: %v", str)
+	}
+
+	host := strings.ToLower(webhookUrl.Hostname())
+
+	if len(alertWebhookHosts) > 0 && !alertWebhookHosts[host] {
+		return fmt.Errorf("Webhooks can't be sent to %v (it isn't one of the -alertWebhookHosts)", host)
+	}
+
+	if alertAllowPrivateWebhooks {
+		return nil
+	}
+
+	if ip := net.ParseIP(host); host == "localhost" || strings.HasSuffix(host, ".localhost") || (ip != nil && isPrivateAddress(ip)) {
+		return fmt.Errorf("Webhooks can't be sent to the loopback, link-local, or private address %v", host)
+	}
+
+	return nil
+}
+
+// isPrivateAddress is true for the addresses webhooks aren't sent to by default
+func isPrivateAddress(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast()
+}
+
+// webhookDialControl refuses to connect to a private address unless -alertAllowPrivateWebhooks,
+// whatever host name the webhook used
+func webhookDialControl(network, address string, conn syscall.RawConn) error {
+	if alertAllowPrivateWebhooks {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip == nil || isPrivateAddress(ip) {
+		return fmt.Errorf("Webhooks can't be sent to the loopback, link-local, or private address %v", host)
+	}
+
+	return nil
+}
+
+// add registers a rule and starts evaluating it each time its location is refreshed.
+// It returns a copy of the registered rule without its secret.
+func (manager *alertManager) add(rule *alertRule) (alertRule, error) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	if len(manager.rules) >= alertMaxRules {
+		return alertRule{}, fmt.Errorf("Too many alert rules (the maximum is %v)", alertMaxRules)
+	}
+
+	manager.nextId++
+	rule.Id = fmt.Sprintf("alert-%v", manager.nextId)
+	rule.Active = false
+	rule.LastValue = nil
+	rule.LastEvaluated = ""
+	rule.stop = make(chan struct{})
+	manager.rules[rule.Id] = rule
+
+	updates, unsubscribe := weatherWatchers.subscribe(rule.query)
+	go manager.evaluateUpdates(rule, updates, unsubscribe)
+
+	ruleCopy := *rule
+	ruleCopy.Secret = ""
+	return ruleCopy, nil
+}
+
+func (manager *alertManager) remove(id string) bool {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	rule, found := manager.rules[id]
+
+	if found {
+		delete(manager.rules, id)
+		close(rule.stop)
+	}
+
+	return found
+}
+
+// list returns copies of the rules without their secrets and, unless showWebhooks, their webhook urls
+func (manager *alertManager) list(showWebhooks bool) []alertRule {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	rules := make([]alertRule, 0, len(manager.rules))
+
+	for _, rule := range manager.rules {
+		ruleCopy := *rule
+		ruleCopy.Secret = ""
+
+		if !showWebhooks {
+			ruleCopy.WebhookUrl = ""
+		}
+
+		rules = append(rules, ruleCopy)
+	}
+
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Id < rules[j].Id })
+	return rules
+}
+
+func (manager *alertManager) evaluateUpdates(rule *alertRule, updates <-chan *weatherUpdate, unsubscribe func()) {
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-rule.stop:
+			return
+		case update := <-updates:
+			if update.Err == nil {
+				manager.evaluate(rule, update)
+			}
+		}
+	}
+}
+
+// evaluate updates the rule's state and sends a webhook when it becomes active or is cleared
+func (manager *alertManager) evaluate(rule *alertRule, update *weatherUpdate) {
+	value := alertFields[rule.Field](update.Data)
+
+	manager.mutex.Lock()
+	rule.LastValue = &value
+	rule.LastEvaluated = update.Data.DataCollectionTime
+
+	event := ""
+
+	if !rule.Active && alertConditionMet(rule.Comparator, value, rule.Threshold) {
+		rule.Active = true
+		event = "triggered"
+	} else if rule.Active && alertConditionCleared(rule.Comparator, value, rule.Threshold, rule.Hysteresis) {
+		rule.Active = false
+		event = "cleared"
+	}
+
+	ruleCopy := *rule
+	manager.mutex.Unlock()
+
+	if event == "" {
+		return
+	}
+
+	logging.LogInfo(0, fmt.Sprintf("Alert %v %v: %v=%v (%v %v)", rule.Id, event, rule.Field, value,
+		rule.Comparator, rule.Threshold))
+
+	go manager.deliver(&ruleCopy, &alertEvent{
+		RuleId:             rule.Id,
+		Event:              event,
+		Field:              rule.Field,
+		Comparator:         rule.Comparator,
+		Threshold:          rule.Threshold,
+		Value:              value,
+		Units:              rule.Units,
+		Unit:               alertFieldUnit(rule.Field, rule.UnitSystem),
+		DataCollectionTime: update.Data.DataCollectionTime,
+		Weather:            update.Simplified,
+	})
+}
+
+func alertConditionMet(comparator string, value, threshold float64) bool {
+	switch comparator {
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	}
+	return false
+}
+
+// alertConditionCleared is true once the value is back on the other side of
+// the threshold by more than the hysteresis
+func alertConditionCleared(comparator string, value, threshold, hysteresis float64) bool {
+	switch comparator {
+	case "<", "<=":
+		return value > threshold+hysteresis
+	case ">", ">=":
+		return value < threshold-hysteresis
+	}
+	return false
+}
+
+// deliver POSTs the event to the rule's webhook, retrying with an increasing delay
+func (manager *alertManager) deliver(rule *alertRule, event *alertEvent) {
+	body, err := json.Marshal(event)
+
+	if err != nil {
+		logging.LogError(0, fmt.Sprintf("Error marshalling alert %v: %v", rule.Id, err))
+		return
+	}
+
+	secret := rule.Secret
+
+	if secret == "" {
+		secret = alertWebhookSecret
+	}
+
+	delay := alertRetryDelay
+
+	for attempt := 1; attempt <= alertMaxAttempts; attempt++ {
+		delivery := alertDelivery{
+			RuleId:     rule.Id,
+			Event:      event.Event,
+			WebhookUrl: rule.WebhookUrl,
+			Attempt:    attempt,
+			Time:       time.Now().UTC().String(),
+		}
+
+		delivery.StatusCode, err = postWebhook(rule.WebhookUrl, body, secret)
+
+		if err != nil {
+			delivery.Error = err.Error()
+		} else {
+			delivery.Delivered = true
+		}
+
+		manager.logDelivery(delivery)
+
+		if delivery.Delivered {
+			logging.LogInfo(0, fmt.Sprintf("Delivered alert %v (%v) to %v", rule.Id, event.Event, rule.WebhookUrl))
+			return
+		}
+
+		logging.LogWarn(0, fmt.Sprintf("Attempt %v to deliver alert %v to %v failed: %v",
+			attempt, rule.Id, rule.WebhookUrl, err))
+
+		if attempt < alertMaxAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+
+	logging.LogError(0, fmt.Sprintf("Giving up delivering alert %v (%v) to %v", rule.Id, event.Event, rule.WebhookUrl))
+}
+
+// postWebhook sends the body with its HMAC-SHA256 signature.  Any 2xx status is a success.
+func postWebhook(webhookUrl string, body []byte, secret string) (int, error) {
+	request, err := http.NewRequest(http.MethodPost, webhookUrl, bytes.NewReader(body))
+
+	if err != nil {
+		return 0, err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		request.Header.Set(alertSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	response, err := webhookClient.Do(request)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer response.Body.Close()
+	io.Copy(io.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("Bad status code: %v", response.Status)
+	}
+
+	return response.StatusCode, nil
+}
+
+func (manager *alertManager) logDelivery(delivery alertDelivery) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	manager.deliveries = append(manager.deliveries, delivery)
+
+	if len(manager.deliveries) > alertDeliveryLogSize {
+		manager.deliveries = manager.deliveries[len(manager.deliveries)-alertDeliveryLogSize:]
+	}
+}
+
+// deliveryLog returns the logged deliveries for a rule (or all rules), most recent first.
+// The webhook urls are left out unless showWebhooks.
+func (manager *alertManager) deliveryLog(ruleId string, showWebhooks bool) []alertDelivery {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	deliveries := []alertDelivery{}
+
+	for inx := len(manager.deliveries) - 1; inx >= 0; inx-- {
+		if ruleId == "" || manager.deliveries[inx].RuleId == ruleId {
+			delivery := manager.deliveries[inx]
+
+			if !showWebhooks {
+				delivery.WebhookUrl = ""
+			}
+
+			deliveries = append(deliveries, delivery)
+		}
+	}
+
+	return deliveries
+}
+
+// isAlertOperator is true when the request has the -alertToken
+func isAlertOperator(request *http.Request) bool {
+	return alertToken != "" &&
+		subtle.ConstantTimeCompare([]byte(request.Header.Get("Authorization")), []byte("Bearer "+alertToken)) == 1
+}
+
+// authorizeAlerts writes an error and returns false when the request can't use the alert endpoints.
+// When there's an -alertToken every request needs it.  Otherwise rules can only be registered when
+// the webhooks are limited to the -alertWebhookHosts.
+func authorizeAlerts(requestNum uint64, writer http.ResponseWriter, request *http.Request) bool {
+	if alertToken != "" && !isAlertOperator(request) {
+		msg := "Missing or invalid alert token"
+		logging.LogHTTPError(requestNum, msg, http.StatusUnauthorized)
+		writer.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(writer, msg, http.StatusUnauthorized)
+		return false
+	}
+
+	if alertToken == "" && len(alertWebhookHosts) == 0 && request.Method == http.MethodPost {
+		msg := "Alert rules can't be registered unless the server is started with -alertToken or -alertWebhookHosts"
+		logging.LogHTTPError(requestNum, msg, http.StatusForbidden)
+		http.Error(writer, msg, http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+// apiAlerts lists (GET), registers (POST) and removes (DELETE with an id parameter) alert rules
+func apiAlerts(requestNum uint64, writer http.ResponseWriter, request *http.Request) {
+	if !authorizeAlerts(requestNum, writer, request) {
+		return
+	}
+
+	switch request.Method {
+	case http.MethodGet:
+		writeJson(requestNum, writer, alerts.list(isAlertOperator(request)), http.StatusOK)
+	case http.MethodPost:
+		body, err := io.ReadAll(io.LimitReader(request.Body, maxAlertRuleBytes))
+
+		if err != nil {
+			msg := fmt.Sprintf("Error reading request body: %v", err)
+			logging.LogHTTPError(requestNum, msg, http.StatusBadRequest)
+			http.Error(writer, msg, http.StatusBadRequest)
+			return
+		}
+
+		rule := &alertRule{}
+
+		if err := json.Unmarshal(body, rule); err != nil {
+			msg := fmt.Sprintf("Invalid alert rule: %v", err)
+			logging.LogHTTPError(requestNum, msg, http.StatusBadRequest)
+			http.Error(writer, msg, http.StatusBadRequest)
+			return
+		}
+
+		if err := validateAlertRule(rule); err != nil {
+			logging.LogHTTPError(requestNum, err.Error(), http.StatusBadRequest)
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		registered, err := alerts.add(rule)
+
+		if err != nil {
+			logging.LogHTTPError(requestNum, err.Error(), http.StatusTooManyRequests)
+			http.Error(writer, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+
+		logging.LogInfo(requestNum, fmt.Sprintf("Registered alert %v", registered.Id))
+		writeJson(requestNum, writer, registered, http.StatusCreated)
+	case http.MethodDelete:
+		id := alertIdParam.value(request.URL.Query())
+
+		if !alerts.remove(id) {
+			msg := fmt.Sprintf("No such alert: %v", id)
+			logging.LogHTTPError(requestNum, msg, http.StatusNotFound)
+			http.Error(writer, msg, http.StatusNotFound)
+			return
+		}
+
+		logging.LogInfo(requestNum, fmt.Sprintf("Removed alert %v", id))
+		writer.WriteHeader(http.StatusNoContent)
+	default:
+		msg := fmt.Sprintf("Unsupported method: %v", request.Method)
+		logging.LogHTTPError(requestNum, msg, http.StatusMethodNotAllowed)
+		http.Error(writer, msg, http.StatusMethodNotAllowed)
+	}
+}
+
+// apiAlertDeliveries returns the webhook delivery log, optionally for one rule (ruleId parameter)
+func apiAlertDeliveries(requestNum uint64, writer http.ResponseWriter, request *http.Request) {
+	if !authorizeAlerts(requestNum, writer, request) {
+		return
+	}
+
+	writeJson(requestNum, writer, alerts.deliveryLog(alertRuleIdParam.value(request.URL.Query()), isAlertOperator(request)),
+		http.StatusOK)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidateWebhookUrl(t *testing.T) {
+	defer func() { alertWebhookHosts, alertAllowPrivateWebhooks = map[string]bool{}, false }()
+
+	tests := []struct {
+		url          string
+		hosts        map[string]bool
+		allowPrivate bool
+		valid        bool
+	}{
+		{"https://example.com/hook", nil, false, true},
+		{"ftp://example.com/hook", nil, false, false},
+		{"https:///hook", nil, false, false},
+		{"http://127.0.0.1:8000/hook", nil, false, false},
+		{"http://localhost/hook", nil, false, false},
+		{"http://10.1.2.3/hook", nil, false, false},
+		{"http://192.168.0.1/hook", nil, false, false},
+		{"http://169.254.169.254/latest/meta-data", nil, false, false},
+		{"http://[::1]/hook", nil, false, false},
+		{"http://[fe80::1]/hook", nil, false, false},
+		{"http://10.1.2.3/hook", nil, true, true},
+		{"https://hooks.example.com/hook", map[string]bool{"hooks.example.com": true}, false, true},
+		{"https://HOOKS.example.com/hook", map[string]bool{"hooks.example.com": true}, false, true},
+		{"https://example.com/hook", map[string]bool{"hooks.example.com": true}, false, false},
+	}
+
+	for _, test := range tests {
+		alertWebhookHosts, alertAllowPrivateWebhooks = test.hosts, test.allowPrivate
+
+		if err := validateWebhookUrl(test.url); (err == nil) != test.valid {
+			t.Errorf("validateWebhookUrl(%v) with hosts %v and allowPrivate %v = %v, want valid %v",
+				test.url, test.hosts, test.allowPrivate, err, test.valid)
+		}
+	}
+}
+
+// A webhook host name can resolve to a private address so the address is checked when connecting
+func TestWebhookRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+
+	if _, err := postWebhook(server.URL, []byte("{}"), ""); err == nil || !strings.Contains(err.Error(), "private address") {
+		t.Errorf("posting to %v = %v, want a private address error", server.URL, err)
+	}
+
+	alertAllowPrivateWebhooks = true
+	defer func() { alertAllowPrivateWebhooks = false }()
+
+	if _, err := postWebhook(server.URL, []byte("{}"), ""); err != nil {
+		t.Errorf("posting to %v with private webhooks allowed = %v", server.URL, err)
+	}
+}
+
+func TestAlertsAuthorization(t *testing.T) {
+	rules := alertMaxRules
+	alertMaxRules = 1
+
+	defer func() {
+		alertMaxRules, alertToken, alertWebhookHosts = rules, "", map[string]bool{}
+
+		for _, rule := range alerts.list(true) {
+			alerts.remove(rule.Id)
+		}
+	}()
+
+	rule := `{"latitude": 48.86, "longitude": 2.35, "field": "temp", "comparator": "<", "value": 0,
+		"webhookUrl": "https://hooks.example.com/hook"}`
+
+	// do sends a request to /api/alerts and returns the status and body
+	do := func(method, token, body string) (int, string) {
+		request := httptest.NewRequest(method, "/api/alerts", strings.NewReader(body))
+
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		recorder := httptest.NewRecorder()
+		apiAlerts(1, recorder, request)
+		return recorder.Code, recorder.Body.String()
+	}
+
+	tests := []struct {
+		name         string
+		token        string   // the -alertToken
+		hosts        []string // the -alertWebhookHosts
+		method       string
+		requestToken string
+		body         string
+		wantStatus   int
+		wantWebhook  bool // whether the response has the webhook url
+	}{
+		{"no token or hosts", "", nil, http.MethodPost, "", rule, http.StatusForbidden, false},
+		{"allowed host", "", []string{"hooks.example.com"}, http.MethodPost, "", rule, http.StatusCreated, true},
+		{"too many rules", "", []string{"hooks.example.com"}, http.MethodPost, "", rule, http.StatusTooManyRequests, false},
+		{"list without token", "", []string{"hooks.example.com"}, http.MethodGet, "", "", http.StatusOK, false},
+		{"missing token", "secret", nil, http.MethodGet, "", "", http.StatusUnauthorized, false},
+		{"wrong token", "secret", nil, http.MethodGet, "guess", "", http.StatusUnauthorized, false},
+		{"list with token", "secret", nil, http.MethodGet, "secret", "", http.StatusOK, true},
+	}
+
+	for _, test := range tests {
+		alertToken, alertWebhookHosts = test.token, map[string]bool{}
+
+		for _, host := range test.hosts {
+			alertWebhookHosts[host] = true
+		}
+
+		status, body := do(test.method, test.requestToken, test.body)
+
+		if status != test.wantStatus {
+			t.Errorf("%v: status = %v (%v), want %v", test.name, status, body, test.wantStatus)
+		}
+
+		if hasWebhook := strings.Contains(body, "hooks.example.com"); hasWebhook != test.wantWebhook {
+			t.Errorf("%v: response %v has the webhook url: %v, want %v", test.name, body, hasWebhook, test.wantWebhook)
+		}
+	}
+}
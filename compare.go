@@ -0,0 +1,304 @@
+package main
+
+import (
+	"current-weather-server/data"
+	"current-weather-server/logging"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The most locations that can be compared in one request
+var maxCompareLocations = 10
+
+// comparedLocation is one location of a comparison.  Deltas are the difference
+// from the first location that has weather (the baseline), so the baseline's are 0.
+type comparedLocation struct {
+	Name      string                  `json:"name,omitempty"`
+	Latitude  float64                 `json:"latitude"`
+	Longitude float64                 `json:"longitude"`
+	Weather   *data.SimplifiedWeather `json:"weather,omitempty"`
+	Deltas    map[string]float64      `json:"deltas,omitempty"`
+	Error     string                  `json:"error,omitempty"`
+}
+
+// comparison is returned by /api/compare.  Each ranking lists the positions of the
+// locations (in Locations) from first to last.  Locations without weather aren't ranked.
+type comparison struct {
+	Baseline  int                `json:"baseline"` // the position of the baseline, -1 when no location has weather
+	Locations []comparedLocation `json:"locations"`
+	Rankings  map[string][]int   `json:"rankings"`
+}
+
+// The fields that get deltas
+var compareFields = []struct {
+	Name  string
+	Value func(*data.SimplifiedWeather) float64
+}{
+	{"temp", func(w *data.SimplifiedWeather) float64 { return w.Temp }},
+	{"tempFeelsLike", func(w *data.SimplifiedWeather) float64 { return w.TempFeelsLike }},
+	{"humidityPercent", func(w *data.SimplifiedWeather) float64 { return w.HumidityPercent }},
+	{"cloudinessPercent", func(w *data.SimplifiedWeather) float64 { return w.CloudinessPercent }},
+	{"windSpeed", func(w *data.SimplifiedWeather) float64 { return w.WindSpeed }},
+	{"pressure", func(w *data.SimplifiedWeather) float64 { return w.Pressure }},
+	{"visibility", func(w *data.SimplifiedWeather) float64 { return w.Visibility }},
+	{"rain1h", func(w *data.SimplifiedWeather) float64 { return w.Rain1h }},
+}
+
+// The rankings and the value the locations are sorted by, highest first
+var compareRankings = []struct {
+	Name  string
+	Value func(*data.SimplifiedWeather) float64
+}{
+	{"warmest", func(w *data.SimplifiedWeather) float64 { return w.Temp }},
+	{"driest", func(w *data.SimplifiedWeather) float64 { return -w.HumidityPercent }},
+	{"leastCloudy", func(w *data.SimplifiedWeather) float64 { return -w.CloudinessPercent }},
+	{"calmest", func(w *data.SimplifiedWeather) float64 { return -w.WindSpeed }},
+}
+
+// parseCompareLocations parses a | separated list of latitude,longitude optionally preceded
+// by name= (e.g. "Paris=48.86,2.35|40.71,-74.01").  It's | rather than ; (as in -exporterLocations)
+// because url.ParseQuery drops query parameters with an unescaped ;.  Newlines also separate
+// locations so the comparison page can take one location per line.
+func parseCompareLocations(str string, options queryOptions) ([]comparedLocation, []*weatherQuery, error) {
+	locations := []comparedLocation{}
+	queries := []*weatherQuery{}
+
+	for _, part := range strings.FieldsFunc(str, func(r rune) bool { return r == '|' || r == '\n' }) {
+		part = strings.TrimSpace(part)
+
+		if part == "" {
+			continue
+		}
+
+		name, coordinates, found := strings.Cut(part, "=")
+
+		if !found {
+			name, coordinates = "", part
+		}
+
+		latitudeStr, longitudeStr, found := strings.Cut(coordinates, ",")
+
+		if !found {
+			return nil, nil, fmt.Errorf("Invalid location (must be [name=]latitude,longitude): %v", part)
+		}
+
+		latitude, err := strconv.ParseFloat(strings.TrimSpace(latitudeStr), 64)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Invalid latitude value: %v", latitudeStr)
+		}
+
+		longitude, err := strconv.ParseFloat(strings.TrimSpace(longitudeStr), 64)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Invalid longitude value: %v", longitudeStr)
+		}
+
+		query, err, _ := newWeatherQuery(latitude, longitude, options)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		locations = append(locations, comparedLocation{Name: strings.TrimSpace(name), Latitude: latitude, Longitude: longitude})
+		queries = append(queries, query)
+	}
+
+	if len(locations) < 2 {
+		return nil, nil, errors.New("At least two locations are needed for a comparison")
+	}
+
+	if len(locations) > maxCompareLocations {
+		return nil, nil, fmt.Errorf("Too many locations: %v (the maximum is %v)", len(locations), maxCompareLocations)
+	}
+
+	return locations, queries, nil
+}
+
+// compareLocations fetches the weather at every location of the request and compares them.
+// The options are the same as /api/currentweather and apply to every location.
+func compareLocations(request *http.Request) (*comparison, error, int) {
+	queryValues := request.URL.Query()
+	options := queryOptionsFromQuery(queryValues)
+
+	if options.Lang == "" {
+		options.Lang = acceptLanguage(request.Header.Get("Accept-Language"))
+	}
+
+	locations, queries, err := parseCompareLocations(locationsParam.value(queryValues), options)
+
+	if err != nil {
+		return nil, err, http.StatusBadRequest
+	}
+
+	results := fetchCurrentWeatherConcurrently(queries)
+
+	for inx, result := range results {
+		if result.Err != nil {
+			locations[inx].Error = result.Err.Error()
+		} else {
+			locations[inx].Weather = result.Simplified
+		}
+	}
+
+	return newComparison(locations), nil, http.StatusOK
+}
+
+// newComparison works out the deltas and rankings of locations that have been fetched
+func newComparison(locations []comparedLocation) *comparison {
+	result := &comparison{Baseline: -1, Locations: locations, Rankings: map[string][]int{}}
+	ranked := []int{}
+
+	for inx, location := range locations {
+		if location.Weather != nil {
+			ranked = append(ranked, inx)
+		}
+	}
+
+	if len(ranked) == 0 {
+		return result
+	}
+
+	result.Baseline = ranked[0]
+	baseline := locations[result.Baseline].Weather
+
+	for _, inx := range ranked {
+		locations[inx].Deltas = map[string]float64{}
+
+		for _, field := range compareFields {
+			delta := field.Value(locations[inx].Weather) - field.Value(baseline)
+			locations[inx].Deltas[field.Name] = math.Round(delta*100) / 100
+		}
+	}
+
+	for _, ranking := range compareRankings {
+		order := append([]int{}, ranked...)
+		sort.SliceStable(order, func(i, j int) bool {
+			return ranking.Value(locations[order[i]].Weather) > ranking.Value(locations[order[j]].Weather)
+		})
+		result.Rankings[ranking.Name] = order
+	}
+
+	return result
+}
+
+// geoJSON returns the comparison as a feature for each location.  The properties of a location
+// with weather are its name, whether it's the baseline, its deltas, its position (from 1) in each
+// ranking, and its weather.  A location without weather has its name and error.
+func (result *comparison) geoJSON() *data.GeoJSONFeatureCollection {
+	collection := &data.GeoJSONFeatureCollection{}
+
+	for inx, location := range result.Locations {
+		properties := data.SelectedWeather{}
+
+		if location.Name != "" {
+			properties = append(properties, data.SelectedField{Name: "name", Value: location.Name})
+		}
+
+		if location.Weather == nil {
+			properties = append(properties, data.SelectedField{Name: "error", Value: location.Error})
+			collection.Features = append(collection.Features,
+				data.NewPointFeature(location.Latitude, location.Longitude, properties))
+			continue
+		}
+
+		ranks := map[string]int{}
+
+		for name, order := range result.Rankings {
+			for position, ranked := range order {
+				if ranked == inx {
+					ranks[name] = position + 1
+				}
+			}
+		}
+
+		properties = append(properties, data.SelectedField{Name: "baseline", Value: inx == result.Baseline},
+			data.SelectedField{Name: "deltas", Value: location.Deltas}, data.SelectedField{Name: "ranks", Value: ranks})
+		collection.Features = append(collection.Features, data.NewWeatherFeature(location.Weather, nil, properties...))
+	}
+
+	return collection
+}
+
+func apiCompare(requestNum uint64, writer http.ResponseWriter, request *http.Request) {
+	geoJSON, err, statusCode := wantsGeoJSON(request)
+
+	if err != nil {
+		logging.LogHTTPError(requestNum, err.Error(), statusCode)
+		http.Error(writer, err.Error(), statusCode)
+		return
+	}
+
+	result, err, statusCode := compareLocations(request)
+
+	if err != nil {
+		logging.LogHTTPError(requestNum, err.Error(), statusCode)
+		http.Error(writer, err.Error(), statusCode)
+		return
+	}
+
+	if geoJSON {
+		writeGeoJson(requestNum, writer, result.geoJSON(), http.StatusOK)
+		return
+	}
+
+	writeJson(requestNum, writer, result, http.StatusOK)
+}
+
+// comparePage is what the compare_weather page is rendered over
+type comparePage struct {
+	Locations  string // the locations as they were entered
+	Units      string
+	Comparison *comparison
+	Rankings   []compareRanking
+	Error      string
+}
+
+// compareRanking is a ranking with the locations' labels
+type compareRanking struct {
+	Name      string
+	Locations []string
+}
+
+// compareWeatherPage shows a form for entering locations and, once
+// they've been entered, the comparison of their weather.
+func compareWeatherPage(requestNum uint64, writer http.ResponseWriter, request *http.Request) {
+	templates := pageTemplates(writer, request)
+	queryValues := request.URL.Query()
+	page := comparePage{Locations: locationsParam.value(queryValues), Units: unitsParam.value(queryValues)}
+
+	if page.Locations != "" {
+		result, err, statusCode := compareLocations(request)
+
+		if err != nil {
+			logging.LogHTTPError(requestNum, err.Error(), statusCode)
+			page.Error = err.Error()
+		} else {
+			page.Comparison = result
+
+			for _, ranking := range compareRankings {
+				labels := []string{}
+				for _, inx := range result.Rankings[ranking.Name] {
+					labels = append(labels, result.Locations[inx].Label())
+				}
+				page.Rankings = append(page.Rankings, compareRanking{ranking.Name, labels})
+			}
+		}
+	}
+
+	templates.ExecuteTemplate(writer, "compare_weather", page)
+}
+
+// Label is the name of the location or its coordinates when it has no name
+func (location comparedLocation) Label() string {
+	if location.Name != "" {
+		return location.Name
+	}
+
+	return fmt.Sprintf("(%v, %v)", location.Latitude, location.Longitude)
+}
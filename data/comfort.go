@@ -0,0 +1,333 @@
+package data
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultComfortProfile is the profile set from -subjectiveTempScale or -coldCoolWarmF
+	DefaultComfortProfile = "default"
+	// CustomComfortProfile is the name reported for per-request coldCoolWarm temperatures
+	CustomComfortProfile = "custom"
+)
+
+// MaxTemperatureBands is the most bands a subjective temperature scale can have.
+// The bands are kept in an array so profiles (and weatherQuery) can be compared.
+const MaxTemperatureBands = 12
+
+// The leading scale items that pick the temperature the bands are compared with
+const (
+	airTemperatureBasis = "temp"
+	feelsLikeBasis      = "feelsLike"
+)
+
+var bandLabelPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// TemperatureBand is a labeled range of temperatures.  It covers the temperatures above
+// the previous band's MaxCelsius up to and including its own.  The top band's is +Inf.
+type TemperatureBand struct {
+	Label      string
+	MaxCelsius float64
+}
+
+// ComfortProfile is an ordered scale of subjective temperatures (e.g. freezing, cold,
+// chilly, mild, warm, hot, scorching).  FeelsLike compares the bands with the feels
+// like temperature instead of the air temperature.
+type ComfortProfile struct {
+	Name      string
+	FeelsLike bool
+	bandCount int
+	bands     [MaxTemperatureBands]TemperatureBand
+}
+
+// The named comfort profiles, including the default profile
+var comfortProfiles = map[string]ComfortProfile{
+	DefaultComfortProfile: mustColdCoolWarm(DefaultComfortProfile, 4.5, 15.5, 25),
+}
+
+func mustColdCoolWarm(name string, cold, cool, warm float64) ComfortProfile {
+	profile, err := NewComfortProfile(name, false, []TemperatureBand{
+		{"cold", cold}, {"cool", cool}, {"warm", warm}, {"hot", math.Inf(1)}})
+
+	if err != nil {
+		panic(err)
+	}
+
+	return profile
+}
+
+// NewComfortProfile checks that there are at least two bands with unique labels, that the
+// temperatures are increasing, and that only the last band is unbounded.
+func NewComfortProfile(name string, feelsLike bool, bands []TemperatureBand) (ComfortProfile, error) {
+	if len(bands) < 2 || len(bands) > MaxTemperatureBands {
+		return ComfortProfile{}, fmt.Errorf("A subjective temperature scale must have between 2 and %v bands, not %v",
+			MaxTemperatureBands, len(bands))
+	}
+
+	profile := ComfortProfile{Name: name, FeelsLike: feelsLike, bandCount: len(bands)}
+	labels := map[string]bool{}
+
+	for inx, band := range bands {
+		if !bandLabelPattern.MatchString(band.Label) || band.Label == airTemperatureBasis || band.Label == feelsLikeBasis {
+			return ComfortProfile{}, fmt.Errorf("Invalid subjective temperature label: %q (must start with a letter "+
+				"followed by letters, digits, _ or - and isn't temp or feelsLike)", band.Label)
+		}
+
+		if labels[band.Label] {
+			return ComfortProfile{}, fmt.Errorf("Duplicate subjective temperature label: %v", band.Label)
+		}
+
+		labels[band.Label] = true
+
+		if inx == len(bands)-1 {
+			band.MaxCelsius = math.Inf(1)
+		} else if math.IsInf(band.MaxCelsius, 0) || math.IsNaN(band.MaxCelsius) {
+			return ComfortProfile{}, fmt.Errorf("Only the last subjective temperature band (not %v) can be unbounded",
+				band.Label)
+		} else if inx > 0 && band.MaxCelsius <= bands[inx-1].MaxCelsius {
+			return ComfortProfile{}, fmt.Errorf("%v temp (%v) must be less than %v (%v)",
+				bands[inx-1].Label, bands[inx-1].MaxCelsius, band.Label, band.MaxCelsius)
+		}
+
+		profile.bands[inx] = band
+	}
+
+	return profile, nil
+}
+
+// Bands returns the bands from coldest to hottest
+func (profile ComfortProfile) Bands() []TemperatureBand {
+	return profile.bands[:profile.bandCount]
+}
+
+// ParseComfortTemperatures parses a subjective temperature scale.  Temperatures without
+// a unit (C, F, or K) are in defaultUnit.  The scale is either
+//
+//	cold,cool,warm[unit]                       e.g. 40,60,77F (cold, cool, warm, and hot)
+//	[feelsLike,]label:max[unit],...,label      e.g. feelsLike,freezing:0C,cold:5C,mild:18C,warm:25C,hot
+//
+// The first item of the second form may be feelsLike (or temp, the default) to compare
+// the bands with the feels like temperature.
+func ParseComfortTemperatures(name, str string, defaultUnit TemperatureUnit) (ComfortProfile, error) {
+	str = strings.TrimSpace(str)
+
+	if !strings.Contains(str, ":") {
+		return parseColdCoolWarm(name, str, defaultUnit)
+	}
+
+	items := strings.Split(str, ",")
+	feelsLike := false
+
+	switch strings.TrimSpace(items[0]) {
+	case feelsLikeBasis:
+		feelsLike, items = true, items[1:]
+	case airTemperatureBasis:
+		items = items[1:]
+	}
+
+	bands := make([]TemperatureBand, len(items))
+
+	for inx, item := range items {
+		label, maxStr, bounded := strings.Cut(strings.TrimSpace(item), ":")
+		bands[inx].Label = strings.TrimSpace(label)
+
+		if inx == len(items)-1 {
+			if bounded {
+				return ComfortProfile{}, fmt.Errorf("The last subjective temperature band (%v) can't have a maximum",
+					bands[inx].Label)
+			}
+			continue
+		}
+
+		if !bounded {
+			return ComfortProfile{}, fmt.Errorf("Missing maximum temperature for subjective temperature band: %v",
+				bands[inx].Label)
+		}
+
+		max, err := parseTemperature(maxStr, defaultUnit)
+
+		if err != nil {
+			return ComfortProfile{}, fmt.Errorf("Invalid maximum temperature for %v: %v", bands[inx].Label, maxStr)
+		}
+
+		bands[inx].MaxCelsius = max
+	}
+
+	return NewComfortProfile(name, feelsLike, bands)
+}
+
+// parseColdCoolWarm parses the "cold,cool,warm" temperatures followed by an optional unit (e.g. "40,60,77F")
+func parseColdCoolWarm(name, str string, defaultUnit TemperatureUnit) (ComfortProfile, error) {
+	unit := defaultUnit
+
+	if len(str) > 0 {
+		if parsedUnit, err := ParseTemperatureUnit(str[len(str)-1:]); err == nil {
+			unit = parsedUnit
+			str = strings.TrimSpace(str[:len(str)-1])
+		}
+	}
+
+	parts := strings.Split(str, ",")
+
+	if len(parts) != 3 {
+		return ComfortProfile{}, fmt.Errorf("Invalid cold,cool,warm temperatures: %v (must be three comma separated "+
+			"temperatures optionally followed by C, F, or K, or label:max,...,label bands)", str)
+	}
+
+	temps := make([]float64, len(parts))
+
+	for inx, part := range parts {
+		temp, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+
+		if err != nil {
+			return ComfortProfile{}, fmt.Errorf("Invalid cold,cool,warm temperature: %v", part)
+		}
+
+		temps[inx] = temp
+	}
+
+	// Checked before converting so the error has the temperatures as they were given
+	if !(temps[0] < temps[1] && temps[1] < temps[2]) {
+		return ComfortProfile{}, fmt.Errorf("Cold temp (%v) must be less than cool (%v).  Cool temp must be less than warm (%v).",
+			temps[0], temps[1], temps[2])
+	}
+
+	return NewComfortProfile(name, false, []TemperatureBand{{"cold", unit.ToCelsius(temps[0])},
+		{"cool", unit.ToCelsius(temps[1])}, {"warm", unit.ToCelsius(temps[2])}, {"hot", math.Inf(1)}})
+}
+
+// parseTemperature parses a number followed by an optional unit (e.g. "-5C") and returns it in celsius
+func parseTemperature(str string, defaultUnit TemperatureUnit) (float64, error) {
+	str = strings.TrimSpace(str)
+	unit := defaultUnit
+
+	if len(str) > 0 {
+		if parsedUnit, err := ParseTemperatureUnit(str[len(str)-1:]); err == nil {
+			unit = parsedUnit
+			str = str[:len(str)-1]
+		}
+	}
+
+	temp, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return unit.ToCelsius(temp), nil
+}
+
+// ParseComfortProfiles parses a semicolon separated list of name=scale
+// (e.g. "phoenix=60,75,95F;oslo=feelsLike,freezing:-5,cold:8,mild:18,warm")
+// and adds them to the named profiles.  Temperatures without a unit are in celsius.
+func ParseComfortProfiles(str string) error {
+	profiles := map[string]ComfortProfile{}
+
+	for _, part := range strings.Split(str, ";") {
+		part = strings.TrimSpace(part)
+
+		if part == "" {
+			continue
+		}
+
+		name, scale, found := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+
+		if !found || name == "" || strings.Contains(name, "|") {
+			return fmt.Errorf("Invalid comfort profile (must be name=cold,cool,warm or name=label:max,...,label without '|' in the name): %v", part)
+		}
+
+		if _, duplicate := profiles[name]; duplicate || name == DefaultComfortProfile || name == CustomComfortProfile {
+			return fmt.Errorf("Duplicate or reserved comfort profile name: %v", name)
+		}
+
+		profile, err := ParseComfortTemperatures(name, scale, Celsius)
+
+		if err != nil {
+			return fmt.Errorf("Comfort profile %v: %v", name, err)
+		}
+
+		profiles[name] = profile
+	}
+
+	for name, profile := range profiles {
+		comfortProfiles[name] = profile
+	}
+
+	return nil
+}
+
+// SetDefaultComfortProfile sets the scale used when the client doesn't pick a profile
+func SetDefaultComfortProfile(profile ComfortProfile) {
+	profile.Name = DefaultComfortProfile
+	comfortProfiles[DefaultComfortProfile] = profile
+}
+
+// DefaultComfort returns the profile used when the client doesn't pick one
+func DefaultComfort() ComfortProfile {
+	return comfortProfiles[DefaultComfortProfile]
+}
+
+// LookupComfortProfile returns a named profile.  An empty name is the default profile.
+func LookupComfortProfile(name string) (ComfortProfile, error) {
+	if name == "" {
+		return DefaultComfort(), nil
+	}
+
+	profile, found := comfortProfiles[name]
+
+	if !found {
+		return ComfortProfile{}, fmt.Errorf("Invalid comfortProfile: %v (valid profiles are %v)", name,
+			strings.Join(ComfortProfileNames(), ", "))
+	}
+
+	return profile, nil
+}
+
+// ComfortProfileNames returns the names of the named profiles
+func ComfortProfileNames() []string {
+	names := make([]string, 0, len(comfortProfiles))
+	for name := range comfortProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SubjectiveTemp returns the label of the band the temperature (in the unit) falls in
+func (profile ComfortProfile) SubjectiveTemp(unit TemperatureUnit, temp float64) string {
+	tempC := unit.ToCelsius(temp)
+	bands := profile.Bands()
+
+	for _, band := range bands {
+		if tempC <= band.MaxCelsius {
+			return band.Label
+		}
+	}
+
+	return bands[len(bands)-1].Label
+}
+
+// Threshold returns the highest temperature that's still in the band with the label
+// in the temperature unit.  The top band has no threshold.
+func (profile ComfortProfile) Threshold(unit TemperatureUnit, label string) (float64, error) {
+	bands := profile.Bands()
+
+	for _, band := range bands[:len(bands)-1] {
+		if band.Label == label {
+			return unit.FromCelsius(band.MaxCelsius), nil
+		}
+	}
+
+	labels := make([]string, len(bands)-1)
+	for inx := range labels {
+		labels[inx] = bands[inx].Label
+	}
+
+	return 0, fmt.Errorf("Invalid subjective temperature: %v (must be %v)", label, strings.Join(labels, ", "))
+}
@@ -0,0 +1,209 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// The most rules a condition rules file can have
+const maxConditionRules = 100
+
+// ConditionRule tags the weather (e.g. "muggy") when all of its conditions are true.
+// Rules are tried from the highest priority down and each tag is returned once.
+// Only the highest priority matching rule of a group is used, so a group can hold
+// tags that shouldn't be returned together (e.g. breezy and windy).
+type ConditionRule struct {
+	Tag        string      `json:"tag"`
+	Priority   int         `json:"priority"`
+	Group      string      `json:"group,omitempty"`
+	Conditions []Condition `json:"conditions"`
+}
+
+// Condition compares a weather field with a value.  The values are metric
+// (°C, m/s, hPa, m, and mm) whatever units the client asked for.
+type Condition struct {
+	Field      string  `json:"field"`
+	Comparator string  `json:"comparator"`
+	Value      float64 `json:"value"`
+}
+
+// conditionRulesFile is the format of the -conditionRules file
+type conditionRulesFile struct {
+	Rules []ConditionRule `json:"rules"`
+}
+
+// conditionFields returns the fields conditions can use from the metric observation
+var conditionFields = map[string]func(data *CurrentWeatherData) float64{
+	"temp":              func(data *CurrentWeatherData) float64 { return data.Main.Temp },
+	"tempFeelsLike":     func(data *CurrentWeatherData) float64 { return data.Main.FeelsLike },
+	"tempHigh":          func(data *CurrentWeatherData) float64 { return data.Main.TempMax },
+	"tempLow":           func(data *CurrentWeatherData) float64 { return data.Main.TempMin },
+	"humidityPercent":   func(data *CurrentWeatherData) float64 { return data.Main.Humidity },
+	"cloudinessPercent": func(data *CurrentWeatherData) float64 { return data.Clouds.All },
+	"windSpeed":         func(data *CurrentWeatherData) float64 { return data.Wind.Speed },
+	"windGust":          func(data *CurrentWeatherData) float64 { return data.Wind.Gust },
+	"pressure":          func(data *CurrentWeatherData) float64 { return data.Main.Pressure },
+	"visibility":        func(data *CurrentWeatherData) float64 { return *data.Visibility },
+	"rain1h":            func(data *CurrentWeatherData) float64 { return data.Rain.H },
+	"dewPoint":          func(data *CurrentWeatherData) float64 { return DewPointCelsius(data.Main.Temp, data.Main.Humidity) },
+}
+
+// fieldPresent returns whether the observation has the fields Open Weather can leave out.
+// Conditions on a missing field are false.
+var fieldPresent = map[string]func(data *CurrentWeatherData) bool{
+	"visibility": func(data *CurrentWeatherData) bool { return data.Visibility != nil },
+}
+
+// The rules used when the server isn't started with -conditionRules.  The same rules are in conditions/conditions.json.
+var conditionRules = sortConditionRules([]ConditionRule{
+	{Tag: "muggy", Priority: 30, Group: "humidity", Conditions: []Condition{{"dewPoint", ">=", 18}}},
+	{Tag: "dry", Priority: 20, Group: "humidity", Conditions: []Condition{{"humidityPercent", "<=", 25}}},
+	{Tag: "windy", Priority: 30, Group: "wind", Conditions: []Condition{{"windSpeed", ">=", 10.8}}},
+	{Tag: "breezy", Priority: 20, Group: "wind", Conditions: []Condition{{"windSpeed", ">=", 5.5}}},
+	{Tag: "gloomy", Priority: 20, Group: "sky", Conditions: []Condition{{"cloudinessPercent", ">=", 85}}},
+	{Tag: "crisp", Priority: 10, Group: "sky", Conditions: []Condition{{"temp", ">=", 0}, {"temp", "<=", 12},
+		{"humidityPercent", "<=", 60}, {"cloudinessPercent", "<=", 30}}},
+	{Tag: "hazy", Priority: 10, Conditions: []Condition{{"visibility", "<", 5000}}},
+})
+
+// sortConditionRules sorts the rules from the highest priority down.  Rules with
+// the same priority are kept in the order they were given.
+func sortConditionRules(rules []ConditionRule) []ConditionRule {
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].Priority > rules[j].Priority })
+	return rules
+}
+
+// LoadConditionRules replaces the condition rules with the ones in a json file
+func LoadConditionRules(path string) error {
+	contents, err := os.ReadFile(path)
+
+	if err != nil {
+		return fmt.Errorf("Error reading condition rules %v: %v", path, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(contents))
+	decoder.DisallowUnknownFields()
+
+	var file conditionRulesFile
+
+	if err := decoder.Decode(&file); err != nil {
+		return fmt.Errorf("Error parsing condition rules %v: %v", path, err)
+	}
+
+	if len(file.Rules) > maxConditionRules {
+		return fmt.Errorf("Too many condition rules in %v: %v (the maximum is %v)", path, len(file.Rules), maxConditionRules)
+	}
+
+	for inx, rule := range file.Rules {
+		if err := validateConditionRule(rule); err != nil {
+			return fmt.Errorf("Invalid condition rule %v in %v: %v", inx+1, path, err)
+		}
+	}
+
+	conditionRules = sortConditionRules(file.Rules)
+	return nil
+}
+
+func validateConditionRule(rule ConditionRule) error {
+	if !bandLabelPattern.MatchString(rule.Tag) {
+		return fmt.Errorf("Invalid tag: %q (must start with a letter followed by letters, digits, _ or -)", rule.Tag)
+	}
+
+	if err := validateConditions(rule.Conditions); err != nil {
+		return fmt.Errorf("%v: %v", rule.Tag, err)
+	}
+
+	return nil
+}
+
+// validateConditions checks the conditions of a condition or recommendation rule
+func validateConditions(conditions []Condition) error {
+	if len(conditions) == 0 {
+		return fmt.Errorf("No conditions")
+	}
+
+	for _, condition := range conditions {
+		if _, found := conditionFields[condition.Field]; !found {
+			return fmt.Errorf("Invalid field: %v (valid fields are %v)", condition.Field,
+				strings.Join(ConditionFieldNames(), ", "))
+		}
+
+		if _, err := compare(0, condition.Comparator, 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ConditionFieldNames returns the fields condition rules can use
+func ConditionFieldNames() []string {
+	names := make([]string, 0, len(conditionFields))
+	for name := range conditionFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func compare(value float64, comparator string, threshold float64) (bool, error) {
+	switch comparator {
+	case "<":
+		return value < threshold, nil
+	case "<=":
+		return value <= threshold, nil
+	case ">":
+		return value > threshold, nil
+	case ">=":
+		return value >= threshold, nil
+	case "==":
+		return value == threshold, nil
+	case "!=":
+		return value != threshold, nil
+	}
+
+	return false, fmt.Errorf("Invalid comparator: %v (must be <, <=, >, >=, ==, or !=)", comparator)
+}
+
+// MatchConditions returns the tags of the rules the weather matches, highest priority first.
+// The data must still be in Open Weather's metric units, the units of the rules.
+func MatchConditions(data *CurrentWeatherData) []string {
+	tags := []string{}
+	tagged := map[string]bool{}
+	groups := map[string]bool{}
+
+	for _, rule := range conditionRules {
+		if tagged[rule.Tag] || (rule.Group != "" && groups[rule.Group]) || !conditionsMatch(rule.Conditions, data) {
+			continue
+		}
+
+		tags = append(tags, rule.Tag)
+		tagged[rule.Tag] = true
+
+		if rule.Group != "" {
+			groups[rule.Group] = true
+		}
+	}
+
+	return tags
+}
+
+// conditionsMatch returns true when all the conditions are true
+func conditionsMatch(conditions []Condition, data *CurrentWeatherData) bool {
+	for _, condition := range conditions {
+		if present, optional := fieldPresent[condition.Field]; optional && !present(data) {
+			return false
+		}
+
+		// The rules were validated when they were loaded
+		if matched, _ := compare(conditionFields[condition.Field](data), condition.Comparator, condition.Value); !matched {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,77 @@
+package data
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The rules are matched against the metric observation, so rounding to the client's units
+// (e.g. whole Beaufort numbers) can't move the weather across a threshold
+func TestConditionsIgnoreUnits(t *testing.T) {
+	rules := conditionRules
+	conditionRules = []ConditionRule{{Tag: "windy", Conditions: []Condition{{"windSpeed", ">=", 7}}}}
+	defer func() { conditionRules = rules }()
+
+	beaufort := MetricUnits
+	beaufort.Speed = Beaufort
+
+	tests := []struct {
+		windSpeed float64 // m/s
+		want      []string
+	}{
+		{6.9, []string{}},
+		{7, []string{"windy"}},
+		{7.5, []string{"windy"}}, // 4 Bft, which is 6.7 m/s
+	}
+
+	for _, units := range []UnitSystem{MetricUnits, beaufort, ImperialUnits} {
+		for _, test := range tests {
+			observation := &CurrentWeatherData{}
+
+			if err := json.Unmarshal([]byte(sampleWeatherJson), observation); err != nil {
+				t.Fatal(err)
+			}
+
+			observation.Wind.Speed = test.windSpeed
+
+			if got := SimplifyCurrentWeatherData(observation, units).Conditions; !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Conditions at %v m/s in %v = %v, want %v", test.windSpeed, units, got, test.want)
+			}
+		}
+	}
+}
+
+// Open Weather sometimes leaves out the visibility, which mustn't be taken as 0 (hazy)
+func TestConditionsWithoutVisibility(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		hazy     bool
+	}{
+		{"clear", sampleWeatherJson, false},
+		{"hazy", strings.Replace(sampleWeatherJson, `"visibility":10000`, `"visibility":3000`, 1), true},
+		{"no visibility", strings.Replace(sampleWeatherJson, `"visibility":10000,`, "", 1), false},
+	}
+
+	for _, test := range tests {
+		observation := &CurrentWeatherData{}
+
+		if err := json.Unmarshal([]byte(test.response), observation); err != nil {
+			t.Fatal(err)
+		}
+
+		simplified := SimplifyCurrentWeatherData(observation, MetricUnits)
+		hazy := false
+
+		for _, tag := range simplified.Conditions {
+			hazy = hazy || tag == "hazy"
+		}
+
+		if hazy != test.hazy || strings.Contains(simplified.Summary, "hazy") != test.hazy {
+			t.Errorf("%v: conditions %v and summary %q, want hazy %v", test.name, simplified.Conditions,
+				simplified.Summary, test.hazy)
+		}
+	}
+}
@@ -2,33 +2,9 @@ package data
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 )
 
-// This map holds the cold, cool, warm temparatures for
-// "C" (celsius), "F" (fahrenheit), and "K" (Kelvin)
-var subjectiveTempMap map[string][]float64
-
-// SetColdCoolWarmCelsius sets what temperatures will be
-// used to determine subjective weather (hot, cold, etc.)
-// Anything above warm is considered hot.
-// The subjective weather is returned in SimplifiedWeather
-// as subjectiveTemp
-func SetColdCoolWarmCelsius(cold, cool, warm float64) error {
-	if !(cold < cool && cool < warm) {
-		return fmt.Errorf("Cold temp (%v) must be less than cool (%v).  Cool temp must be less than warm (%v).",
-			cold, cool, warm)
-	}
-
-	subjectiveTempMap = map[string][]float64{}
-
-	subjectiveTempMap["C"] = []float64{cold, cool, warm}
-	subjectiveTempMap["F"] = []float64{CelsiusToFahrenheit(cold), CelsiusToFahrenheit(cool), CelsiusToFahrenheit(warm)}
-	subjectiveTempMap["K"] = []float64{CelsiusToKelvin(cold), CelsiusToKelvin(cool), CelsiusToKelvin(warm)}
-	return nil
-}
-
 func CelsiusToFahrenheit(c float64) float64 {
 	return c*9.0/5.0 + 32
 }
@@ -41,10 +17,16 @@ func FahrenheitToCelsius(f float64) float64 {
 	return (f - 32) * 5.0 / 9.0
 }
 
+func KelvinToCelsius(k float64) float64 {
+	return k - 273.15
+}
+
 type CurrentWeatherData struct {
 	// not part of the json return structure
 	// added to the structure after the call to Open Weather
-	Units              string
+	Units              UnitSystem
+	Lang               string         // the language of the descriptions
+	Comfort            ComfortProfile // the default comfort profile is used when it has no name
 	DataCollectionTime string
 
 	// These attributes are in the json return structure
@@ -69,7 +51,7 @@ type CurrentWeatherData struct {
 		SeaLevel  float64 `json:"sea_level"`
 		GrndLevel float64 `json:"grnd_level"`
 	} `json:"main"`
-	Visibility int `json:"visibility"`
+	Visibility *float64 `json:"visibility,omitempty"` // Open Weather sometimes leaves it out
 	Wind       struct {
 		Speed float64 `json:"speed"`
 		Deg   float64 `json:"deg"`
@@ -96,29 +78,55 @@ type CurrentWeatherData struct {
 }
 
 // SimplifiedWeather is the structure returned by
-// calls to /api/currentweather
+// calls to /api/currentweather.
+// The compact tag holds the short name used for the field
+// when the client asks for a compact response.
 type SimplifiedWeather struct {
-	Units              string  `json:"units"`
-	DataCollectionTime string  `json:"dataCollectionTime"`
-	Lat                float64 `json:"latitude"`
-	Long               float64 `json:"longitude"`
-	CloudinessPercent  float64 `json:"cloudinessPercent"`
-	HumidityPercent    float64 `json:"humidityPercent"`
-	Temp               float64 `json:"temp"`
-	TempHigh           float64 `json:"tempHigh"`
-	TempLow            float64 `json:"tempLow"`
-	TempFeelsLike      float64 `json:"tempFeelsLike"`
-	ExpectedWeather    string  `json:"expectedWeather"`
-	WeatherDescription string  `json:"weatherDescription"`
-	SubjectiveTemp     string  `json:"subjectiveTemp"`
-	Summary            string  `json:"summary"`
+	Units              string         `json:"units" compact:"u"` // the temperature unit
+	DataCollectionTime string         `json:"dataCollectionTime" compact:"dt"`
+	Lat                float64        `json:"latitude" compact:"lat"`
+	Long               float64        `json:"longitude" compact:"lon"`
+	CloudinessPercent  float64        `json:"cloudinessPercent" compact:"cld"`
+	HumidityPercent    float64        `json:"humidityPercent" compact:"hum"`
+	Temp               float64        `json:"temp" compact:"t"`
+	TempHigh           float64        `json:"tempHigh" compact:"th"`
+	TempLow            float64        `json:"tempLow" compact:"tl"`
+	TempFeelsLike      float64        `json:"tempFeelsLike" compact:"tf"`
+	WindSpeed          float64        `json:"windSpeed" compact:"ws"`
+	WindGust           float64        `json:"windGust" compact:"wg"`
+	WindDirection      float64        `json:"windDirection" compact:"wdir"`
+	WindUnit           string         `json:"windUnit" compact:"wu"`
+	Pressure           float64        `json:"pressure" compact:"p"`
+	PressureUnit       string         `json:"pressureUnit" compact:"pu"`
+	Visibility         float64        `json:"visibility" compact:"v"`
+	DistanceUnit       string         `json:"distanceUnit" compact:"vu"`
+	Rain1h             float64        `json:"rain1h" compact:"r"`
+	PrecipUnit         string         `json:"precipUnit" compact:"ru"`
+	Derived            DerivedMetrics `json:"derived" compact:"dv"`
+	ExpectedWeather    string         `json:"expectedWeather" compact:"ew"`
+	WeatherDescription string         `json:"weatherDescription" compact:"wd"`
+	SubjectiveTemp     string         `json:"subjectiveTemp" compact:"st"`
+	ComfortProfile     string         `json:"comfortProfile" compact:"cp"`   // the profile subjectiveTemp is based on
+	Conditions         []string       `json:"conditions" compact:"cd"`       // the tags of the matching condition rules
+	Trends             *Trends        `json:"trends,omitempty" compact:"tr"` // left out until the location has recent observations
+	Summary            string         `json:"summary" compact:"s"`
 }
 
 // SimplifyCurrentWeatherData generates a SimplifiedWeather object from
-// the CurrentWeatherData (returned by Open Weather) and the
-// cold, cool, warm temperature set when the application started.
-func SimplifyCurrentWeatherData(data *CurrentWeatherData) *SimplifiedWeather {
-	if data == nil || data.Weather == nil || len(data.Weather) == 0 {
+// the CurrentWeatherData (returned by Open Weather in metric units) and the
+// comfort profile (subjective temperature scale) asked for by the client.
+// The data is converted to the units (see ConvertFromMetric) once the derived
+// metrics and conditions, which need the unrounded metric values, are worked out.
+func SimplifyCurrentWeatherData(data *CurrentWeatherData, units UnitSystem) *SimplifiedWeather {
+	if data == nil {
+		return nil
+	}
+
+	derived := ComputeDerivedMetrics(data, units)
+	conditions := MatchConditions(data)
+	ConvertFromMetric(data, units)
+
+	if data.Weather == nil || len(data.Weather) == 0 {
 		return nil
 	}
 
@@ -132,6 +140,20 @@ func SimplifyCurrentWeatherData(data *CurrentWeatherData) *SimplifiedWeather {
 	simplified.TempLow = data.Main.TempMin
 	simplified.TempFeelsLike = data.Main.FeelsLike
 	simplified.HumidityPercent = data.Main.Humidity
+	simplified.WindSpeed = data.Wind.Speed
+	simplified.WindGust = data.Wind.Gust
+	simplified.WindDirection = data.Wind.Deg
+	simplified.WindUnit = string(data.Units.Speed)
+	simplified.Pressure = data.Main.Pressure
+	simplified.PressureUnit = string(data.Units.Pressure)
+	if data.Visibility != nil {
+		simplified.Visibility = *data.Visibility
+	}
+
+	simplified.DistanceUnit = string(data.Units.Distance)
+	simplified.Rain1h = data.Rain.H
+	simplified.PrecipUnit = string(data.Units.Precipitation)
+	simplified.Derived = derived
 	mainDesc := make([]string, len(data.Weather))
 	mainSubDesc := make([]string, len(data.Weather))
 	for inx, weather := range data.Weather {
@@ -140,63 +162,47 @@ func SimplifyCurrentWeatherData(data *CurrentWeatherData) *SimplifiedWeather {
 	}
 
 	simplified.ExpectedWeather = strings.ToLower(strings.Join(mainDesc, ","))
-	simplified.WeatherDescription = strings.ToLower(strings.Join(mainSubDesc, ","))
-
-	switch data.Units {
-	case "standard":
-		simplified.Units = "K" // kelvin
-	case "metric":
-		simplified.Units = "C" // celsius
-	case "imperial":
-		simplified.Units = "F" // fahrenheit
-	}
-
-	m := subjectiveTempMap[simplified.Units]
-	cold, cool, warm := m[0], m[1], m[2]
+	// Open Weather localizes the descriptions.  They're only lower cased in English
+	// because other languages (e.g. German) capitalize some words.
+	catalog := CatalogFor(data.Lang)
+	simplified.WeatherDescription = strings.Join(mainSubDesc, ",")
 
-	if simplified.Temp <= cold {
-		simplified.SubjectiveTemp = "cold"
-	} else if simplified.Temp <= cool {
-		simplified.SubjectiveTemp = "cool"
-	} else if simplified.Temp <= warm {
-		simplified.SubjectiveTemp = "warm"
-	} else {
-		simplified.SubjectiveTemp = "hot"
+	if catalog.Lang == DefaultLanguage {
+		simplified.WeatherDescription = strings.ToLower(simplified.WeatherDescription)
 	}
 
-	simplified.Summary = fmt.Sprintf("The weather will be %v.  Expect %v with a high of %v °%v, a low of %v °%v, "+
-		"and an average temperature of %v °%v.  It'll fell like %v °%v with a humidity of %v%% and a cloud cover of %v%%.",
-		simplified.SubjectiveTemp, simplified.ExpectedWeather,
-		simplified.TempHigh, simplified.Units,
-		simplified.TempLow, simplified.Units,
-		simplified.Temp, simplified.Units,
-		simplified.TempFeelsLike, simplified.Units,
-		simplified.HumidityPercent, simplified.CloudinessPercent)
-
-	return simplified
-}
+	simplified.Units = string(data.Units.Temperature)
 
-func ParseColdCoolWarmValues(str string) (float64, float64, float64, error) {
-	parts := strings.Split(str, ",")
+	comfort := data.Comfort
 
-	if len(parts) != 3 {
-		return 0, 0, 0, fmt.Errorf("Invalid number of temperatures for coldCoolWarmF: %v", len(parts))
+	if comfort.Name == "" {
+		comfort = DefaultComfort()
 	}
 
-	cold, err := strconv.ParseFloat(parts[0], 64)
-	if err != nil {
-		return 0, 0, 0, fmt.Errorf("Error parsing cold value in coldCoolWarmF: %v", err)
+	if comfort.FeelsLike {
+		simplified.SubjectiveTemp = comfort.SubjectiveTemp(data.Units.Temperature, simplified.TempFeelsLike)
+	} else {
+		simplified.SubjectiveTemp = comfort.SubjectiveTemp(data.Units.Temperature, simplified.Temp)
 	}
 
-	cool, err := strconv.ParseFloat(parts[1], 64)
-	if err != nil {
-		return 0, 0, 0, fmt.Errorf("Error parsing cool value in coldCoolWarmF: %v", err)
+	simplified.ComfortProfile = comfort.Name
+
+	temperature := func(t float64) string {
+		return fmt.Sprintf("%v °%v", catalog.FormatNumber(t), simplified.Units)
 	}
 
-	warm, err := strconv.ParseFloat(parts[2], 64)
-	if err != nil {
-		return 0, 0, 0, fmt.Errorf("Error parsing warm value in coldCoolWarmF: %v", err)
+	// The summary uses the (localized) description because expectedWeather is always English
+	simplified.Summary = catalog.Format("summary",
+		catalog.Text(simplified.SubjectiveTemp), simplified.WeatherDescription,
+		temperature(simplified.TempHigh), temperature(simplified.TempLow),
+		temperature(simplified.Temp), temperature(simplified.TempFeelsLike),
+		catalog.FormatNumber(simplified.HumidityPercent), catalog.FormatNumber(simplified.CloudinessPercent))
+
+	simplified.Conditions = conditions
+
+	if len(simplified.Conditions) > 0 {
+		simplified.Summary += "  " + catalog.Format("conditionsSummary", catalog.TextList(simplified.Conditions))
 	}
 
-	return cold, cool, warm, nil
+	return simplified
 }
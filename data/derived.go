@@ -0,0 +1,104 @@
+package data
+
+import "math"
+
+// DerivedMetrics are computed from the temperature, humidity and wind returned by
+// Open Weather.  The temperatures are in the requested temperature unit.
+type DerivedMetrics struct {
+	DewPoint  float64 `json:"dewPoint"`
+	HeatIndex float64 `json:"heatIndex"`
+	WindChill float64 `json:"windChill"` // the air temperature when it's above 10 °C or the wind is below 4.8 km/h
+	Humidex   float64 `json:"humidex"`
+	// Grams of water vapor per cubic meter of air
+	AbsoluteHumidity float64 `json:"absoluteHumidity"`
+}
+
+// ComputeDerivedMetrics computes the derived metrics from data in Open Weather's metric
+// units (before ConvertFromMetric rounds it) and converts the results to the unit system.
+func ComputeDerivedMetrics(data *CurrentWeatherData, units UnitSystem) DerivedMetrics {
+	tempC := data.Main.Temp
+	windKmh := KilometersPerHour.FromMetersPerSecond(data.Wind.Speed)
+	humidity := data.Main.Humidity
+	dewPointC := DewPointCelsius(tempC, humidity)
+
+	temperature := func(c float64) float64 { return roundTo(units.Temperature.FromCelsius(c), 2) }
+
+	return DerivedMetrics{
+		DewPoint:         temperature(dewPointC),
+		HeatIndex:        temperature(HeatIndexCelsius(tempC, humidity)),
+		WindChill:        temperature(WindChillCelsius(tempC, windKmh)),
+		Humidex:          temperature(HumidexCelsius(tempC, dewPointC)),
+		AbsoluteHumidity: roundTo(AbsoluteHumidity(tempC, humidity), 2),
+	}
+}
+
+// The Magnus formula coefficients (Alduchov and Eskridge) for saturation
+// vapor pressure over water, valid from -45 °C to 60 °C
+const (
+	magnusA = 17.625
+	magnusB = 243.04 // °C
+	magnusC = 6.1094 // hPa
+)
+
+// saturationVaporPressure returns the saturation vapor pressure in hPa
+func saturationVaporPressure(tempC float64) float64 {
+	return magnusC * math.Exp(magnusA*tempC/(magnusB+tempC))
+}
+
+// DewPointCelsius uses the Magnus formula.  e.g. 20 °C at 50% is 9.3 °C.
+func DewPointCelsius(tempC, humidityPercent float64) float64 {
+	if humidityPercent <= 0 {
+		// The dew point of perfectly dry air is undefined, use the lowest humidity Open Weather reports
+		humidityPercent = 1
+	}
+
+	gamma := math.Log(humidityPercent/100) + magnusA*tempC/(magnusB+tempC)
+	return magnusB * gamma / (magnusA - gamma)
+}
+
+// HeatIndexCelsius uses the US National Weather Service algorithm: Steadman's simple formula,
+// or the Rothfusz regression when that averages 80 °F or more with the temperature.
+// e.g. 90 °F at 70% is 106 °F (41 °C) and 79 °F at 90% is 80.8 °F.
+func HeatIndexCelsius(tempC, humidityPercent float64) float64 {
+	t := CelsiusToFahrenheit(tempC)
+	rh := humidityPercent
+
+	heatIndex := 0.5 * (t + 61.0 + (t-68.0)*1.2 + rh*0.094)
+
+	if (heatIndex+t)/2 >= 80 {
+		heatIndex = -42.379 + 2.04901523*t + 10.14333127*rh - 0.22475541*t*rh - 0.00683783*t*t -
+			0.05481717*rh*rh + 0.00122874*t*t*rh + 0.00085282*t*rh*rh - 0.00000199*t*t*rh*rh
+
+		if rh < 13 && t >= 80 && t <= 112 {
+			heatIndex -= (13 - rh) / 4 * math.Sqrt((17-math.Abs(t-95))/17)
+		} else if rh > 85 && t >= 80 && t <= 87 {
+			heatIndex += (rh - 85) / 10 * (87 - t) / 5
+		}
+	}
+
+	return FahrenheitToCelsius(heatIndex)
+}
+
+// WindChillCelsius uses the formula adopted by Environment Canada and the US
+// National Weather Service in 2001.  e.g. -10 °C with a 20 km/h wind is -17.9 °C.
+func WindChillCelsius(tempC, windKmh float64) float64 {
+	if tempC > 10 || windKmh < 4.8 {
+		return tempC
+	}
+
+	v := math.Pow(windKmh, 0.16)
+	return 13.12 + 0.6215*tempC - 11.37*v + 0.3965*tempC*v
+}
+
+// HumidexCelsius uses Environment Canada's formula.  e.g. 30 °C with a dew point of 15 °C is 34.
+func HumidexCelsius(tempC, dewPointC float64) float64 {
+	vaporPressure := 6.11 * math.Exp(5417.7530*(1/273.16-1/(273.15+dewPointC)))
+	return tempC + 0.5555*(vaporPressure-10)
+}
+
+// AbsoluteHumidity returns grams of water vapor per cubic meter.  e.g. 20 °C at 50% is 8.6 g/m³.
+func AbsoluteHumidity(tempC, humidityPercent float64) float64 {
+	vaporPressure := saturationVaporPressure(tempC) * humidityPercent / 100 // hPa
+	// The water vapor gas constant is 461.5 J/(kg K).  hPa -> Pa is *100 and kg -> g is *1000.
+	return vaporPressure * 100 * 1000 / (461.5 * (tempC + 273.15))
+}
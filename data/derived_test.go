@@ -0,0 +1,121 @@
+package data
+
+import (
+	"math"
+	"testing"
+)
+
+// The examples are the ones in the doc comments of the formulas
+func TestDewPointCelsius(t *testing.T) {
+	tests := []struct {
+		tempC, humidity, want float64
+	}{
+		{20, 50, 9.3},
+		{20, 100, 20},
+		{30, 70, 23.9},
+		{-10, 80, -12.8},
+	}
+
+	for _, test := range tests {
+		if got := DewPointCelsius(test.tempC, test.humidity); math.Abs(got-test.want) > 0.05 {
+			t.Errorf("DewPointCelsius(%v, %v) = %v, want %v", test.tempC, test.humidity, got, test.want)
+		}
+	}
+}
+
+func TestHeatIndexCelsius(t *testing.T) {
+	tests := []struct {
+		tempF, humidity, wantF float64
+	}{
+		{90, 70, 106},
+		{80, 40, 80},
+		{100, 50, 118},
+		{79, 90, 80.8}, // Steadman's simple formula
+		{50, 50, 47},
+	}
+
+	for _, test := range tests {
+		got := CelsiusToFahrenheit(HeatIndexCelsius(FahrenheitToCelsius(test.tempF), test.humidity))
+
+		if math.Abs(got-test.wantF) > 0.5 {
+			t.Errorf("HeatIndexCelsius(%v °F, %v) = %v °F, want %v °F", test.tempF, test.humidity, got, test.wantF)
+		}
+	}
+}
+
+func TestWindChillCelsius(t *testing.T) {
+	tests := []struct {
+		tempC, windKmh, want float64
+	}{
+		{-10, 20, -17.9},
+		{-20, 30, -32.6},
+		{0, 10, -3.3},
+		{11, 30, 11},  // above 10 °C it's the air temperature
+		{-10, 4, -10}, // as it is below 4.8 km/h
+	}
+
+	for _, test := range tests {
+		if got := WindChillCelsius(test.tempC, test.windKmh); math.Abs(got-test.want) > 0.05 {
+			t.Errorf("WindChillCelsius(%v, %v) = %v, want %v", test.tempC, test.windKmh, got, test.want)
+		}
+	}
+}
+
+func TestHumidexCelsius(t *testing.T) {
+	tests := []struct {
+		tempC, dewPointC, want float64
+	}{
+		{30, 15, 34},
+		{30, 25, 42},
+		{20, 10, 21},
+	}
+
+	for _, test := range tests {
+		if got := HumidexCelsius(test.tempC, test.dewPointC); math.Abs(got-test.want) > 0.5 {
+			t.Errorf("HumidexCelsius(%v, %v) = %v, want %v", test.tempC, test.dewPointC, got, test.want)
+		}
+	}
+}
+
+func TestAbsoluteHumidity(t *testing.T) {
+	tests := []struct {
+		tempC, humidity, want float64
+	}{
+		{20, 50, 8.6},
+		{30, 100, 30.3},
+		{0, 100, 4.8},
+		{20, 0, 0},
+	}
+
+	for _, test := range tests {
+		if got := AbsoluteHumidity(test.tempC, test.humidity); math.Abs(got-test.want) > 0.05 {
+			t.Errorf("AbsoluteHumidity(%v, %v) = %v, want %v", test.tempC, test.humidity, got, test.want)
+		}
+	}
+}
+
+// The derived metrics are computed from the metric observation so the units only change the results
+func TestComputeDerivedMetricsUnits(t *testing.T) {
+	observation := &CurrentWeatherData{}
+	observation.Main.Temp = -10
+	observation.Main.Humidity = 50
+	observation.Wind.Speed = 20 / 3.6 // 20 km/h, which rounds to 4 Bft
+
+	beaufort := MetricUnits
+	beaufort.Speed = Beaufort
+
+	tests := []struct {
+		units         UnitSystem
+		wantWindChill float64
+	}{
+		{MetricUnits, -17.86},
+		{beaufort, -17.86},
+		{ImperialUnits, -0.15},
+	}
+
+	for _, test := range tests {
+		if got := ComputeDerivedMetrics(observation, test.units).WindChill; got != test.wantWindChill {
+			t.Errorf("ComputeDerivedMetrics in %v: wind chill = %v, want %v", test.units, got, test.wantWindChill)
+		}
+	}
+}
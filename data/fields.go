@@ -0,0 +1,180 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// weatherField describes one field of SimplifiedWeather as it
+// appears in the API: its json name, its short (compact) name
+// and its index in the struct.
+type weatherField struct {
+	Name        string
+	CompactName string
+	index       int
+}
+
+// The fields of SimplifiedWeather in declaration order.  Built from
+// the json and compact struct tags so the two can never drift apart.
+var simplifiedWeatherFields = buildWeatherFields(reflect.TypeOf(SimplifiedWeather{}))
+
+func buildWeatherFields(t reflect.Type) []weatherField {
+	fields := make([]weatherField, 0, t.NumField())
+
+	for inx := 0; inx < t.NumField(); inx++ {
+		structField := t.Field(inx)
+		name := strings.Split(structField.Tag.Get("json"), ",")[0]
+
+		if name == "" || name == "-" {
+			continue
+		}
+
+		compactName := structField.Tag.Get("compact")
+
+		if compactName == "" {
+			compactName = name
+		}
+
+		fields = append(fields, weatherField{Name: name, CompactName: compactName, index: inx})
+	}
+
+	return fields
+}
+
+// WeatherFieldNames returns the json names of all the fields
+// that can be requested with the fields parameter.
+func WeatherFieldNames() []string {
+	names := make([]string, len(simplifiedWeatherFields))
+	for inx, field := range simplifiedWeatherFields {
+		names[inx] = field.Name
+	}
+	return names
+}
+
+// FieldSelection describes which SimplifiedWeather fields should be
+// returned to the client and whether they should use their short names.
+type FieldSelection struct {
+	fields  []weatherField
+	Compact bool
+}
+
+// ParseFieldSelection parses a comma separated list of json field names
+// (e.g. "temp,subjectiveTemp").  An empty list selects every field.
+// Unknown or repeated field names are reported as an error.
+func ParseFieldSelection(fieldsStr string, compact bool) (*FieldSelection, error) {
+	selection := &FieldSelection{Compact: compact}
+
+	if strings.TrimSpace(fieldsStr) == "" {
+		selection.fields = simplifiedWeatherFields
+		return selection, nil
+	}
+
+	seen := map[string]bool{}
+
+	for _, name := range strings.Split(fieldsStr, ",") {
+		name = strings.TrimSpace(name)
+
+		if seen[name] {
+			return nil, fmt.Errorf("Field requested more than once: %v", name)
+		}
+
+		field, found := findWeatherField(name)
+
+		if !found {
+			return nil, fmt.Errorf("Invalid field: %v (valid fields are %v)", name,
+				strings.Join(WeatherFieldNames(), ","))
+		}
+
+		seen[name] = true
+		selection.fields = append(selection.fields, field)
+	}
+
+	return selection, nil
+}
+
+func findWeatherField(name string) (weatherField, bool) {
+	for _, field := range simplifiedWeatherFields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return weatherField{}, false
+}
+
+// IsDefault is true when every field is selected with its normal name,
+// i.e. when the selection doesn't change the response at all.
+func (s *FieldSelection) IsDefault() bool {
+	return !s.Compact && len(s.fields) == len(simplifiedWeatherFields)
+}
+
+// Apply returns the selected fields of the weather in the order
+// they were requested.
+func (s *FieldSelection) Apply(weather *SimplifiedWeather) SelectedWeather {
+	if weather == nil {
+		return nil
+	}
+
+	value := reflect.ValueOf(weather).Elem()
+	selected := make(SelectedWeather, len(s.fields))
+
+	for inx, field := range s.fields {
+		name := field.Name
+		if s.Compact {
+			name = field.CompactName
+		}
+		selected[inx] = SelectedField{Name: name, Value: value.Field(field.index).Interface()}
+	}
+
+	return selected
+}
+
+// Select returns the weather unchanged when the selection is the default
+// and the selected fields otherwise.  The result is meant to be marshalled.
+func (s *FieldSelection) Select(weather *SimplifiedWeather) interface{} {
+	if s.IsDefault() {
+		return weather
+	}
+	return s.Apply(weather)
+}
+
+// SelectedField is a single name/value pair of a SelectedWeather
+type SelectedField struct {
+	Name  string
+	Value interface{}
+}
+
+// SelectedWeather is the subset of a SimplifiedWeather chosen by a
+// FieldSelection.  It marshals to a json object that keeps the
+// order of the fields.
+type SelectedWeather []SelectedField
+
+func (s SelectedWeather) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+
+	for inx, field := range s {
+		if inx > 0 {
+			buffer.WriteByte(',')
+		}
+
+		nameBytes, err := json.Marshal(field.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		valueBytes, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		buffer.Write(nameBytes)
+		buffer.WriteByte(':')
+		buffer.Write(valueBytes)
+	}
+
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,76 @@
+package data
+
+// GeoJSONType is the media type of GeoJSON (RFC 7946)
+const GeoJSONType = "application/geo+json"
+
+// GeoJSONGeometry is a GeoJSON Point.  The coordinates are the longitude and then the latitude.
+type GeoJSONGeometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+// GeoJSONFeature is a GeoJSON Feature.  The properties must marshal to a json object.
+type GeoJSONFeature struct {
+	Type       string           `json:"type"`
+	Geometry   *GeoJSONGeometry `json:"geometry"`
+	Properties interface{}      `json:"properties"`
+}
+
+// GeoJSONFeatureCollection is a GeoJSON FeatureCollection.  Members are foreign members
+// (e.g. whether a list was truncated) written between the bbox and the features.
+type GeoJSONFeatureCollection struct {
+	Bbox     []float64 // minLon, minLat, maxLon, maxLat
+	Members  []SelectedField
+	Features []*GeoJSONFeature
+}
+
+// NewPointFeature returns a feature at a location
+func NewPointFeature(latitude, longitude float64, properties interface{}) *GeoJSONFeature {
+	return &GeoJSONFeature{Type: "Feature", Properties: properties,
+		Geometry: &GeoJSONGeometry{Type: "Point", Coordinates: []float64{longitude, latitude}}}
+}
+
+// NewWeatherFeature returns a feature at the weather's location whose properties are the
+// fields of the selection (every field when it's nil) after the extra properties.  Without
+// weather the feature has no geometry and only the extra properties.
+func NewWeatherFeature(weather *SimplifiedWeather, selection *FieldSelection, extra ...SelectedField) *GeoJSONFeature {
+	properties := append(SelectedWeather{}, extra...)
+
+	if weather == nil {
+		return &GeoJSONFeature{Type: "Feature", Properties: properties}
+	}
+
+	if selection == nil {
+		selection = &FieldSelection{fields: simplifiedWeatherFields}
+	}
+
+	for _, field := range selection.Apply(weather) {
+		// Trends are left out until there are some, as they are from the weather's json
+		if trends, ok := field.Value.(*Trends); ok && trends == nil {
+			continue
+		}
+		properties = append(properties, field)
+	}
+
+	return NewPointFeature(weather.Lat, weather.Long, properties)
+}
+
+// MarshalJSON writes the type first, as GeoJSON readers expect, rather than in the
+// order of a map's keys
+func (collection GeoJSONFeatureCollection) MarshalJSON() ([]byte, error) {
+	object := SelectedWeather{{Name: "type", Value: "FeatureCollection"}}
+
+	if collection.Bbox != nil {
+		object = append(object, SelectedField{Name: "bbox", Value: collection.Bbox})
+	}
+
+	features := collection.Features
+
+	if features == nil {
+		features = []*GeoJSONFeature{}
+	}
+
+	object = append(object, collection.Members...)
+	object = append(object, SelectedField{Name: "features", Value: features})
+	return object.MarshalJSON()
+}
@@ -2,50 +2,50 @@ package data
 
 const TEMPLATE_FILES = `
 {{ define "version_page" }}
-	<html>
+	<html lang="{{ lang }}">
 		<head>
 			<meta charset="utf-8">
-			<title>Current Weather Server</title>
+			<title>{{ t "serverTitle" }}</title>
 		</head>
 		<body>
-	         <b>Current Weather Server</b>
+	         <b>{{ t "serverTitle" }}</b>
              <br>
-             <b>Version:</b> {{.}} 
+             <b>{{ t "version" }}:</b> {{.}} 
 		</body>
 	</html>
 {{ end }}
 
 {{ define "get_longitude_latitude" }}
-	<html>
+	<html lang="{{ lang }}">
 		<head>
 			<meta charset="utf-8">
-			<title>Get Current Weather for...</title>
+			<title>{{ t "getWeatherTitle" }}</title>
             <script>
                 function displayError(msg) {
-                    document.getElementById("validationError").innerHTML = msg;
+                    document.getElementById("validationError").textContent = msg;
                 }
 
 				function validateForm() {
                   displayError("");
 				  let longitudeText = document.forms["longLatForm"]["longitude"].value.trim();
 				  if (longitudeText == "") {
-					displayError("Longitude missing");
+					displayError({{ t "longitudeMissing" }});
 					return false;
 				  }
 
                   if (! /^[-+]?[0-9]*\.?[0-9]+$/.test(longitudeText)) {
-                     displayError("Invalid number format for Longitude: " + longitudeText);
+                     displayError({{ t "invalidLongitudeFormat" }} + longitudeText);
                      return false;
                   }
 
 				  let latitudeText = document.forms["longLatForm"]["latitude"].value.trim();
 				  if (latitudeText == "") {
-					displayError("Latitude missing");
+					displayError({{ t "latitudeMissing" }});
 					return false;
 				  }
 
                   if (! /^[-+]?[0-9]*\.?[0-9]+$/.test(latitudeText)) {
-                     displayError("Invalid number format for Latitude: " + latitudeText );
+                     displayError({{ t "invalidLatitudeFormat" }} + latitudeText );
                      return false;
                   }
 
@@ -53,14 +53,14 @@ const TEMPLATE_FILES = `
 
 
                   if (isNaN(longitude) || longitude < -180 || longitude > 180) {
-                      displayError("Invalid longitude value.  Must be a number between -180 and 180");
+                      displayError({{ t "invalidLongitudeRange" }});
                       return false;
                   }
 
                   latitude = parseFloat(latitudeText);
 
                   if (isNaN(latitude) || latitude < -90 || latitude > 90) {
-                      displayError("Invalid latitude value.  Must be a number between -90 and 90");
+                      displayError({{ t "invalidLatitudeRange" }});
                       return false;
                   }
                   console.log("Form valid");
@@ -69,79 +69,369 @@ const TEMPLATE_FILES = `
             </script>
 		</head>
 		<body>
-	         <b>Current Weather Server</b>
+	         <b>{{ t "serverTitle" }}</b>
              <br>
              <br>
 
 			<form name="longLatForm" action="/displaycurrentweather.html" onSubmit="return validateForm()" method="get">
-			  <label for="longitude">Longitude:</label>
+			  <input type="hidden" name="lang" value="{{ lang }}">
+			  <label for="longitude">{{ t "longitude" }}:</label>
 			  <input type="text" id="longitude" name="longitude" value="0.0">
-			  <label for="latitude">Latitude:</label>
+			  <label for="latitude">{{ t "latitude" }}:</label>
 			  <input type="text" id="latitude" name="latitude" value="0.0"> <br><br>
 
 				<fieldset>
-				  <legend>Temperature Unit:</legend>
+				  <legend>{{ t "temperatureUnit" }}:</legend>
 				
 				  <div>
 					  <input type="radio" id="imperial" name="units" value="imperial" checked>
-					  <label for="imperial">Fahrenheit</label>
+					  <label for="imperial">{{ t "fahrenheit" }}</label>
 				  
 					  <input type="radio" id="metric" name="units" value="metric">
-					  <label for="metric">Celsius</label>
+					  <label for="metric">{{ t "celsius" }}</label>
 				  
 					  <input type="radio" id="standard" name="units" value="standard">
-					  <label for="standard">Kelvin</label>
+					  <label for="standard">{{ t "kelvin" }}</label>
 				  </div>
 				</fieldset>
 
+              <br>
+
+				<fieldset>
+				  <legend>{{ t "otherUnits" }}:</legend>
+
+				  <label for="windUnit">{{ t "wind" }}:</label>
+				  <select id="windUnit" name="windUnit">
+					  <option value="" selected>{{ t "default" }}</option>
+					  <option value="m/s">m/s</option>
+					  <option value="km/h">km/h</option>
+					  <option value="mph">mph</option>
+					  <option value="kn">{{ t "knots" }}</option>
+					  <option value="Bft">{{ t "beaufort" }}</option>
+				  </select>
+
+				  <label for="pressureUnit">{{ t "pressure" }}:</label>
+				  <select id="pressureUnit" name="pressureUnit">
+					  <option value="" selected>hPa</option>
+					  <option value="inHg">inHg</option>
+					  <option value="mmHg">mmHg</option>
+				  </select>
+
+				  <label for="distanceUnit">{{ t "visibility" }}:</label>
+				  <select id="distanceUnit" name="distanceUnit">
+					  <option value="" selected>m</option>
+					  <option value="km">km</option>
+					  <option value="mi">mi</option>
+				  </select>
+
+				  <label for="precipUnit">{{ t "rain" }}:</label>
+				  <select id="precipUnit" name="precipUnit">
+					  <option value="" selected>mm</option>
+					  <option value="in">in</option>
+				  </select>
+				</fieldset>
+
               <br><br>
-			  <input type="submit" value="Get Current Weather">
+			  <input type="submit" value="{{ t "getCurrentWeather" }}">
 			</form>
 
             <div id="validationError" style="color:red">
                  
             </div> 
 
+            <br>
+            <a href="compareweather.html?lang={{ lang }}">{{ t "compareLocations" }}</a>
+
 		</body>
 	</html>
 
 {{ end }}
 
 {{ define "display_current_weather" }}
-	<html>
+	<html lang="{{ lang }}">
 		<head>
 			<meta charset="utf-8">
-			<title>Current Weather Server</title>
+			<title>{{ t "serverTitle" }}</title>
 		</head>
 		<body>
-	         <b>Current Weather at:</b>
+	         <b>{{ t "currentWeatherAt" }}:</b>
              <br><br>
-             <b>Latitude:</b> {{ .Lat }} <br>
-             <b>Longitude:</b> {{ .Long }} <br>
+             <b>{{ t "latitude" }}:</b> {{ num .Lat }} <br>
+             <b>{{ t "longitude" }}:</b> {{ num .Long }} <br>
+             <br>
+             <b>{{ t "dataCollectionTime" }}:</b> {{ .DataCollectionTime }} <br>
+             <b>{{ t "summaryLabel" }}:</b> {{ .Summary }} <br>
              <br>
-             <b>Data Collection Time:</b> {{ .DataCollectionTime }} <br>
-             <b>Summary:</b> {{ .Summary }} <br>
+             {{ with .Recommendations }}
+             <b>{{ t "recommendations" }}:</b> {{ .Summary }} <br>
+             <table>
+               <tr><th>{{ t "activities" }}</th><th>{{ t "score" }}</th><th></th></tr>
+               {{ range .Activities }}
+               <tr><td>{{ t .Activity }}</td><td>{{ .Score }}</td><td>{{ t .Rating }}{{ if .Reasons }} ({{ list .Reasons }}){{ end }}</td></tr>
+               {{ end }}
+             </table>
+             {{ if .Clothing }}<b>{{ t "clothing" }}:</b> {{ list .Clothing }} <br>{{ end }}
+             {{ end }}
+
+             <br><br>
+
+             <a href="getcurrentweather.html?lang={{ lang }}">{{ t "checkAnotherLocation" }}</a> 
+		</body>
+	</html>
+{{ end }}
+
+{{ define "display_current_weather_fields" }}
+	<html lang="{{ lang }}">
+		<head>
+			<meta charset="utf-8">
+			<title>{{ t "serverTitle" }}</title>
+		</head>
+		<body>
+	         <b>{{ t "currentWeather" }}:</b>
+             <br><br>
+             {{ range . }}
+             <b>{{ .Name }}:</b> {{ num .Value }} <br>
+             {{ end }}
 
              <br><br>
 
-             <a href="getcurrentweather.html">Check another location</a> 
+             <a href="getcurrentweather.html?lang={{ lang }}">{{ t "checkAnotherLocation" }}</a> 
 		</body>
 	</html>
 {{ end }}
 
 {{ define "display_current_weather_error" }}
-	<html>
+	<html lang="{{ lang }}">
 		<head>
 			<meta charset="utf-8">
-			<title>Current Weather Server</title>
+			<title>{{ t "serverTitle" }}</title>
 		</head>
 		<body>
-	         <b>Current Weather at:</b>
+	         <b>{{ t "currentWeatherAt" }}:</b>
              <br><br>
-             <b>Error:</b> {{ . }} <br>
+             <b>{{ t "error" }}:</b> {{ . }} <br>
              <br><br>
 
-             <a href="getcurrentweather.html">Check another location</a> 
+             <a href="getcurrentweather.html?lang={{ lang }}">{{ t "checkAnotherLocation" }}</a> 
+		</body>
+	</html>
+{{ end }}
+
+{{ define "compare_weather" }}
+	<html lang="{{ lang }}">
+		<head>
+			<meta charset="utf-8">
+			<title>{{ t "compareTitle" }}</title>
+		</head>
+		<body>
+	         <b>{{ t "compareTitle" }}</b>
+             <br>
+             <br>
+
+			<form name="compareForm" action="/compareweather.html" method="get">
+			  <input type="hidden" name="lang" value="{{ lang }}">
+			  <label for="locations">{{ t "locations" }} ({{ t "locationsFormat" }}):</label>
+			  <br>
+			  <textarea id="locations" name="locations" rows="4" cols="60">{{ .Locations }}</textarea>
+			  <br><br>
+
+				<fieldset>
+				  <legend>{{ t "temperatureUnit" }}:</legend>
+
+				  <div>
+					  <input type="radio" id="imperial" name="units" value="imperial"{{ if or (eq .Units "") (eq .Units "imperial") }} checked{{ end }}>
+					  <label for="imperial">{{ t "fahrenheit" }}</label>
+
+					  <input type="radio" id="metric" name="units" value="metric"{{ if eq .Units "metric" }} checked{{ end }}>
+					  <label for="metric">{{ t "celsius" }}</label>
+
+					  <input type="radio" id="standard" name="units" value="standard"{{ if eq .Units "standard" }} checked{{ end }}>
+					  <label for="standard">{{ t "kelvin" }}</label>
+				  </div>
+				</fieldset>
+
+              <br>
+			  <input type="submit" value="{{ t "compare" }}">
+			</form>
+
+             {{ if .Error }}
+             <b>{{ t "error" }}:</b> {{ .Error }} <br>
+             {{ end }}
+
+             {{ with .Comparison }}
+             <table>
+               <tr><th>{{ t "location" }}</th><th>{{ t "temperature" }}</th><th>{{ t "tempDifference" }}</th>
+                   <th>{{ t "humidity" }}</th><th>{{ t "cloudiness" }}</th><th>{{ t "wind" }}</th><th>{{ t "summaryLabel" }}</th></tr>
+               {{ range .Locations }}
+               {{ if .Weather }}
+               {{ $units := .Weather.Units }}
+               <tr><td>{{ .Label }}</td>
+                   <td>{{ num .Weather.Temp }} °{{ $units }} ({{ num .Weather.TempFeelsLike }} °{{ $units }})</td>
+                   <td>{{ num (index .Deltas "temp") }} °{{ $units }}</td>
+                   <td>{{ num .Weather.HumidityPercent }}%</td>
+                   <td>{{ num .Weather.CloudinessPercent }}%</td>
+                   <td>{{ num .Weather.WindSpeed }} {{ .Weather.WindUnit }}</td>
+                   <td>{{ .Weather.Summary }}</td></tr>
+               {{ else }}
+               <tr><td>{{ .Label }}</td><td colspan="6">{{ t "error" }}: {{ .Error }}</td></tr>
+               {{ end }}
+               {{ end }}
+             </table>
+             <br>
+             {{ end }}
+
+             {{ if .Rankings }}
+             <b>{{ t "rankings" }}:</b> <br>
+             {{ range .Rankings }}
+             <b>{{ t .Name }}:</b> {{ range $inx, $label := .Locations }}{{ if $inx }}, {{ end }}{{ $label }}{{ end }} <br>
+             {{ end }}
+             {{ end }}
+
+             <br><br>
+
+             <a href="getcurrentweather.html?lang={{ lang }}">{{ t "checkAnotherLocation" }}</a>
+		</body>
+	</html>
+{{ end }}
+
+{{ define "api_docs" }}
+	<html>
+		<head>
+			<meta charset="utf-8">
+			<title>Current Weather Server API</title>
+			<style>
+				body { font-family: sans-serif; margin: 2em; }
+				.operation { border: 1px solid #ccc; border-radius: 4px; margin: 1em 0; padding: 0.5em 1em; }
+				.method { display: inline-block; width: 5em; font-weight: bold; }
+				.params td { padding: 2px 8px; vertical-align: top; }
+				pre { background: #f4f4f4; padding: 0.5em; overflow: auto; max-height: 25em; }
+			</style>
+			<script>
+				function element(tag, text) {
+					let e = document.createElement(tag);
+					if (text !== undefined) {
+						e.textContent = text;
+					}
+					return e;
+				}
+
+				function schemaName(schema) {
+					if (!schema) {
+						return "";
+					}
+					if (schema["$ref"]) {
+						return schema["$ref"].split("/").pop();
+					}
+					if (schema.allOf) {
+						return schemaName(schema.allOf[0]);
+					}
+					if (schema.type == "array") {
+						return "[" + schemaName(schema.items) + "]";
+					}
+					return schema.type || "any";
+				}
+
+				function tryOperation(path, method, inputs, output) {
+					let query = new URLSearchParams();
+					for (let input of inputs) {
+						if (input.value.trim() != "") {
+							query.append(input.name, input.value.trim());
+						}
+					}
+					let url = path + (query.toString() ? "?" + query.toString() : "");
+					output.textContent = "GET " + url + "\n";
+					fetch(url).then(response => response.text().then(text => {
+						output.textContent += response.status + " " + response.statusText + "\n\n" + text;
+					})).catch(err => {
+						output.textContent += err;
+					});
+				}
+
+				function renderOperation(container, path, method, operation) {
+					let div = element("div");
+					div.className = "operation";
+					let title = element("div");
+					let methodSpan = element("span", method.toUpperCase());
+					methodSpan.className = "method";
+					title.appendChild(methodSpan);
+					title.appendChild(element("code", path));
+					title.appendChild(element("span", " - " + (operation.summary || "")));
+					div.appendChild(title);
+
+					let inputs = [];
+					if (operation.parameters) {
+						let table = element("table");
+						table.className = "params";
+						for (let param of operation.parameters) {
+							let row = element("tr");
+							row.appendChild(element("td", param.name + (param.required ? " *" : "")));
+							row.appendChild(element("td", param.schema.type));
+							let inputCell = element("td");
+							let input = element("input");
+							input.name = param.name;
+							inputs.push(input);
+							inputCell.appendChild(input);
+							row.appendChild(inputCell);
+							row.appendChild(element("td", param.description || ""));
+							table.appendChild(row);
+						}
+						div.appendChild(table);
+					}
+
+					if (operation.requestBody) {
+						for (let [contentType, media] of Object.entries(operation.requestBody.content || {})) {
+							div.appendChild(element("div", "Request body: " + contentType +
+								(media.schema ? " " + schemaName(media.schema) : "")));
+						}
+					}
+
+					for (let [contentType, media] of Object.entries(operation.responses["200"].content || {})) {
+						div.appendChild(element("div", "Response: " + contentType +
+							(media.schema ? " " + schemaName(media.schema) : "")));
+					}
+
+					if (method == "get" && !path.startsWith("/api/ws") && !path.endsWith("/stream")) {
+						let output = element("pre");
+						let button = element("button", "Try it");
+						button.onclick = () => tryOperation(path, method, inputs, output);
+						div.appendChild(button);
+						div.appendChild(output);
+					}
+
+					container.appendChild(div);
+				}
+
+				function renderSpec(spec) {
+					document.getElementById("title").textContent = spec.info.title + " (version " + spec.info.version + ")";
+					document.getElementById("description").textContent = spec.info.description;
+					let operations = document.getElementById("operations");
+					for (let [path, pathItem] of Object.entries(spec.paths)) {
+						for (let [method, operation] of Object.entries(pathItem)) {
+							renderOperation(operations, path, method, operation);
+						}
+					}
+					let schemas = document.getElementById("schemas");
+					for (let [name, schema] of Object.entries(spec.components.schemas)) {
+						schemas.appendChild(element("h3", name));
+						schemas.appendChild(element("pre", JSON.stringify(schema, null, 2)));
+					}
+				}
+
+				window.onload = function() {
+					fetch("/api/openapi.json")
+						.then(response => response.json())
+						.then(renderSpec)
+						.catch(err => document.getElementById("description").textContent = "Error loading spec: " + err);
+				}
+			</script>
+		</head>
+		<body>
+			<h2 id="title">Current Weather Server API</h2>
+			<div id="description"></div>
+			<p><a href="/api/openapi.json">OpenAPI specification</a></p>
+			<div id="operations"></div>
+			<h2>Schemas</h2>
+			<div id="schemas"></div>
 		</body>
 	</html>
 {{ end }}
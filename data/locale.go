@@ -0,0 +1,562 @@
+package data
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage is used when the client doesn't ask for a supported language
+const DefaultLanguage = "en"
+
+// Catalog holds the messages and number format of one language.
+// Messages that take arguments use explicit argument indexes (%[1]v)
+// so each translation can put them in its own order.
+type Catalog struct {
+	Lang             string
+	DecimalSeparator string
+	GroupSeparator   string
+	// Numbers with fewer integer digits than this aren't grouped (e.g. 1000 vs 10,000)
+	MinGroupingDigits int
+	messages          map[string]string
+}
+
+// The narrow no-break space French uses to group digits and before %
+const narrowNoBreakSpace = "\u202f"
+
+var catalogs = map[string]*Catalog{
+	"en": {
+		Lang: "en", DecimalSeparator: ".", GroupSeparator: ",", MinGroupingDigits: 4,
+		messages: map[string]string{
+			"summary": "The weather will be %[1]v.  Expect %[2]v with a high of %[3]v, a low of %[4]v, " +
+				"and an average temperature of %[5]v.  It'll feel like %[6]v with a humidity of %[7]v%% " +
+				"and a cloud cover of %[8]v%%.",
+			"cold": "cold",
+			"cool": "cool",
+			"warm": "warm",
+			"hot":  "hot",
+			// Labels often used in -subjectiveTempScale.  Other labels aren't translated.
+			"freezing":  "freezing",
+			"chilly":    "chilly",
+			"mild":      "mild",
+			"scorching": "scorching",
+			// The tags of the built in condition rules
+			"muggy":  "muggy",
+			"dry":    "dry",
+			"windy":  "windy",
+			"breezy": "breezy",
+			"gloomy": "gloomy",
+			"crisp":  "crisp",
+			"hazy":   "hazy",
+
+			"conditionsSummary": "It'll also be %[1]v.",
+			"and":               "and",
+
+			// The trends summary
+			"pressureRisingFast":  "The pressure is rising fast.",
+			"pressureRising":      "The pressure is rising.",
+			"pressureFalling":     "The pressure is falling.",
+			"pressureFallingFast": "The pressure is falling fast.",
+			"warmerThanUsual":     "It's %[1]v warmer than usual for this hour.",
+			"colderThanUsual":     "It's %[1]v colder than usual for this hour.",
+
+			// The activities, clothing, ratings, and reasons of the built in recommendation rules
+			"running":          "running",
+			"cycling":          "cycling",
+			"picnic":           "a picnic",
+			"outdoorWork":      "outdoor work",
+			"warmCoat":         "a warm coat",
+			"hatAndGloves":     "a hat and gloves",
+			"lightJacket":      "a light jacket",
+			"sweater":          "a sweater",
+			"tShirt":           "a t-shirt",
+			"umbrella":         "an umbrella",
+			"rainJacket":       "a rain jacket",
+			"windbreaker":      "a windbreaker",
+			"sunglasses":       "sunglasses",
+			"good":             "good",
+			"fair":             "fair",
+			"poor":             "poor",
+			"wet":              "wet",
+			"goodActivities":   "Good weather for %[1]v.",
+			"noGoodActivities": "Not a good day for outdoor activities.",
+			"clothingSummary":  "Wear or bring %[1]v.",
+			"recommendations":  "Recommendations",
+			"activities":       "Activities",
+			"clothing":         "Clothing",
+			"score":            "Score",
+
+			"serverTitle":            "Current Weather Server",
+			"version":                "Version",
+			"getWeatherTitle":        "Get Current Weather for...",
+			"longitude":              "Longitude",
+			"latitude":               "Latitude",
+			"temperatureUnit":        "Temperature Unit",
+			"fahrenheit":             "Fahrenheit",
+			"celsius":                "Celsius",
+			"kelvin":                 "Kelvin",
+			"otherUnits":             "Other Units",
+			"wind":                   "Wind",
+			"pressure":               "Pressure",
+			"visibility":             "Visibility",
+			"rain":                   "Rain",
+			"default":                "Default",
+			"knots":                  "knots",
+			"beaufort":               "Beaufort",
+			"getCurrentWeather":      "Get Current Weather",
+			"currentWeatherAt":       "Current Weather at",
+			"currentWeather":         "Current Weather",
+			"dataCollectionTime":     "Data Collection Time",
+			"summaryLabel":           "Summary",
+			"error":                  "Error",
+			"checkAnotherLocation":   "Check another location",
+			"longitudeMissing":       "Longitude missing",
+			"latitudeMissing":        "Latitude missing",
+			"invalidLongitudeFormat": "Invalid number format for Longitude: ",
+			"invalidLatitudeFormat":  "Invalid number format for Latitude: ",
+			"invalidLongitudeRange":  "Invalid longitude value.  Must be a number between -180 and 180",
+			"invalidLatitudeRange":   "Invalid latitude value.  Must be a number between -90 and 90",
+
+			"compareTitle":     "Compare the Weather at...",
+			"compareLocations": "Compare locations",
+			"compare":          "Compare",
+			"locations":        "Locations",
+			"locationsFormat":  "one name=latitude,longitude per line",
+			"location":         "Location",
+			"temperature":      "Temperature",
+			"tempDifference":   "Difference",
+			"humidity":         "Humidity",
+			"cloudiness":       "Cloudiness",
+			"rankings":         "Rankings",
+			"warmest":          "Warmest",
+			"driest":           "Driest",
+			"leastCloudy":      "Least cloudy",
+			"calmest":          "Calmest",
+		},
+	},
+	"es": {
+		Lang: "es", DecimalSeparator: ",", GroupSeparator: ".", MinGroupingDigits: 5,
+		messages: map[string]string{
+			"summary": "El tiempo será %[1]v.  Pronóstico: %[2]v, con una máxima de %[3]v, una mínima de %[4]v " +
+				"y una temperatura media de %[5]v.  La sensación térmica será de %[6]v con una humedad del %[7]v %% " +
+				"y una nubosidad del %[8]v %%.",
+			"cold":      "frío",
+			"cool":      "fresco",
+			"warm":      "cálido",
+			"hot":       "caluroso",
+			"freezing":  "gélido",
+			"chilly":    "destemplado",
+			"mild":      "templado",
+			"scorching": "abrasador",
+			"muggy":     "bochornoso",
+			"dry":       "seco",
+			"windy":     "ventoso",
+			"breezy":    "con brisa",
+			"gloomy":    "gris",
+			"crisp":     "fresco y despejado",
+			"hazy":      "brumoso",
+
+			"conditionsSummary": "Además, estará %[1]v.",
+			"and":               "y",
+
+			"pressureRisingFast":  "La presión sube rápidamente.",
+			"pressureRising":      "La presión sube.",
+			"pressureFalling":     "La presión baja.",
+			"pressureFallingFast": "La presión baja rápidamente.",
+			"warmerThanUsual":     "Hace %[1]v más de lo habitual a esta hora.",
+			"colderThanUsual":     "Hace %[1]v menos de lo habitual a esta hora.",
+
+			"running":          "correr",
+			"cycling":          "ir en bicicleta",
+			"picnic":           "un pícnic",
+			"outdoorWork":      "trabajar al aire libre",
+			"warmCoat":         "un abrigo",
+			"hatAndGloves":     "gorro y guantes",
+			"lightJacket":      "una chaqueta ligera",
+			"sweater":          "un jersey",
+			"tShirt":           "una camiseta",
+			"umbrella":         "un paraguas",
+			"rainJacket":       "un chubasquero",
+			"windbreaker":      "un cortavientos",
+			"sunglasses":       "gafas de sol",
+			"good":             "bueno",
+			"fair":             "regular",
+			"poor":             "malo",
+			"wet":              "lluvioso",
+			"goodActivities":   "Buen tiempo para %[1]v.",
+			"noGoodActivities": "No es un buen día para actividades al aire libre.",
+			"clothingSummary":  "Lleva %[1]v.",
+			"recommendations":  "Recomendaciones",
+			"activities":       "Actividades",
+			"clothing":         "Ropa",
+			"score":            "Puntuación",
+
+			"serverTitle":            "Servidor del Tiempo Actual",
+			"version":                "Versión",
+			"getWeatherTitle":        "Consultar el tiempo actual en...",
+			"longitude":              "Longitud",
+			"latitude":               "Latitud",
+			"temperatureUnit":        "Unidad de temperatura",
+			"fahrenheit":             "Fahrenheit",
+			"celsius":                "Celsius",
+			"kelvin":                 "Kelvin",
+			"otherUnits":             "Otras unidades",
+			"wind":                   "Viento",
+			"pressure":               "Presión",
+			"visibility":             "Visibilidad",
+			"rain":                   "Lluvia",
+			"default":                "Predeterminada",
+			"knots":                  "nudos",
+			"beaufort":               "Beaufort",
+			"getCurrentWeather":      "Consultar el tiempo actual",
+			"currentWeatherAt":       "Tiempo actual en",
+			"currentWeather":         "Tiempo actual",
+			"dataCollectionTime":     "Hora de la observación",
+			"summaryLabel":           "Resumen",
+			"error":                  "Error",
+			"checkAnotherLocation":   "Consultar otra ubicación",
+			"longitudeMissing":       "Falta la longitud",
+			"latitudeMissing":        "Falta la latitud",
+			"invalidLongitudeFormat": "Formato de número no válido para la longitud: ",
+			"invalidLatitudeFormat":  "Formato de número no válido para la latitud: ",
+			"invalidLongitudeRange":  "Longitud no válida.  Debe ser un número entre -180 y 180",
+			"invalidLatitudeRange":   "Latitud no válida.  Debe ser un número entre -90 y 90",
+
+			"compareTitle":     "Comparar el tiempo en...",
+			"compareLocations": "Comparar ubicaciones",
+			"compare":          "Comparar",
+			"locations":        "Ubicaciones",
+			"locationsFormat":  "un nombre=latitud,longitud por línea",
+			"location":         "Ubicación",
+			"temperature":      "Temperatura",
+			"tempDifference":   "Diferencia",
+			"humidity":         "Humedad",
+			"cloudiness":       "Nubosidad",
+			"rankings":         "Clasificaciones",
+			"warmest":          "Más cálido",
+			"driest":           "Más seco",
+			"leastCloudy":      "Menos nublado",
+			"calmest":          "Más tranquilo",
+		},
+	},
+	"fr": {
+		Lang: "fr", DecimalSeparator: ",", GroupSeparator: narrowNoBreakSpace, MinGroupingDigits: 4,
+		messages: map[string]string{
+			"summary": "Le temps sera %[1]v.  Prévisions : %[2]v, avec une maximale de %[3]v, une minimale de %[4]v " +
+				"et une température moyenne de %[5]v.  Le ressenti sera de %[6]v avec une humidité de %[7]v" +
+				narrowNoBreakSpace + "%% et une couverture nuageuse de %[8]v" + narrowNoBreakSpace + "%%.",
+			"cold":      "froid",
+			"cool":      "frais",
+			"warm":      "doux",
+			"hot":       "chaud",
+			"freezing":  "glacial",
+			"chilly":    "frisquet",
+			"mild":      "clément",
+			"scorching": "torride",
+			"muggy":     "lourd",
+			"dry":       "sec",
+			"windy":     "venteux",
+			"breezy":    "frais avec de la brise",
+			"gloomy":    "maussade",
+			"crisp":     "vif",
+			"hazy":      "brumeux",
+
+			"conditionsSummary": "Il fera aussi %[1]v.",
+			"and":               "et",
+
+			"pressureRisingFast":  "La pression monte rapidement.",
+			"pressureRising":      "La pression monte.",
+			"pressureFalling":     "La pression baisse.",
+			"pressureFallingFast": "La pression baisse rapidement.",
+			"warmerThanUsual":     "Il fait %[1]v de plus que d'habitude à cette heure.",
+			"colderThanUsual":     "Il fait %[1]v de moins que d'habitude à cette heure.",
+
+			"running":          "la course à pied",
+			"cycling":          "le vélo",
+			"picnic":           "un pique-nique",
+			"outdoorWork":      "le travail en extérieur",
+			"warmCoat":         "un manteau chaud",
+			"hatAndGloves":     "un bonnet et des gants",
+			"lightJacket":      "une veste légère",
+			"sweater":          "un pull",
+			"tShirt":           "un t-shirt",
+			"umbrella":         "un parapluie",
+			"rainJacket":       "une veste de pluie",
+			"windbreaker":      "un coupe-vent",
+			"sunglasses":       "des lunettes de soleil",
+			"good":             "bon",
+			"fair":             "moyen",
+			"poor":             "mauvais",
+			"wet":              "pluvieux",
+			"goodActivities":   "Beau temps pour %[1]v.",
+			"noGoodActivities": "Ce n'est pas une bonne journée pour les activités en plein air.",
+			"clothingSummary":  "Prévoyez %[1]v.",
+			"recommendations":  "Recommandations",
+			"activities":       "Activités",
+			"clothing":         "Vêtements",
+			"score":            "Score",
+
+			"serverTitle":            "Serveur de météo actuelle",
+			"version":                "Version",
+			"getWeatherTitle":        "Consulter la météo actuelle à...",
+			"longitude":              "Longitude",
+			"latitude":               "Latitude",
+			"temperatureUnit":        "Unité de température",
+			"fahrenheit":             "Fahrenheit",
+			"celsius":                "Celsius",
+			"kelvin":                 "Kelvin",
+			"otherUnits":             "Autres unités",
+			"wind":                   "Vent",
+			"pressure":               "Pression",
+			"visibility":             "Visibilité",
+			"rain":                   "Pluie",
+			"default":                "Par défaut",
+			"knots":                  "nœuds",
+			"beaufort":               "Beaufort",
+			"getCurrentWeather":      "Consulter la météo actuelle",
+			"currentWeatherAt":       "Météo actuelle à",
+			"currentWeather":         "Météo actuelle",
+			"dataCollectionTime":     "Heure de l'observation",
+			"summaryLabel":           "Résumé",
+			"error":                  "Erreur",
+			"checkAnotherLocation":   "Consulter un autre lieu",
+			"longitudeMissing":       "Longitude manquante",
+			"latitudeMissing":        "Latitude manquante",
+			"invalidLongitudeFormat": "Format de nombre invalide pour la longitude : ",
+			"invalidLatitudeFormat":  "Format de nombre invalide pour la latitude : ",
+			"invalidLongitudeRange":  "Longitude invalide.  Doit être un nombre entre -180 et 180",
+			"invalidLatitudeRange":   "Latitude invalide.  Doit être un nombre entre -90 et 90",
+
+			"compareTitle":     "Comparer la météo à...",
+			"compareLocations": "Comparer des lieux",
+			"compare":          "Comparer",
+			"locations":        "Lieux",
+			"locationsFormat":  "un nom=latitude,longitude par ligne",
+			"location":         "Lieu",
+			"temperature":      "Température",
+			"tempDifference":   "Écart",
+			"humidity":         "Humidité",
+			"cloudiness":       "Nébulosité",
+			"rankings":         "Classements",
+			"warmest":          "Le plus chaud",
+			"driest":           "Le plus sec",
+			"leastCloudy":      "Le moins nuageux",
+			"calmest":          "Le plus calme",
+		},
+	},
+	"de": {
+		Lang: "de", DecimalSeparator: ",", GroupSeparator: ".", MinGroupingDigits: 4,
+		messages: map[string]string{
+			"summary": "Das Wetter wird %[1]v.  Erwartet: %[2]v, mit einer Höchsttemperatur von %[3]v, einer " +
+				"Tiefsttemperatur von %[4]v und einer Durchschnittstemperatur von %[5]v.  Gefühlt sind es %[6]v " +
+				"bei einer Luftfeuchtigkeit von %[7]v %% und einer Bewölkung von %[8]v %%.",
+			"cold":      "kalt",
+			"cool":      "kühl",
+			"warm":      "warm",
+			"hot":       "heiß",
+			"freezing":  "eisig",
+			"chilly":    "frisch",
+			"mild":      "mild",
+			"scorching": "glühend heiß",
+			"muggy":     "schwül",
+			"dry":       "trocken",
+			"windy":     "windig",
+			"breezy":    "luftig",
+			"gloomy":    "trüb",
+			"crisp":     "frisch und klar",
+			"hazy":      "diesig",
+
+			"conditionsSummary": "Außerdem wird es %[1]v.",
+			"and":               "und",
+
+			"pressureRisingFast":  "Der Luftdruck steigt schnell.",
+			"pressureRising":      "Der Luftdruck steigt.",
+			"pressureFalling":     "Der Luftdruck fällt.",
+			"pressureFallingFast": "Der Luftdruck fällt schnell.",
+			"warmerThanUsual":     "Es ist %[1]v wärmer als üblich für diese Uhrzeit.",
+			"colderThanUsual":     "Es ist %[1]v kälter als üblich für diese Uhrzeit.",
+
+			"running":          "Laufen",
+			"cycling":          "Radfahren",
+			"picnic":           "ein Picknick",
+			"outdoorWork":      "Arbeiten im Freien",
+			"warmCoat":         "einen warmen Mantel",
+			"hatAndGloves":     "Mütze und Handschuhe",
+			"lightJacket":      "eine leichte Jacke",
+			"sweater":          "einen Pullover",
+			"tShirt":           "ein T-Shirt",
+			"umbrella":         "einen Regenschirm",
+			"rainJacket":       "eine Regenjacke",
+			"windbreaker":      "eine Windjacke",
+			"sunglasses":       "eine Sonnenbrille",
+			"good":             "gut",
+			"fair":             "mittel",
+			"poor":             "schlecht",
+			"wet":              "nass",
+			"goodActivities":   "Gutes Wetter für %[1]v.",
+			"noGoodActivities": "Kein guter Tag für Aktivitäten im Freien.",
+			"clothingSummary":  "Mitnehmen: %[1]v.",
+			"recommendations":  "Empfehlungen",
+			"activities":       "Aktivitäten",
+			"clothing":         "Kleidung",
+			"score":            "Punktzahl",
+
+			"serverTitle":            "Server für das aktuelle Wetter",
+			"version":                "Version",
+			"getWeatherTitle":        "Aktuelles Wetter abrufen für...",
+			"longitude":              "Längengrad",
+			"latitude":               "Breitengrad",
+			"temperatureUnit":        "Temperatureinheit",
+			"fahrenheit":             "Fahrenheit",
+			"celsius":                "Celsius",
+			"kelvin":                 "Kelvin",
+			"otherUnits":             "Weitere Einheiten",
+			"wind":                   "Wind",
+			"pressure":               "Luftdruck",
+			"visibility":             "Sichtweite",
+			"rain":                   "Regen",
+			"default":                "Standard",
+			"knots":                  "Knoten",
+			"beaufort":               "Beaufort",
+			"getCurrentWeather":      "Aktuelles Wetter abrufen",
+			"currentWeatherAt":       "Aktuelles Wetter bei",
+			"currentWeather":         "Aktuelles Wetter",
+			"dataCollectionTime":     "Zeitpunkt der Beobachtung",
+			"summaryLabel":           "Zusammenfassung",
+			"error":                  "Fehler",
+			"checkAnotherLocation":   "Anderen Ort abfragen",
+			"longitudeMissing":       "Längengrad fehlt",
+			"latitudeMissing":        "Breitengrad fehlt",
+			"invalidLongitudeFormat": "Ungültiges Zahlenformat für den Längengrad: ",
+			"invalidLatitudeFormat":  "Ungültiges Zahlenformat für den Breitengrad: ",
+			"invalidLongitudeRange":  "Ungültiger Längengrad.  Muss eine Zahl zwischen -180 und 180 sein",
+			"invalidLatitudeRange":   "Ungültiger Breitengrad.  Muss eine Zahl zwischen -90 und 90 sein",
+
+			"compareTitle":     "Wetter vergleichen für...",
+			"compareLocations": "Orte vergleichen",
+			"compare":          "Vergleichen",
+			"locations":        "Orte",
+			"locationsFormat":  "ein Name=Breitengrad,Längengrad pro Zeile",
+			"location":         "Ort",
+			"temperature":      "Temperatur",
+			"tempDifference":   "Differenz",
+			"humidity":         "Luftfeuchtigkeit",
+			"cloudiness":       "Bewölkung",
+			"rankings":         "Ranglisten",
+			"warmest":          "Am wärmsten",
+			"driest":           "Am trockensten",
+			"leastCloudy":      "Am wenigsten bewölkt",
+			"calmest":          "Am windstillsten",
+		},
+	},
+}
+
+// SupportedLanguages returns the languages that have a catalog
+func SupportedLanguages() []string {
+	languages := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// CatalogFor returns the catalog of a language or its base language
+// (e.g. "es" for "es-MX").  The English catalog is returned if there's neither.
+func CatalogFor(lang string) *Catalog {
+	base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(lang)), "-")
+
+	if catalog, found := catalogs[base]; found {
+		return catalog
+	}
+
+	return catalogs[DefaultLanguage]
+}
+
+// ParseLanguage returns the supported language named by lang (e.g. "fr" for "fr-CA")
+func ParseLanguage(lang string) (string, error) {
+	base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(lang)), "-")
+
+	if _, found := catalogs[base]; !found {
+		return "", fmt.Errorf("Unsupported language: %v (supported languages are %v)", lang,
+			strings.Join(SupportedLanguages(), ", "))
+	}
+
+	return base, nil
+}
+
+// Text returns the message with the key.  Messages missing from a catalog
+// fall back to English and then to the key itself.
+func (c *Catalog) Text(key string) string {
+	if message, found := c.messages[key]; found {
+		return message
+	}
+
+	if message, found := catalogs[DefaultLanguage].messages[key]; found {
+		return message
+	}
+
+	return key
+}
+
+// Format formats the message with the key using fmt.Sprintf
+func (c *Catalog) Format(key string, args ...interface{}) string {
+	return fmt.Sprintf(c.Text(key), args...)
+}
+
+// List joins the items the way the language does (e.g. "a, b and c")
+func (c *Catalog) List(items []string) string {
+	if len(items) <= 1 {
+		return strings.Join(items, "")
+	}
+
+	return strings.Join(items[:len(items)-1], ", ") + " " + c.Text("and") + " " + items[len(items)-1]
+}
+
+// TextList translates the keys (e.g. condition tags) and joins them with List
+func (c *Catalog) TextList(keys []string) string {
+	texts := make([]string, len(keys))
+	for inx, key := range keys {
+		texts[inx] = c.Text(key)
+	}
+	return c.List(texts)
+}
+
+// FormatNumber formats a float with the language's decimal and group separators.
+// Other values are formatted with %v.
+func (c *Catalog) FormatNumber(value interface{}) string {
+	number, ok := value.(float64)
+
+	if !ok {
+		return fmt.Sprintf("%v", value)
+	}
+
+	str := strconv.FormatFloat(number, 'f', -1, 64)
+	sign := ""
+
+	if strings.HasPrefix(str, "-") {
+		sign, str = "-", str[1:]
+	}
+
+	integer, fraction, hasFraction := strings.Cut(str, ".")
+
+	if len(integer) >= c.MinGroupingDigits {
+		var grouped strings.Builder
+
+		for inx, digit := range integer {
+			if inx > 0 && (len(integer)-inx)%3 == 0 {
+				grouped.WriteString(c.GroupSeparator)
+			}
+			grouped.WriteRune(digit)
+		}
+
+		integer = grouped.String()
+	}
+
+	if hasFraction {
+		return sign + integer + c.DecimalSeparator + fraction
+	}
+
+	return sign + integer
+}
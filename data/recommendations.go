@@ -0,0 +1,246 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Every activity starts with this score and its rules add to (or take from) it
+const maxActivityScore = 100
+
+// The lowest scores rated good and fair.  Lower scores are poor.
+const (
+	goodActivityScore = 70
+	fairActivityScore = 40
+)
+
+// The most rules a recommendation rules file can have in total
+const maxRecommendationRules = 200
+
+// ActivityRules score one activity (e.g. running)
+type ActivityRules struct {
+	Activity string        `json:"activity"`
+	Rules    []ScoringRule `json:"rules"`
+}
+
+// ScoringRule adds Score (usually negative) to an activity's score when all of its
+// conditions are true.  Reason (e.g. wet) tells the client why the score changed.
+type ScoringRule struct {
+	Reason     string      `json:"reason"`
+	Score      int         `json:"score"`
+	Conditions []Condition `json:"conditions"`
+}
+
+// ClothingRule recommends an item of clothing (e.g. umbrella) when all of its conditions are true
+type ClothingRule struct {
+	Item       string      `json:"item"`
+	Conditions []Condition `json:"conditions"`
+}
+
+// recommendationRulesFile is the format of the -recommendationRules file
+type recommendationRulesFile struct {
+	Activities []ActivityRules `json:"activities"`
+	Clothing   []ClothingRule  `json:"clothing"`
+}
+
+// ActivityScore is how good the weather is for an activity from 0 to 100
+type ActivityScore struct {
+	Activity string   `json:"activity"`
+	Score    int      `json:"score"`
+	Rating   string   `json:"rating"`  // good, fair, or poor
+	Reasons  []string `json:"reasons"` // the reasons of the rules that changed the score
+}
+
+// Recommendations is returned by /api/recommendations
+type Recommendations struct {
+	DataCollectionTime string          `json:"dataCollectionTime"`
+	Lat                float64         `json:"latitude"`
+	Long               float64         `json:"longitude"`
+	Activities         []ActivityScore `json:"activities"` // from the best to the worst
+	Clothing           []string        `json:"clothing"`
+	Summary            string          `json:"summary"`
+}
+
+// The rules used when the server isn't started with -recommendationRules.
+// The same rules are in recommendations/recommendations.json.
+var recommendationRules = recommendationRulesFile{
+	Activities: []ActivityRules{
+		{Activity: "running", Rules: []ScoringRule{
+			{"cold", -40, []Condition{{"tempFeelsLike", "<", 0}}},
+			{"hot", -50, []Condition{{"tempFeelsLike", ">", 27}}},
+			{"muggy", -20, []Condition{{"dewPoint", ">=", 18}}},
+			{"wet", -30, []Condition{{"rain1h", ">", 0}}},
+			{"windy", -30, []Condition{{"windSpeed", ">=", 10.8}}},
+		}},
+		{Activity: "cycling", Rules: []ScoringRule{
+			{"cold", -40, []Condition{{"tempFeelsLike", "<", 5}}},
+			{"hot", -40, []Condition{{"tempFeelsLike", ">", 32}}},
+			{"wet", -50, []Condition{{"rain1h", ">", 0}}},
+			{"windy", -40, []Condition{{"windSpeed", ">=", 8}}},
+		}},
+		{Activity: "picnic", Rules: []ScoringRule{
+			{"cold", -50, []Condition{{"tempFeelsLike", "<", 15}}},
+			{"hot", -40, []Condition{{"tempFeelsLike", ">", 32}}},
+			{"wet", -80, []Condition{{"rain1h", ">", 0}}},
+			{"windy", -30, []Condition{{"windSpeed", ">=", 8}}},
+			{"gloomy", -20, []Condition{{"cloudinessPercent", ">=", 85}}},
+		}},
+		{Activity: "outdoorWork", Rules: []ScoringRule{
+			{"cold", -50, []Condition{{"tempFeelsLike", "<", -10}}},
+			{"hot", -60, []Condition{{"tempFeelsLike", ">", 35}}},
+			{"wet", -40, []Condition{{"rain1h", ">=", 2.5}}},
+			{"windy", -40, []Condition{{"windSpeed", ">=", 13.9}}},
+		}},
+	},
+	Clothing: []ClothingRule{
+		{"warmCoat", []Condition{{"tempFeelsLike", "<=", 5}}},
+		{"hatAndGloves", []Condition{{"tempFeelsLike", "<=", 0}}},
+		{"lightJacket", []Condition{{"tempFeelsLike", ">", 5}, {"tempFeelsLike", "<=", 15}}},
+		{"sweater", []Condition{{"tempFeelsLike", ">", 15}, {"tempFeelsLike", "<=", 20}}},
+		{"tShirt", []Condition{{"tempFeelsLike", ">", 20}}},
+		{"umbrella", []Condition{{"rain1h", ">", 0}, {"windSpeed", "<", 8}}},
+		{"rainJacket", []Condition{{"rain1h", ">", 0}, {"windSpeed", ">=", 8}}},
+		{"windbreaker", []Condition{{"windSpeed", ">=", 8}, {"tempFeelsLike", ">", 5}}},
+		{"sunglasses", []Condition{{"cloudinessPercent", "<=", 30}}},
+	},
+}
+
+// LoadRecommendationRules replaces the activity and clothing rules with the ones in a json file
+func LoadRecommendationRules(path string) error {
+	contents, err := os.ReadFile(path)
+
+	if err != nil {
+		return fmt.Errorf("Error reading recommendation rules %v: %v", path, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(contents))
+	decoder.DisallowUnknownFields()
+
+	var file recommendationRulesFile
+
+	if err := decoder.Decode(&file); err != nil {
+		return fmt.Errorf("Error parsing recommendation rules %v: %v", path, err)
+	}
+
+	if err := validateRecommendationRules(file); err != nil {
+		return fmt.Errorf("Invalid recommendation rules in %v: %v", path, err)
+	}
+
+	recommendationRules = file
+	return nil
+}
+
+func validateRecommendationRules(file recommendationRulesFile) error {
+	ruleCount := len(file.Clothing)
+	activities := map[string]bool{}
+
+	for _, activity := range file.Activities {
+		if !bandLabelPattern.MatchString(activity.Activity) || activities[activity.Activity] {
+			return fmt.Errorf("Invalid or duplicate activity: %q", activity.Activity)
+		}
+
+		activities[activity.Activity] = true
+		ruleCount += len(activity.Rules)
+
+		for _, rule := range activity.Rules {
+			if !bandLabelPattern.MatchString(rule.Reason) {
+				return fmt.Errorf("Invalid reason for %v: %q", activity.Activity, rule.Reason)
+			}
+
+			if err := validateConditions(rule.Conditions); err != nil {
+				return fmt.Errorf("%v (%v): %v", activity.Activity, rule.Reason, err)
+			}
+		}
+	}
+
+	for _, rule := range file.Clothing {
+		if !bandLabelPattern.MatchString(rule.Item) {
+			return fmt.Errorf("Invalid clothing item: %q", rule.Item)
+		}
+
+		if err := validateConditions(rule.Conditions); err != nil {
+			return fmt.Errorf("%v: %v", rule.Item, err)
+		}
+	}
+
+	if ruleCount > maxRecommendationRules {
+		return fmt.Errorf("Too many rules: %v (the maximum is %v)", ruleCount, maxRecommendationRules)
+	}
+
+	return nil
+}
+
+// Recommend scores the activities and picks the clothing for the weather, which must still be
+// in Open Weather's metric units, the units of the rules.  The summary is in data.Lang.
+func Recommend(data *CurrentWeatherData) *Recommendations {
+	recommendations := &Recommendations{
+		DataCollectionTime: data.DataCollectionTime,
+		Lat:                data.Coord.Lat,
+		Long:               data.Coord.Lon,
+		Activities:         []ActivityScore{},
+		Clothing:           []string{},
+	}
+
+	for _, activity := range recommendationRules.Activities {
+		score := ActivityScore{Activity: activity.Activity, Score: maxActivityScore, Reasons: []string{}}
+
+		for _, rule := range activity.Rules {
+			if conditionsMatch(rule.Conditions, data) {
+				score.Score += rule.Score
+				score.Reasons = append(score.Reasons, rule.Reason)
+			}
+		}
+
+		score.Score = min(max(score.Score, 0), maxActivityScore)
+		score.Rating = activityRating(score.Score)
+		recommendations.Activities = append(recommendations.Activities, score)
+	}
+
+	sort.SliceStable(recommendations.Activities, func(i, j int) bool {
+		return recommendations.Activities[i].Score > recommendations.Activities[j].Score
+	})
+
+	for _, rule := range recommendationRules.Clothing {
+		if conditionsMatch(rule.Conditions, data) {
+			recommendations.Clothing = append(recommendations.Clothing, rule.Item)
+		}
+	}
+
+	recommendations.Summary = recommendationSummary(recommendations, CatalogFor(data.Lang))
+	return recommendations
+}
+
+func activityRating(score int) string {
+	if score >= goodActivityScore {
+		return "good"
+	} else if score >= fairActivityScore {
+		return "fair"
+	}
+	return "poor"
+}
+
+// recommendationSummary lists the activities rated good and the clothing
+func recommendationSummary(recommendations *Recommendations, catalog *Catalog) string {
+	good := []string{}
+
+	for _, activity := range recommendations.Activities {
+		if activity.Rating == "good" {
+			good = append(good, activity.Activity)
+		}
+	}
+
+	summary := catalog.Text("noGoodActivities")
+
+	if len(good) > 0 {
+		summary = catalog.Format("goodActivities", catalog.TextList(good))
+	}
+
+	if len(recommendations.Clothing) > 0 {
+		summary += "  " + catalog.Format("clothingSummary", catalog.TextList(recommendations.Clothing))
+	}
+
+	return summary
+}
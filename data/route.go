@@ -0,0 +1,302 @@
+package data
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// The mean radius of the earth used for distances along a route
+const earthRadiusKm = 6371.0088
+
+// RoutePoint is a point of a route
+type RoutePoint struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// RouteSegment is a stretch of a route from StartKm to EndKm along it.  Middle is the
+// point halfway along the stretch.
+type RouteSegment struct {
+	StartKm float64
+	EndKm   float64
+	Middle  RoutePoint
+}
+
+func (point RoutePoint) validate() error {
+	if point.Latitude < -90 || point.Latitude > 90 || point.Longitude < -180 || point.Longitude > 180 ||
+		math.IsNaN(point.Latitude) || math.IsNaN(point.Longitude) {
+		return fmt.Errorf("Invalid route point: %v,%v (the latitude must be between -90 and 90 and the "+
+			"longitude between -180 and 180)", point.Latitude, point.Longitude)
+	}
+	return nil
+}
+
+// gpxPoint is a trkpt or rtept of a GPX file
+type gpxPoint struct {
+	Latitude  float64 `xml:"lat,attr"`
+	Longitude float64 `xml:"lon,attr"`
+}
+
+// gpxFile is the part of a GPX file that makes up the route
+type gpxFile struct {
+	Tracks []struct {
+		Segments []struct {
+			Points []gpxPoint `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+	Routes []struct {
+		Points []gpxPoint `xml:"rtept"`
+	} `xml:"rte"`
+}
+
+// ParseGPX returns the points of a GPX file's tracks, or of its routes when it has no tracks.
+// The tracks (and their segments) are joined in the order they're in the file.
+func ParseGPX(body []byte) ([]RoutePoint, error) {
+	file := gpxFile{}
+
+	if err := xml.Unmarshal(body, &file); err != nil {
+		return nil, fmt.Errorf("Invalid GPX: %v", err)
+	}
+
+	points := []RoutePoint{}
+
+	for _, track := range file.Tracks {
+		for _, segment := range track.Segments {
+			for _, point := range segment.Points {
+				points = append(points, RoutePoint{Latitude: point.Latitude, Longitude: point.Longitude})
+			}
+		}
+	}
+
+	if len(points) == 0 {
+		for _, route := range file.Routes {
+			for _, point := range route.Points {
+				points = append(points, RoutePoint{Latitude: point.Latitude, Longitude: point.Longitude})
+			}
+		}
+	}
+
+	return points, validateRoute(points)
+}
+
+// geoJSONObject is the part of a GeoJSON LineString, Feature, or FeatureCollection that makes up a route
+type geoJSONObject struct {
+	Type        string           `json:"type"`
+	Coordinates json.RawMessage  `json:"coordinates"` // read once the type is known to be a LineString
+	Geometry    *geoJSONObject   `json:"geometry"`
+	Features    []*geoJSONObject `json:"features"`
+}
+
+// ParseGeoJSONLine returns the points of a GeoJSON LineString, a Feature whose geometry is a
+// LineString, or a FeatureCollection of them (joined in order).  Altitudes are ignored.
+func ParseGeoJSONLine(body []byte) ([]RoutePoint, error) {
+	object := &geoJSONObject{}
+
+	if err := json.Unmarshal(body, object); err != nil {
+		return nil, fmt.Errorf("Invalid GeoJSON: %v", err)
+	}
+
+	lines := []*geoJSONObject{}
+
+	switch object.Type {
+	case "LineString":
+		lines = append(lines, object)
+	case "Feature":
+		lines = append(lines, object.Geometry)
+	case "FeatureCollection":
+		for _, feature := range object.Features {
+			if feature != nil {
+				lines = append(lines, feature.Geometry)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported GeoJSON type: %v (must be LineString, Feature, or FeatureCollection)", object.Type)
+	}
+
+	points := []RoutePoint{}
+
+	for _, line := range lines {
+		if line == nil || line.Type != "LineString" {
+			return nil, errors.New("Every GeoJSON geometry of a route must be a LineString")
+		}
+
+		positions := [][]float64{}
+
+		if err := json.Unmarshal(line.Coordinates, &positions); err != nil {
+			return nil, fmt.Errorf("Invalid GeoJSON LineString coordinates: %v", err)
+		}
+
+		for _, coordinates := range positions {
+			if len(coordinates) < 2 {
+				return nil, fmt.Errorf("Invalid GeoJSON position: %v (must be [longitude, latitude])", coordinates)
+			}
+
+			points = append(points, RoutePoint{Latitude: coordinates[1], Longitude: coordinates[0]})
+		}
+	}
+
+	return points, validateRoute(points)
+}
+
+// DecodePolyline decodes an encoded polyline (the Google Maps format).  Precision is the
+// number of decimals the coordinates were encoded with, 5 for Google and 6 for OSRM's polyline6.
+func DecodePolyline(str string, precision int) ([]RoutePoint, error) {
+	factor := math.Pow10(precision)
+	points := []RoutePoint{}
+	latitude, longitude := 0, 0
+	inx := 0
+
+	// next decodes the next value, a zigzag encoded integer in 5 bit chunks from the lowest
+	next := func() (int, error) {
+		result, shift := 0, 0
+
+		for {
+			if inx >= len(str) {
+				return 0, errors.New("Invalid polyline: it ends in the middle of a coordinate")
+			}
+
+			chunk := int(str[inx]) - 63
+			inx++
+
+			if chunk < 0 || chunk > 63 {
+				return 0, fmt.Errorf("Invalid polyline: unexpected character %q at %v", str[inx-1], inx-1)
+			}
+
+			if shift > 30 {
+				return 0, errors.New("Invalid polyline: a coordinate is too long")
+			}
+
+			result |= (chunk & 0x1f) << shift
+			shift += 5
+
+			if chunk < 0x20 {
+				break
+			}
+		}
+
+		if result&1 != 0 {
+			return ^(result >> 1), nil
+		}
+		return result >> 1, nil
+	}
+
+	for inx < len(str) {
+		deltaLatitude, err := next()
+
+		if err != nil {
+			return nil, err
+		}
+
+		deltaLongitude, err := next()
+
+		if err != nil {
+			return nil, err
+		}
+
+		latitude += deltaLatitude
+		longitude += deltaLongitude
+		points = append(points, RoutePoint{Latitude: float64(latitude) / factor, Longitude: float64(longitude) / factor})
+	}
+
+	return points, validateRoute(points)
+}
+
+func validateRoute(points []RoutePoint) error {
+	if len(points) < 2 {
+		return fmt.Errorf("A route must have at least 2 points (it has %v)", len(points))
+	}
+
+	for _, point := range points {
+		if err := point.validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DistanceKm is the great circle distance between two points
+func DistanceKm(from, to RoutePoint) float64 {
+	radians := math.Pi / 180
+	deltaLatitude := (to.Latitude - from.Latitude) * radians
+	deltaLongitude := (to.Longitude - from.Longitude) * radians
+	a := math.Pow(math.Sin(deltaLatitude/2), 2) +
+		math.Cos(from.Latitude*radians)*math.Cos(to.Latitude*radians)*math.Pow(math.Sin(deltaLongitude/2), 2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// RouteLengthKm is the distance along the route
+func RouteLengthKm(points []RoutePoint) float64 {
+	length := 0.0
+
+	for inx := 1; inx < len(points); inx++ {
+		length += DistanceKm(points[inx-1], points[inx])
+	}
+
+	return length
+}
+
+// RouteSegmentCount is the number of segments SplitRoute makes of a route of the length
+func RouteSegmentCount(lengthKm, spacingKm float64) float64 {
+	// The tolerance keeps a route that's a whole number of segments long from getting an extra tiny one
+	return math.Max(1, math.Ceil(lengthKm/spacingKm-1e-9))
+}
+
+// SplitRoute divides the route into segments spacingKm long, except the last, which is what's left
+func SplitRoute(points []RoutePoint, spacingKm float64) []RouteSegment {
+	length := RouteLengthKm(points)
+	segments := make([]RouteSegment, int(RouteSegmentCount(length, spacingKm)))
+
+	// The middles are found in one walk along the route since they're in order
+	walked := 0.0 // the distance to points[inx]
+	inx := 0
+
+	for segmentInx := range segments {
+		segment := &segments[segmentInx]
+		segment.StartKm = float64(segmentInx) * spacingKm
+		segment.EndKm = math.Min(length, segment.StartKm+spacingKm)
+		middle := (segment.StartKm + segment.EndKm) / 2
+
+		for inx < len(points)-2 && walked+DistanceKm(points[inx], points[inx+1]) < middle {
+			walked += DistanceKm(points[inx], points[inx+1])
+			inx++
+		}
+
+		step := DistanceKm(points[inx], points[inx+1])
+		fraction := 0.0
+
+		if step > 0 {
+			fraction = math.Max(0, math.Min(1, (middle-walked)/step))
+		}
+
+		segment.Middle = interpolate(points[inx], points[inx+1], fraction)
+	}
+
+	return segments
+}
+
+// interpolate returns the point the fraction of the way from one point to the next.  Points
+// are close together along a route so the straight line in degrees is near enough.
+func interpolate(from, to RoutePoint, fraction float64) RoutePoint {
+	deltaLongitude := to.Longitude - from.Longitude
+
+	// Go the short way across the antimeridian
+	if deltaLongitude > 180 {
+		deltaLongitude -= 360
+	} else if deltaLongitude < -180 {
+		deltaLongitude += 360
+	}
+
+	longitude := from.Longitude + deltaLongitude*fraction
+
+	if longitude > 180 {
+		longitude -= 360
+	} else if longitude < -180 {
+		longitude += 360
+	}
+
+	return RoutePoint{Latitude: roundTo(from.Latitude+(to.Latitude-from.Latitude)*fraction, 6), Longitude: roundTo(longitude, 6)}
+}
@@ -0,0 +1,202 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// DefaultSummaryStyle is the built in summary sentence
+const DefaultSummaryStyle = "default"
+
+// The extension of the summary template files
+const summaryTemplateExtension = ".tmpl"
+
+// The longest summary a template may render
+const maxSummaryBytes = 4096
+
+var summaryStyleNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// SummaryModel is what summary templates are rendered over
+type SummaryModel struct {
+	Weather *SimplifiedWeather  // the same data returned by /api/currentweather
+	Data    *CurrentWeatherData // the data returned by Open Weather
+	Units   UnitSystem
+	Lang    string
+}
+
+// The summary templates by style and then language.  Each template is parsed
+// once per language so num and t use that language's catalog.
+var summaryTemplates = map[string]map[string]*template.Template{}
+
+// summaryFuncs returns the functions available to summary templates.
+// None of them have side effects or access anything outside the model.
+func summaryFuncs(catalog *Catalog) template.FuncMap {
+	return template.FuncMap{
+		// round rounds a number to the number of decimals
+		"round": func(value float64, decimals int) float64 {
+			return roundTo(value, decimals)
+		},
+		// num formats a number the way the language does (e.g. 9,2 in German)
+		"num": catalog.FormatNumber,
+		// t translates a message (e.g. a subjectiveTemp value) into the language
+		"t": catalog.Text,
+		// list translates and joins tags (e.g. the conditions) the way the language does
+		"list": catalog.TextList,
+		// label returns the label written after a value in a unit (e.g. °C for C)
+		"label": UnitLabel,
+		"lower": strings.ToLower,
+		"upper": strings.ToUpper,
+		// abs is handy for wind directions and temperature differences
+		"abs": math.Abs,
+	}
+}
+
+// UnitLabel returns the label written after a value in the unit.
+// Temperatures get a degree sign.  Other units are their own label.
+func UnitLabel(unit interface{}) string {
+	if temperatureUnit, ok := unit.(TemperatureUnit); ok {
+		return "°" + string(temperatureUnit)
+	}
+	return fmt.Sprintf("%v", unit)
+}
+
+// LoadSummaryTemplates parses every *.tmpl file in the directory.  The style name is
+// the file name without the extension.  Each template is rendered over sample weather
+// in every language so templates that fail (e.g. refer to a missing field) are
+// reported when the server starts rather than when a client asks for them.
+func LoadSummaryTemplates(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+summaryTemplateExtension))
+
+	if err != nil {
+		return err
+	}
+
+	if len(paths) == 0 {
+		return fmt.Errorf("No summary templates (*%v) found in %v", summaryTemplateExtension, dir)
+	}
+
+	templates := map[string]map[string]*template.Template{}
+
+	for _, path := range paths {
+		style := strings.TrimSuffix(filepath.Base(path), summaryTemplateExtension)
+
+		if !summaryStyleNamePattern.MatchString(style) || style == DefaultSummaryStyle {
+			return fmt.Errorf("Invalid summary style name: %v (must be letters, digits, _ or - and not %v)",
+				style, DefaultSummaryStyle)
+		}
+
+		text, err := os.ReadFile(path)
+
+		if err != nil {
+			return fmt.Errorf("Error reading summary template %v: %v", path, err)
+		}
+
+		templates[style] = map[string]*template.Template{}
+
+		for _, lang := range SupportedLanguages() {
+			tmpl, err := template.New(style).Funcs(summaryFuncs(CatalogFor(lang))).Parse(string(text))
+
+			if err != nil {
+				return fmt.Errorf("Error parsing summary template %v: %v", path, err)
+			}
+
+			templates[style][lang] = tmpl
+
+			if _, err := renderSummary(tmpl, sampleSummaryModel(lang)); err != nil {
+				return fmt.Errorf("Error rendering summary template %v (%v): %v", path, lang, err)
+			}
+		}
+	}
+
+	summaryTemplates = templates
+	return nil
+}
+
+// SummaryStyles returns the names of the summary styles, including the default style
+func SummaryStyles() []string {
+	styles := []string{DefaultSummaryStyle}
+	for style := range summaryTemplates {
+		styles = append(styles, style)
+	}
+	sort.Strings(styles[1:])
+	return styles
+}
+
+// ValidateSummaryStyle checks a style asked for by a client.  An empty style is the default.
+func ValidateSummaryStyle(style string) (string, error) {
+	if style == "" || style == DefaultSummaryStyle {
+		return DefaultSummaryStyle, nil
+	}
+
+	if _, found := summaryTemplates[style]; !found {
+		return "", fmt.Errorf("Invalid summaryStyle: %v (valid styles are %v)", style,
+			strings.Join(SummaryStyles(), ", "))
+	}
+
+	return style, nil
+}
+
+// RenderSummary replaces the summary of the simplified weather with the one rendered
+// by the style's template.  The default style leaves the summary as it is.
+func RenderSummary(style string, data *CurrentWeatherData, simplified *SimplifiedWeather) error {
+	if style == "" || style == DefaultSummaryStyle || simplified == nil {
+		return nil
+	}
+
+	tmpl := summaryTemplates[style][CatalogFor(data.Lang).Lang]
+
+	if tmpl == nil {
+		return fmt.Errorf("Invalid summaryStyle: %v", style)
+	}
+
+	summary, err := renderSummary(tmpl, &SummaryModel{Weather: simplified, Data: data, Units: data.Units,
+		Lang: CatalogFor(data.Lang).Lang})
+
+	if err != nil {
+		return fmt.Errorf("Error rendering summary style %v: %v", style, err)
+	}
+
+	simplified.Summary = summary
+	return nil
+}
+
+func renderSummary(tmpl *template.Template, model *SummaryModel) (string, error) {
+	var buffer bytes.Buffer
+
+	if err := tmpl.Execute(&buffer, model); err != nil {
+		return "", err
+	}
+
+	if buffer.Len() > maxSummaryBytes {
+		return "", fmt.Errorf("Summary is longer than %v bytes", maxSummaryBytes)
+	}
+
+	return strings.TrimSpace(buffer.String()), nil
+}
+
+// The Open Weather response templates are checked against at startup
+const sampleWeatherJson = `{"coord":{"lon":80,"lat":30},"weather":[{"id":804,"main":"Clouds",
+"description":"overcast clouds","icon":"04d"}],"main":{"temp":11,"feels_like":9.2,"temp_min":10,"temp_max":12,
+"pressure":1012,"humidity":40},"visibility":10000,"wind":{"speed":5,"deg":200,"gust":7},"rain":{"1h":0.4},
+"clouds":{"all":97},"dt":1711482248,"timezone":0,"name":"Sample"}`
+
+func sampleSummaryModel(lang string) *SummaryModel {
+	var sample CurrentWeatherData
+
+	if err := json.Unmarshal([]byte(sampleWeatherJson), &sample); err != nil {
+		panic(err)
+	}
+
+	sample.Lang = lang
+	weather := SimplifyCurrentWeatherData(&sample, MetricUnits)
+
+	return &SummaryModel{Weather: weather, Data: &sample, Units: sample.Units, Lang: lang}
+}
@@ -0,0 +1,118 @@
+package data
+
+import (
+	"fmt"
+	"math"
+)
+
+// The pressure tendencies
+const (
+	PressureRisingFast  = "risingFast"
+	PressureRising      = "rising"
+	PressureSteady      = "steady"
+	PressureFalling     = "falling"
+	PressureFallingFast = "fallingFast"
+)
+
+// The 3 hour pressure changes (hPa) at which the pressure is rising or falling and rising
+// or falling fast.  They're the Met Office's "rising" and "rising quickly" thresholds.
+const (
+	pressureChangingHPa = 1.6
+	pressureFastHPa     = 3.6
+)
+
+// The summary messages of the changing pressure tendencies
+var pressureTendencyMessages = map[string]string{
+	PressureRisingFast:  "pressureRisingFast",
+	PressureRising:      "pressureRising",
+	PressureFalling:     "pressureFalling",
+	PressureFallingFast: "pressureFallingFast",
+}
+
+// The smallest difference from the usual temperature (°C) mentioned in the summary
+const minSummaryAnomalyC = 2
+
+// Trends are how the weather at a location has changed over the recent observations and how
+// the temperature compares to the same local hour on the previous days.  Temperatures and
+// pressures are in the requested units.  Changes are left out when there wasn't an
+// observation around the right time.
+type Trends struct {
+	PressureTendency string   `json:"pressureTendency,omitempty"` // risingFast, rising, steady, falling, or fallingFast over 3 hours
+	PressureChange3h *float64 `json:"pressureChange3h,omitempty"`
+	TempChange1h     *float64 `json:"tempChange1h,omitempty"`
+	TempChange3h     *float64 `json:"tempChange3h,omitempty"`
+	TempChange24h    *float64 `json:"tempChange24h,omitempty"`
+	TempBaseline     *float64 `json:"tempBaseline,omitempty"` // the mean temperature at this local hour on the previous days
+	TempAnomaly      *float64 `json:"tempAnomaly,omitempty"`  // how much warmer (or colder when negative) it is than the baseline
+	BaselineDays     int      `json:"baselineDays,omitempty"` // the number of days in the baseline
+}
+
+// PressureTendency classifies a 3 hour pressure change in hPa
+func PressureTendency(changeHPa float64) string {
+	switch {
+	case changeHPa >= pressureFastHPa:
+		return PressureRisingFast
+	case changeHPa >= pressureChangingHPa:
+		return PressureRising
+	case changeHPa <= -pressureFastHPa:
+		return PressureFallingFast
+	case changeHPa <= -pressureChangingHPa:
+		return PressureFalling
+	}
+	return PressureSteady
+}
+
+// IsEmpty is true when none of the trends could be computed
+func (trends *Trends) IsEmpty() bool {
+	return trends.PressureChange3h == nil && trends.TempChange1h == nil && trends.TempChange3h == nil &&
+		trends.TempChange24h == nil && trends.TempAnomaly == nil
+}
+
+// ConvertFromMetric returns a copy of metric trends in the units
+func (trends *Trends) ConvertFromMetric(units UnitSystem) *Trends {
+	convert := func(value *float64, fn func(float64) float64) *float64 {
+		if value == nil {
+			return nil
+		}
+		converted := roundTo(fn(*value), 2)
+		return &converted
+	}
+
+	return &Trends{
+		PressureTendency: trends.PressureTendency,
+		PressureChange3h: convert(trends.PressureChange3h, units.Pressure.FromHectopascals),
+		TempChange1h:     convert(trends.TempChange1h, units.Temperature.FromCelsiusDifference),
+		TempChange3h:     convert(trends.TempChange3h, units.Temperature.FromCelsiusDifference),
+		TempChange24h:    convert(trends.TempChange24h, units.Temperature.FromCelsiusDifference),
+		TempBaseline:     convert(trends.TempBaseline, units.Temperature.FromCelsius),
+		TempAnomaly:      convert(trends.TempAnomaly, units.Temperature.FromCelsiusDifference),
+		BaselineDays:     trends.BaselineDays,
+	}
+}
+
+// Summary describes the notable trends (a rising or falling pressure and a temperature
+// at least 2 °C from the usual) in the language.  It's empty when nothing is notable.
+func (trends *Trends) Summary(lang string, unit TemperatureUnit) string {
+	catalog := CatalogFor(lang)
+	summary := ""
+
+	if message, found := pressureTendencyMessages[trends.PressureTendency]; found {
+		summary = catalog.Text(message)
+	}
+
+	if trends.TempAnomaly != nil && math.Abs(unit.ToCelsiusDifference(*trends.TempAnomaly)) >= minSummaryAnomalyC {
+		message := "warmerThanUsual"
+		if *trends.TempAnomaly < 0 {
+			message = "colderThanUsual"
+		}
+
+		difference := fmt.Sprintf("%v °%v", catalog.FormatNumber(roundTo(math.Abs(*trends.TempAnomaly), 1)), unit)
+
+		if summary != "" {
+			summary += "  "
+		}
+		summary += catalog.Format(message, difference)
+	}
+
+	return summary
+}
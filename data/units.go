@@ -0,0 +1,357 @@
+package data
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// TemperatureUnit is the unit temperatures are returned in
+type TemperatureUnit string
+
+const (
+	Celsius    TemperatureUnit = "C"
+	Fahrenheit TemperatureUnit = "F"
+	Kelvin     TemperatureUnit = "K"
+)
+
+// SpeedUnit is the unit wind speeds are returned in
+type SpeedUnit string
+
+const (
+	MetersPerSecond   SpeedUnit = "m/s"
+	KilometersPerHour SpeedUnit = "km/h"
+	MilesPerHour      SpeedUnit = "mph"
+	Knots             SpeedUnit = "kn"
+	Beaufort          SpeedUnit = "Bft"
+)
+
+// PressureUnit is the unit atmospheric pressures are returned in
+type PressureUnit string
+
+const (
+	Hectopascals         PressureUnit = "hPa"
+	InchesOfMercury      PressureUnit = "inHg"
+	MillimetersOfMercury PressureUnit = "mmHg"
+)
+
+// DistanceUnit is the unit visibility is returned in
+type DistanceUnit string
+
+const (
+	Meters     DistanceUnit = "m"
+	Kilometers DistanceUnit = "km"
+	Miles      DistanceUnit = "mi"
+)
+
+// PrecipitationUnit is the unit rainfall is returned in
+type PrecipitationUnit string
+
+const (
+	Millimeters PrecipitationUnit = "mm"
+	Inches      PrecipitationUnit = "in"
+)
+
+// UnitSystem holds the unit used for each kind of quantity
+type UnitSystem struct {
+	Temperature   TemperatureUnit   `json:"temperature"`
+	Speed         SpeedUnit         `json:"speed"`
+	Pressure      PressureUnit      `json:"pressure"`
+	Distance      DistanceUnit      `json:"distance"`
+	Precipitation PrecipitationUnit `json:"precipitation"`
+}
+
+func (units UnitSystem) String() string {
+	return fmt.Sprintf("%v,%v,%v,%v,%v", units.Temperature, units.Speed, units.Pressure, units.Distance,
+		units.Precipitation)
+}
+
+// The units Open Weather returns for each of its unit systems.  Open Weather
+// returns data in the metric system which is then converted to the requested units.
+var (
+	MetricUnits   = UnitSystem{Celsius, MetersPerSecond, Hectopascals, Meters, Millimeters}
+	ImperialUnits = UnitSystem{Fahrenheit, MilesPerHour, Hectopascals, Meters, Millimeters}
+	StandardUnits = UnitSystem{Kelvin, MetersPerSecond, Hectopascals, Meters, Millimeters}
+)
+
+// UnitSystemFor returns the units of an Open Weather unit system
+// (metric, imperial, or standard).  The default is metric.
+func UnitSystemFor(units string) (UnitSystem, error) {
+	switch units {
+	case "metric", "": // celsius, meters/sec
+		return MetricUnits, nil
+	case "imperial": // fahrenheit, miles/hour
+		return ImperialUnits, nil
+	case "standard": // kelvin, meters/sec
+		return StandardUnits, nil
+	}
+
+	return UnitSystem{}, fmt.Errorf("Invalid units value: %v", units)
+}
+
+// ParseUnitSystem starts with the units of an Open Weather unit system and
+// replaces the unit of each quantity that's given (i.e. not empty).
+func ParseUnitSystem(units, tempUnit, windUnit, pressureUnit, distanceUnit, precipUnit string) (UnitSystem, error) {
+	system, err := UnitSystemFor(units)
+
+	if err != nil {
+		return UnitSystem{}, err
+	}
+
+	if tempUnit != "" {
+		if system.Temperature, err = ParseTemperatureUnit(tempUnit); err != nil {
+			return UnitSystem{}, err
+		}
+	}
+
+	if windUnit != "" {
+		if system.Speed, err = ParseSpeedUnit(windUnit); err != nil {
+			return UnitSystem{}, err
+		}
+	}
+
+	if pressureUnit != "" {
+		if system.Pressure, err = ParsePressureUnit(pressureUnit); err != nil {
+			return UnitSystem{}, err
+		}
+	}
+
+	if distanceUnit != "" {
+		if system.Distance, err = ParseDistanceUnit(distanceUnit); err != nil {
+			return UnitSystem{}, err
+		}
+	}
+
+	if precipUnit != "" {
+		if system.Precipitation, err = ParsePrecipitationUnit(precipUnit); err != nil {
+			return UnitSystem{}, err
+		}
+	}
+
+	return system, nil
+}
+
+// ParseTemperatureUnit accepts the unit symbol (C, F, K) or its name (celsius, etc.)
+func ParseTemperatureUnit(str string) (TemperatureUnit, error) {
+	switch strings.ToLower(str) {
+	case "c", "celsius":
+		return Celsius, nil
+	case "f", "fahrenheit":
+		return Fahrenheit, nil
+	case "k", "kelvin":
+		return Kelvin, nil
+	}
+
+	return "", fmt.Errorf("Invalid temperature unit: %v (must be C, F, or K)", str)
+}
+
+// ParseSpeedUnit accepts m/s (or ms), km/h (or kmh, kph), mph, kn (or knots, kt), and Bft (or beaufort)
+func ParseSpeedUnit(str string) (SpeedUnit, error) {
+	switch strings.ToLower(str) {
+	case "m/s", "ms":
+		return MetersPerSecond, nil
+	case "km/h", "kmh", "kph":
+		return KilometersPerHour, nil
+	case "mph":
+		return MilesPerHour, nil
+	case "kn", "knots", "kt":
+		return Knots, nil
+	case "bft", "beaufort":
+		return Beaufort, nil
+	}
+
+	return "", fmt.Errorf("Invalid wind unit: %v (must be m/s, km/h, mph, kn, or Bft)", str)
+}
+
+// ParsePressureUnit accepts hPa (or mbar), inHg, and mmHg
+func ParsePressureUnit(str string) (PressureUnit, error) {
+	switch strings.ToLower(str) {
+	case "hpa", "mbar":
+		return Hectopascals, nil
+	case "inhg":
+		return InchesOfMercury, nil
+	case "mmhg":
+		return MillimetersOfMercury, nil
+	}
+
+	return "", fmt.Errorf("Invalid pressure unit: %v (must be hPa, inHg, or mmHg)", str)
+}
+
+// ParseDistanceUnit accepts m, km, and mi
+func ParseDistanceUnit(str string) (DistanceUnit, error) {
+	switch strings.ToLower(str) {
+	case "m":
+		return Meters, nil
+	case "km":
+		return Kilometers, nil
+	case "mi":
+		return Miles, nil
+	}
+
+	return "", fmt.Errorf("Invalid distance unit: %v (must be m, km, or mi)", str)
+}
+
+// ParsePrecipitationUnit accepts mm and in
+func ParsePrecipitationUnit(str string) (PrecipitationUnit, error) {
+	switch strings.ToLower(str) {
+	case "mm":
+		return Millimeters, nil
+	case "in":
+		return Inches, nil
+	}
+
+	return "", fmt.Errorf("Invalid precipitation unit: %v (must be mm or in)", str)
+}
+
+func (u TemperatureUnit) FromCelsius(c float64) float64 {
+	switch u {
+	case Fahrenheit:
+		return CelsiusToFahrenheit(c)
+	case Kelvin:
+		return CelsiusToKelvin(c)
+	}
+	return c
+}
+
+func (u TemperatureUnit) ToCelsius(t float64) float64 {
+	switch u {
+	case Fahrenheit:
+		return FahrenheitToCelsius(t)
+	case Kelvin:
+		return KelvinToCelsius(t)
+	}
+	return t
+}
+
+// FromCelsiusDifference converts a temperature difference (rather than a temperature)
+func (u TemperatureUnit) FromCelsiusDifference(c float64) float64 {
+	if u == Fahrenheit {
+		return c * 9.0 / 5.0
+	}
+	return c
+}
+
+// ToCelsiusDifference converts a temperature difference to celsius
+func (u TemperatureUnit) ToCelsiusDifference(t float64) float64 {
+	if u == Fahrenheit {
+		return t * 5.0 / 9.0
+	}
+	return t
+}
+
+// The Beaufort number is approximated by v = 0.836 * B^1.5 (v in m/s)
+const beaufortCoefficient = 0.836
+
+func (u SpeedUnit) FromMetersPerSecond(mps float64) float64 {
+	switch u {
+	case KilometersPerHour:
+		return mps * 3.6
+	case MilesPerHour:
+		return mps / 0.44704
+	case Knots:
+		return mps * 3600 / 1852
+	case Beaufort:
+		return math.Min(12, math.Round(math.Pow(math.Max(mps, 0)/beaufortCoefficient, 2.0/3.0)))
+	}
+	return mps
+}
+
+func (u SpeedUnit) ToMetersPerSecond(speed float64) float64 {
+	switch u {
+	case KilometersPerHour:
+		return speed / 3.6
+	case MilesPerHour:
+		return speed * 0.44704
+	case Knots:
+		return speed * 1852 / 3600
+	case Beaufort:
+		return beaufortCoefficient * math.Pow(math.Max(speed, 0), 1.5)
+	}
+	return speed
+}
+
+func (u PressureUnit) FromHectopascals(hPa float64) float64 {
+	switch u {
+	case InchesOfMercury:
+		return hPa / 33.8639
+	case MillimetersOfMercury:
+		return hPa / 1.33322
+	}
+	return hPa
+}
+
+func (u PressureUnit) ToHectopascals(pressure float64) float64 {
+	switch u {
+	case InchesOfMercury:
+		return pressure * 33.8639
+	case MillimetersOfMercury:
+		return pressure * 1.33322
+	}
+	return pressure
+}
+
+func (u DistanceUnit) FromMeters(m float64) float64 {
+	switch u {
+	case Kilometers:
+		return m / 1000
+	case Miles:
+		return m / 1609.344
+	}
+	return m
+}
+
+func (u DistanceUnit) ToMeters(distance float64) float64 {
+	switch u {
+	case Kilometers:
+		return distance * 1000
+	case Miles:
+		return distance * 1609.344
+	}
+	return distance
+}
+
+func (u PrecipitationUnit) FromMillimeters(mm float64) float64 {
+	if u == Inches {
+		return mm / 25.4
+	}
+	return mm
+}
+
+func (u PrecipitationUnit) ToMillimeters(precipitation float64) float64 {
+	if u == Inches {
+		return precipitation * 25.4
+	}
+	return precipitation
+}
+
+// roundTo rounds away the noise left by unit conversions
+func roundTo(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
+
+// ConvertFromMetric converts weather data fetched in Open Weather's metric
+// units into the unit system.  The data's Units are set to the unit system.
+func ConvertFromMetric(data *CurrentWeatherData, units UnitSystem) {
+	temperature := func(c float64) float64 { return roundTo(units.Temperature.FromCelsius(c), 2) }
+	speed := func(mps float64) float64 { return roundTo(units.Speed.FromMetersPerSecond(mps), 2) }
+	pressure := func(hPa float64) float64 { return roundTo(units.Pressure.FromHectopascals(hPa), 2) }
+
+	data.Main.Temp = temperature(data.Main.Temp)
+	data.Main.FeelsLike = temperature(data.Main.FeelsLike)
+	data.Main.TempMin = temperature(data.Main.TempMin)
+	data.Main.TempMax = temperature(data.Main.TempMax)
+	data.Main.Pressure = pressure(data.Main.Pressure)
+	data.Main.SeaLevel = pressure(data.Main.SeaLevel)
+	data.Main.GrndLevel = pressure(data.Main.GrndLevel)
+	data.Wind.Speed = speed(data.Wind.Speed)
+	data.Wind.Gust = speed(data.Wind.Gust)
+	if data.Visibility != nil {
+		// A new value since copies of the observation share the pointer
+		visibility := roundTo(units.Distance.FromMeters(*data.Visibility), 3)
+		data.Visibility = &visibility
+	}
+
+	data.Rain.H = roundTo(units.Precipitation.FromMillimeters(data.Rain.H), 3)
+	data.Units = units
+}
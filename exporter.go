@@ -0,0 +1,222 @@
+package main
+
+import (
+	"current-weather-server/data"
+	"current-weather-server/logging"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// exporterLocation is one of the locations configured with -exporterLocations
+type exporterLocation struct {
+	Name  string
+	Query weatherQuery
+
+	// The last successful observation and the result of the last refresh
+	Data        *data.CurrentWeatherData
+	LastRefresh time.Time
+	LastOk      bool
+}
+
+// exporterMetric describes one gauge published for every location
+type exporterMetric struct {
+	Name  string
+	Help  string
+	Value func(*data.CurrentWeatherData) float64
+}
+
+// The exporter always fetches metric units so the metric names can include their unit
+var exporterMetrics = []exporterMetric{
+	{"weather_temperature_celsius", "Current temperature",
+		func(d *data.CurrentWeatherData) float64 { return d.Main.Temp }},
+	{"weather_feels_like_celsius", "Current feels like temperature",
+		func(d *data.CurrentWeatherData) float64 { return d.Main.FeelsLike }},
+	{"weather_humidity_percent", "Current relative humidity",
+		func(d *data.CurrentWeatherData) float64 { return d.Main.Humidity }},
+	{"weather_cloudiness_percent", "Current cloud cover",
+		func(d *data.CurrentWeatherData) float64 { return d.Clouds.All }},
+	{"weather_pressure_hpa", "Current atmospheric pressure at sea level",
+		func(d *data.CurrentWeatherData) float64 { return d.Main.Pressure }},
+	{"weather_wind_speed_meters_per_second", "Current wind speed",
+		func(d *data.CurrentWeatherData) float64 { return d.Wind.Speed }},
+	{"weather_wind_gust_meters_per_second", "Current wind gust speed",
+		func(d *data.CurrentWeatherData) float64 { return d.Wind.Gust }},
+	{"weather_wind_direction_degrees", "Current wind direction (meteorological)",
+		func(d *data.CurrentWeatherData) float64 { return d.Wind.Deg }},
+	{"weather_observation_timestamp_seconds", "Time of the Open Weather observation (unix time)",
+		func(d *data.CurrentWeatherData) float64 { return float64(d.Dt) }},
+}
+
+type weatherExporter struct {
+	mutex     sync.Mutex
+	locations []*exporterLocation
+}
+
+var exporter = &weatherExporter{}
+
+// parseExporterLocations parses a semicolon separated list of name=latitude,longitude
+// e.g. "dc1=37.77,-122.42;dc2=40.71,-74.01"
+func parseExporterLocations(str string) ([]*exporterLocation, error) {
+	locations := []*exporterLocation{}
+	names := map[string]bool{}
+
+	for _, part := range strings.Split(str, ";") {
+		part = strings.TrimSpace(part)
+
+		if part == "" {
+			continue
+		}
+
+		name, coordinates, found := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+
+		if !found || name == "" {
+			return nil, fmt.Errorf("Invalid exporter location (must be name=latitude,longitude): %v", part)
+		}
+
+		if names[name] {
+			return nil, fmt.Errorf("Duplicate exporter location name: %v", name)
+		}
+
+		latitudeStr, longitudeStr, found := strings.Cut(coordinates, ",")
+
+		if !found {
+			return nil, fmt.Errorf("Invalid exporter location (must be name=latitude,longitude): %v", part)
+		}
+
+		latitude, err := strconv.ParseFloat(strings.TrimSpace(latitudeStr), 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid latitude value for exporter location %v: %v", name, latitudeStr)
+		}
+
+		longitude, err := strconv.ParseFloat(strings.TrimSpace(longitudeStr), 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid longitude value for exporter location %v: %v", name, longitudeStr)
+		}
+
+		query, err, _ := newWeatherQuery(latitude, longitude, queryOptions{Units: "metric"})
+
+		if err != nil {
+			return nil, fmt.Errorf("Exporter location %v: %v", name, err)
+		}
+
+		names[name] = true
+		locations = append(locations, &exporterLocation{Name: name, Query: *query})
+	}
+
+	return locations, nil
+}
+
+// start refreshes the locations now and then every interval in the background
+func (e *weatherExporter) start(locations []*exporterLocation, interval time.Duration) {
+	e.mutex.Lock()
+	e.locations = locations
+	e.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			e.refresh()
+			<-ticker.C
+		}
+	}()
+}
+
+func (e *weatherExporter) refresh() {
+	e.mutex.Lock()
+	queries := make([]*weatherQuery, len(e.locations))
+	for inx, location := range e.locations {
+		queries[inx] = &location.Query
+	}
+	e.mutex.Unlock()
+
+	results := fetchCurrentWeatherConcurrently(queries)
+	now := time.Now()
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for inx, result := range results {
+		location := e.locations[inx]
+		location.LastRefresh = now
+		location.LastOk = result.Err == nil
+
+		if result.Err != nil {
+			logging.LogError(0, fmt.Sprintf("Exporter error refreshing %v: %v", location.Name, result.Err))
+			continue
+		}
+
+		location.Data = result.Data
+	}
+}
+
+// writeMetrics writes the gauges in the Prometheus text exposition format.
+// Locations that have never been fetched successfully only report weather_refresh_success.
+func (e *weatherExporter) writeMetrics(builder *strings.Builder) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for _, metric := range exporterMetrics {
+		fmt.Fprintf(builder, "# HELP %v %v\n# TYPE %v gauge\n", metric.Name, metric.Help, metric.Name)
+
+		for _, location := range e.locations {
+			if location.Data != nil {
+				fmt.Fprintf(builder, "%v{%v} %v\n", metric.Name, exporterLabels(location),
+					formatMetricValue(metric.Value(location.Data)))
+			}
+		}
+	}
+
+	builder.WriteString("# HELP weather_refresh_success Whether the last refresh from Open Weather succeeded\n")
+	builder.WriteString("# TYPE weather_refresh_success gauge\n")
+
+	for _, location := range e.locations {
+		success := 0
+		if location.LastOk {
+			success = 1
+		}
+		fmt.Fprintf(builder, "weather_refresh_success{%v} %v\n", exporterLabels(location), success)
+	}
+
+	builder.WriteString("# HELP weather_last_refresh_timestamp_seconds Time of the last refresh from Open Weather (unix time)\n")
+	builder.WriteString("# TYPE weather_last_refresh_timestamp_seconds gauge\n")
+
+	for _, location := range e.locations {
+		if !location.LastRefresh.IsZero() {
+			fmt.Fprintf(builder, "weather_last_refresh_timestamp_seconds{%v} %v\n", exporterLabels(location),
+				location.LastRefresh.Unix())
+		}
+	}
+}
+
+func exporterLabels(location *exporterLocation) string {
+	return fmt.Sprintf(`location="%v",latitude="%v",longitude="%v"`, escapeLabelValue(location.Name),
+		location.Query.Latitude, location.Query.Longitude)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+func formatMetricValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// metricsHandler serves the exporter's gauges.  It never calls Open Weather itself.
+func metricsHandler(requestNum uint64, writer http.ResponseWriter, request *http.Request) {
+	var builder strings.Builder
+	exporter.writeMetrics(&builder)
+
+	writer.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writer.Write([]byte(builder.String()))
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"current-weather-server/data"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// The response formats of the endpoints that return locations
+const (
+	jsonFormat    = "json"
+	geoJSONFormat = "geojson"
+)
+
+// wantsGeoJSON is true when the request asks for GeoJSON with the format parameter or,
+// when there's no format parameter, with the Accept header
+func wantsGeoJSON(request *http.Request) (bool, error, int) {
+	switch format := geoFormatParam.value(request.URL.Query()); format {
+	case geoJSONFormat:
+		return true, nil, http.StatusOK
+	case jsonFormat:
+		return false, nil, http.StatusOK
+	case "":
+	default:
+		return false, fmt.Errorf("Invalid format value: %v (must be %v or %v)", format, jsonFormat, geoJSONFormat),
+			http.StatusBadRequest
+	}
+
+	for _, accepted := range strings.Split(request.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted)); err == nil && mediaType == data.GeoJSONType {
+			return true, nil, http.StatusOK
+		}
+	}
+
+	return false, nil, http.StatusOK
+}
+
+// writeGeoJson writes a GeoJSON response
+func writeGeoJson(requestNum uint64, writer http.ResponseWriter, value interface{}, statusCode int) {
+	writeJsonAs(requestNum, writer, data.GeoJSONType, value, statusCode)
+}
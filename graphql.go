@@ -0,0 +1,614 @@
+package main
+
+import (
+	"context"
+	"current-weather-server/data"
+	"current-weather-server/logging"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// The limits applied to every GraphQL query before it's executed.
+// maxGraphqlLocations is the maximum number of Open Weather calls
+// a single query can cause.
+var maxGraphqlDepth = 10
+var maxGraphqlLocations = 20
+
+// The largest GraphQL request body that will be read
+const maxGraphqlBodyBytes = 1 << 20
+
+// The names of the Query fields that call Open Weather once
+// and the ones that call it once per location.
+var graphqlSingleLocationFields = map[string]bool{"currentWeather": true, "currentWeatherData": true}
+var graphqlLocationListFields = map[string]bool{"currentWeatherList": true}
+
+var graphqlSchema graphql.Schema
+
+// graphqlLocationWeather is one entry of the list returned by currentWeatherList
+type graphqlLocationWeather struct {
+	Latitude  float64
+	Longitude float64
+	Weather   *data.SimplifiedWeather
+	Data      *data.CurrentWeatherData
+	Error     string
+}
+
+func init() {
+	var err error
+	graphqlSchema, err = buildGraphqlSchema()
+
+	if err != nil {
+		fmt.Printf("Error building GraphQL schema: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func buildGraphqlSchema() (graphql.Schema, error) {
+	objectTypes := map[reflect.Type]*graphql.Object{}
+	simplifiedWeatherType := graphqlObjectFromStruct("SimplifiedWeather", reflect.TypeOf(data.SimplifiedWeather{}), objectTypes)
+	currentWeatherDataType := graphqlObjectFromStruct("CurrentWeatherData", reflect.TypeOf(data.CurrentWeatherData{}), objectTypes)
+
+	locationInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "LocationInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"lat": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Float)},
+			"lon": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Float)},
+		},
+	})
+
+	locationWeatherType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "LocationWeather",
+		Fields: graphql.Fields{
+			"lat":     &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Resolve: resolveLocationWeather("lat")},
+			"lon":     &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Resolve: resolveLocationWeather("lon")},
+			"weather": &graphql.Field{Type: simplifiedWeatherType, Resolve: resolveLocationWeather("weather")},
+			"data":    &graphql.Field{Type: currentWeatherDataType, Resolve: resolveLocationWeather("data")},
+			"error":   &graphql.Field{Type: graphql.String, Resolve: resolveLocationWeather("error")},
+		},
+	})
+
+	locationArgs := graphql.FieldConfigArgument{
+		"lat": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Float)},
+		"lon": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Float)},
+	}
+
+	for name, argument := range optionArgs() {
+		locationArgs[name] = argument
+	}
+
+	listArgs := optionArgs()
+	listArgs["locations"] = &graphql.ArgumentConfig{
+		Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(locationInputType))),
+	}
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"currentWeather": &graphql.Field{
+				Type:        simplifiedWeatherType,
+				Description: "The same data returned by /api/currentweather",
+				Args:        locationArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					_, simplifiedData, err := resolveCurrentWeather(p)
+					return simplifiedData, err
+				},
+			},
+			"currentWeatherData": &graphql.Field{
+				Type:        currentWeatherDataType,
+				Description: "The data returned by Open Weather",
+				Args:        locationArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					currentWeatherData, _, err := resolveCurrentWeather(p)
+					return currentWeatherData, err
+				},
+			},
+			"currentWeatherList": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(locationWeatherType))),
+				Description: "The weather at several locations.  A failure at one location is returned in its error field.",
+				Args:        listArgs,
+				Resolve:     resolveCurrentWeatherList,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+// optionArgs returns the unit, language, summary style, comfort, and trend summary arguments, which match the /api/currentweather parameters
+func optionArgs() graphql.FieldConfigArgument {
+	args := graphql.FieldConfigArgument{}
+
+	for _, param := range []apiParam{unitsParam, tempUnitParam, windUnitParam, pressureUnitParam,
+		distanceUnitParam, precipUnitParam, langParam, summaryStyleParam, comfortProfileParam, coldCoolWarmParam} {
+		args[param.Name] = &graphql.ArgumentConfig{Type: graphql.String, Description: param.Description}
+	}
+
+	args[trendSummaryParam.Name] = &graphql.ArgumentConfig{Type: graphql.Boolean, Description: trendSummaryParam.Description}
+	return args
+}
+
+// queryOptionsFromArgs reads the option arguments of a query.  The language defaults
+// to the one picked from the Accept-Language header.
+func queryOptionsFromArgs(p graphql.ResolveParams) queryOptions {
+	args := p.Args
+	var options queryOptions
+	options.Units, _ = args[unitsParam.Name].(string)
+	options.TempUnit, _ = args[tempUnitParam.Name].(string)
+	options.WindUnit, _ = args[windUnitParam.Name].(string)
+	options.PressureUnit, _ = args[pressureUnitParam.Name].(string)
+	options.DistanceUnit, _ = args[distanceUnitParam.Name].(string)
+	options.PrecipUnit, _ = args[precipUnitParam.Name].(string)
+	options.Lang, _ = args[langParam.Name].(string)
+	options.SummaryStyle, _ = args[summaryStyleParam.Name].(string)
+	options.ComfortProfile, _ = args[comfortProfileParam.Name].(string)
+	options.ColdCoolWarm, _ = args[coldCoolWarmParam.Name].(string)
+
+	if trendSummary, ok := args[trendSummaryParam.Name].(bool); ok {
+		options.TrendSummary = strconv.FormatBool(trendSummary)
+	}
+
+	if options.Lang == "" {
+		options.Lang, _ = p.Context.Value(graphqlLangKey{}).(string)
+	}
+
+	return options
+}
+
+// graphqlObjectFromStruct builds a GraphQL object type from a struct using the
+// same names as its json encoding, so the GraphQL fields match the REST API.
+func graphqlObjectFromStruct(name string, t reflect.Type, objectTypes map[reflect.Type]*graphql.Object) *graphql.Object {
+	if objectType, found := objectTypes[t]; found {
+		return objectType
+	}
+
+	fields := graphql.Fields{}
+
+	for inx := 0; inx < t.NumField(); inx++ {
+		structField := t.Field(inx)
+		fieldName := strings.Split(structField.Tag.Get("json"), ",")[0]
+
+		if fieldName == "-" || !structField.IsExported() {
+			continue
+		}
+
+		if fieldName == "" {
+			fieldName = strings.ToLower(structField.Name[:1]) + structField.Name[1:]
+		}
+
+		// GraphQL names can't start with a digit (e.g. rain.1h)
+		if fieldName[0] >= '0' && fieldName[0] <= '9' {
+			fieldName = "_" + fieldName
+		}
+
+		fieldType := graphqlOutputType(name+structField.Name, structField.Type, objectTypes)
+
+		if fieldType == nil {
+			continue
+		}
+
+		fields[fieldName] = &graphql.Field{Type: fieldType, Resolve: resolveStructField(inx)}
+	}
+
+	objectType := graphql.NewObject(graphql.ObjectConfig{Name: name, Fields: fields})
+	objectTypes[t] = objectType
+	return objectType
+}
+
+func graphqlOutputType(name string, t reflect.Type, objectTypes map[reflect.Type]*graphql.Object) graphql.Output {
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return graphql.Float
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return graphql.Int
+	case reflect.String:
+		return graphql.String
+	case reflect.Bool:
+		return graphql.Boolean
+	case reflect.Ptr:
+		return graphqlOutputType(name, t.Elem(), objectTypes)
+	case reflect.Slice:
+		elemType := graphqlOutputType(name, t.Elem(), objectTypes)
+		if elemType == nil {
+			return nil
+		}
+		return graphql.NewList(elemType)
+	case reflect.Struct:
+		return graphqlObjectFromStruct(name, t, objectTypes)
+	}
+
+	return nil
+}
+
+func resolveStructField(inx int) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		value := reflect.ValueOf(p.Source)
+
+		if value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return nil, nil
+			}
+			value = value.Elem()
+		}
+
+		return value.Field(inx).Interface(), nil
+	}
+}
+
+func resolveLocationWeather(fieldName string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		locationWeather, ok := p.Source.(*graphqlLocationWeather)
+
+		if !ok {
+			return nil, nil
+		}
+
+		switch fieldName {
+		case "lat":
+			return locationWeather.Latitude, nil
+		case "lon":
+			return locationWeather.Longitude, nil
+		case "weather":
+			return locationWeather.Weather, nil
+		case "data":
+			return locationWeather.Data, nil
+		case "error":
+			if locationWeather.Error == "" {
+				return nil, nil
+			}
+			return locationWeather.Error, nil
+		}
+
+		return nil, nil
+	}
+}
+
+// graphqlLangKey holds the language picked from the Accept-Language header in the query's context
+type graphqlLangKey struct{}
+
+func resolveCurrentWeather(p graphql.ResolveParams) (*data.CurrentWeatherData, *data.SimplifiedWeather, error) {
+	latitude, _ := p.Args["lat"].(float64)
+	longitude, _ := p.Args["lon"].(float64)
+	query, err, _ := newWeatherQuery(latitude, longitude, queryOptionsFromArgs(p))
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	currentWeatherData, simplifiedData, err, _ := fetchCurrentWeather(query)
+	return currentWeatherData, simplifiedData, err
+}
+
+func resolveCurrentWeatherList(p graphql.ResolveParams) (interface{}, error) {
+	options := queryOptionsFromArgs(p)
+	locations, _ := p.Args["locations"].([]interface{})
+
+	results := make([]*graphqlLocationWeather, len(locations))
+	queries := make([]*weatherQuery, 0, len(locations))
+	queryResults := make([]*graphqlLocationWeather, 0, len(locations))
+
+	for inx, location := range locations {
+		locationMap, _ := location.(map[string]interface{})
+		latitude, _ := locationMap["lat"].(float64)
+		longitude, _ := locationMap["lon"].(float64)
+		results[inx] = &graphqlLocationWeather{Latitude: latitude, Longitude: longitude}
+
+		query, err, _ := newWeatherQuery(latitude, longitude, options)
+
+		if err != nil {
+			results[inx].Error = err.Error()
+			continue
+		}
+
+		queries = append(queries, query)
+		queryResults = append(queryResults, results[inx])
+	}
+
+	for inx, fetched := range fetchCurrentWeatherConcurrently(queries) {
+		if fetched.Err != nil {
+			queryResults[inx].Error = fetched.Err.Error()
+			continue
+		}
+
+		queryResults[inx].Data = fetched.Data
+		queryResults[inx].Weather = fetched.Simplified
+	}
+
+	return results, nil
+}
+
+// checkGraphqlLimits rejects queries that are nested too deeply or that
+// would call Open Weather for too many locations.  It runs before the
+// query is executed so a rejected query never calls Open Weather.
+func checkGraphqlLimits(document *ast.Document, variables map[string]interface{}) error {
+	fragments := map[string]*ast.FragmentDefinition{}
+
+	for _, definition := range document.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok && fragment.Name != nil {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+
+		if !ok {
+			continue
+		}
+
+		depth, err := graphqlSelectionDepth(operation.SelectionSet, fragments, map[string]bool{})
+
+		if err != nil {
+			return err
+		}
+
+		if depth > maxGraphqlDepth {
+			return fmt.Errorf("Query depth %v exceeds the maximum of %v", depth, maxGraphqlDepth)
+		}
+
+		locationCount, err := graphqlLocationCount(operation.SelectionSet, fragments, variables, map[string]bool{})
+
+		if err != nil {
+			return err
+		}
+
+		if locationCount > maxGraphqlLocations {
+			return fmt.Errorf("Query requests %v locations which exceeds the maximum of %v",
+				locationCount, maxGraphqlLocations)
+		}
+	}
+
+	return nil
+}
+
+func graphqlSelectionDepth(selectionSet *ast.SelectionSet, fragments map[string]*ast.FragmentDefinition,
+	visiting map[string]bool) (int, error) {
+
+	if selectionSet == nil {
+		return 0, nil
+	}
+
+	maxDepth := 0
+
+	for _, selection := range selectionSet.Selections {
+		depth := 0
+		var err error
+
+		switch s := selection.(type) {
+		case *ast.Field:
+			depth, err = graphqlSelectionDepth(s.SelectionSet, fragments, visiting)
+			depth++
+		case *ast.InlineFragment:
+			depth, err = graphqlSelectionDepth(s.SelectionSet, fragments, visiting)
+		case *ast.FragmentSpread:
+			depth, err = graphqlFragmentSpread(s, fragments, visiting, func(fragment *ast.FragmentDefinition) (int, error) {
+				return graphqlSelectionDepth(fragment.SelectionSet, fragments, visiting)
+			})
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+	}
+
+	return maxDepth, nil
+}
+
+// graphqlLocationCount counts the Open Weather calls caused by the Query fields of a selection set
+func graphqlLocationCount(selectionSet *ast.SelectionSet, fragments map[string]*ast.FragmentDefinition,
+	variables map[string]interface{}, visiting map[string]bool) (int, error) {
+
+	if selectionSet == nil {
+		return 0, nil
+	}
+
+	count := 0
+
+	for _, selection := range selectionSet.Selections {
+		switch s := selection.(type) {
+		case *ast.Field:
+			if s.Name == nil {
+				continue
+			}
+
+			if graphqlSingleLocationFields[s.Name.Value] {
+				count++
+			} else if graphqlLocationListFields[s.Name.Value] {
+				count += graphqlLocationsArgumentLength(s, variables)
+			}
+		case *ast.InlineFragment:
+			inlineCount, err := graphqlLocationCount(s.SelectionSet, fragments, variables, visiting)
+			if err != nil {
+				return 0, err
+			}
+			count += inlineCount
+		case *ast.FragmentSpread:
+			spreadCount, err := graphqlFragmentSpread(s, fragments, visiting, func(fragment *ast.FragmentDefinition) (int, error) {
+				return graphqlLocationCount(fragment.SelectionSet, fragments, variables, visiting)
+			})
+			if err != nil {
+				return 0, err
+			}
+			count += spreadCount
+		}
+	}
+
+	return count, nil
+}
+
+// graphqlFragmentSpread evaluates a named fragment, failing if the fragment refers back to itself
+func graphqlFragmentSpread(spread *ast.FragmentSpread, fragments map[string]*ast.FragmentDefinition,
+	visiting map[string]bool, evaluate func(*ast.FragmentDefinition) (int, error)) (int, error) {
+
+	if spread.Name == nil {
+		return 0, nil
+	}
+
+	name := spread.Name.Value
+	fragment, found := fragments[name]
+
+	if !found {
+		return 0, nil
+	}
+
+	if visiting[name] {
+		return 0, fmt.Errorf("Cannot spread fragment %v within itself", name)
+	}
+
+	visiting[name] = true
+	defer delete(visiting, name)
+	return evaluate(fragment)
+}
+
+// graphqlLocationsArgumentLength returns the number of entries in the locations argument
+// of a field, whether it's written in the query or passed as a variable.
+func graphqlLocationsArgumentLength(field *ast.Field, variables map[string]interface{}) int {
+	for _, argument := range field.Arguments {
+		if argument.Name == nil || argument.Name.Value != "locations" {
+			continue
+		}
+
+		switch value := argument.Value.(type) {
+		case *ast.ListValue:
+			return len(value.Values)
+		case *ast.Variable:
+			if value.Name == nil {
+				return 0
+			}
+			if list, ok := variables[value.Name.Value].([]interface{}); ok {
+				return len(list)
+			}
+			// A single value is coerced to a list of one
+			if variables[value.Name.Value] != nil {
+				return 1
+			}
+		default:
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// graphqlRequest is the body of a GraphQL POST request
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+func readGraphqlRequest(request *http.Request) (*graphqlRequest, error) {
+	graphqlReq := &graphqlRequest{}
+
+	switch request.Method {
+	case http.MethodGet:
+		queryValues := request.URL.Query()
+		graphqlReq.Query = graphqlQueryParam.value(queryValues)
+		graphqlReq.OperationName = graphqlOperationNameParam.value(queryValues)
+
+		if variablesStr := graphqlVariablesParam.value(queryValues); variablesStr != "" {
+			if err := json.Unmarshal([]byte(variablesStr), &graphqlReq.Variables); err != nil {
+				return nil, fmt.Errorf("Invalid variables: %v", err)
+			}
+		}
+	case http.MethodPost:
+		body, err := io.ReadAll(io.LimitReader(request.Body, maxGraphqlBodyBytes))
+
+		if err != nil {
+			return nil, fmt.Errorf("Error reading request body: %v", err)
+		}
+
+		if strings.HasPrefix(request.Header.Get("Content-Type"), "application/graphql") {
+			graphqlReq.Query = string(body)
+		} else if err := json.Unmarshal(body, graphqlReq); err != nil {
+			return nil, fmt.Errorf("Invalid request body: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported method: %v", request.Method)
+	}
+
+	if graphqlReq.Query == "" {
+		return nil, errors.New("missing query")
+	}
+
+	return graphqlReq, nil
+}
+
+func writeGraphqlResult(requestNum uint64, writer http.ResponseWriter, result *graphql.Result, statusCode int) {
+	jsonBytes, err := json.Marshal(result)
+
+	if err != nil {
+		msg := fmt.Sprintf("Error marshing response: %v", err)
+		logging.LogError(requestNum, msg)
+		http.Error(writer, msg, http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(statusCode)
+	writer.Write(jsonBytes)
+}
+
+func graphqlErrorResult(err error) *graphql.Result {
+	return &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError(err.Error())}}
+}
+
+func apiGraphql(requestNum uint64, writer http.ResponseWriter, request *http.Request) {
+	graphqlReq, err := readGraphqlRequest(request)
+
+	if err != nil {
+		logging.LogHTTPError(requestNum, err.Error(), http.StatusBadRequest)
+		writeGraphqlResult(requestNum, writer, graphqlErrorResult(err), http.StatusBadRequest)
+		return
+	}
+
+	document, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(graphqlReq.Query),
+		Name: "GraphQL request",
+	})})
+
+	if err != nil {
+		logging.LogHTTPError(requestNum, err.Error(), http.StatusBadRequest)
+		writeGraphqlResult(requestNum, writer, graphqlErrorResult(err), http.StatusBadRequest)
+		return
+	}
+
+	err = checkGraphqlLimits(document, graphqlReq.Variables)
+
+	if err != nil {
+		logging.LogHTTPError(requestNum, err.Error(), http.StatusBadRequest)
+		writeGraphqlResult(requestNum, writer, graphqlErrorResult(err), http.StatusBadRequest)
+		return
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         graphqlSchema,
+		RequestString:  graphqlReq.Query,
+		VariableValues: graphqlReq.Variables,
+		OperationName:  graphqlReq.OperationName,
+		Context: context.WithValue(request.Context(), graphqlLangKey{},
+			acceptLanguage(request.Header.Get("Accept-Language"))),
+	})
+
+	for _, resultErr := range result.Errors {
+		logging.LogError(requestNum, fmt.Sprintf("GraphQL: %v", resultErr.Message))
+	}
+
+	writeGraphqlResult(requestNum, writer, result, http.StatusOK)
+}
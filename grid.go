@@ -0,0 +1,260 @@
+package main
+
+import (
+	"current-weather-server/data"
+	"current-weather-server/logging"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// The most points a grid can have
+var maxGridCells = 100
+
+// The field returned when the grid request doesn't ask for any
+const defaultGridField = "temp"
+
+// gridError is a point of a grid whose weather couldn't be fetched
+type gridError struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Error     string  `json:"error"`
+}
+
+// gridUnits are the units of the grid's values
+type gridUnits struct {
+	Temperature   string `json:"temperature"`
+	Wind          string `json:"wind"`
+	Pressure      string `json:"pressure"`
+	Distance      string `json:"distance"`
+	Precipitation string `json:"precipitation"`
+}
+
+// weatherGrid is returned by /api/grid.  The points are every step degrees from the minimum
+// to the maximum latitude (the rows, south to north) and longitude (the columns, west to east).
+// Values has a matrix for each selected field where Values[field][row][column] is the value
+// at Latitudes[row], Longitudes[column], or null when the point's weather couldn't be fetched.
+type weatherGrid struct {
+	Bbox       []float64                  `json:"bbox"` // minLon, minLat, maxLon, maxLat
+	Step       float64                    `json:"step"`
+	Latitudes  []float64                  `json:"latitudes"`
+	Longitudes []float64                  `json:"longitudes"`
+	Units      gridUnits                  `json:"units"`
+	Values     map[string][][]interface{} `json:"values"`
+	Errors     []gridError                `json:"errors,omitempty"`
+	fields     []string                   // the selected fields in the order they were asked for
+}
+
+// parseBbox parses minLon,minLat,maxLon,maxLat.  Boxes crossing the antimeridian aren't supported.
+func parseBbox(str string) ([]float64, error) {
+	parts := strings.Split(str, ",")
+
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("Invalid bbox value: %v (must be minLon,minLat,maxLon,maxLat)", str)
+	}
+
+	bbox := make([]float64, 4)
+
+	for inx, part := range parts {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid bbox value: %v (must be minLon,minLat,maxLon,maxLat)", str)
+		}
+
+		bbox[inx] = value
+	}
+
+	minLon, minLat, maxLon, maxLat := bbox[0], bbox[1], bbox[2], bbox[3]
+
+	if !validLongitude(minLon) || !validLongitude(maxLon) {
+		return nil, fmt.Errorf("Invalid bbox longitude: %v (must be between -180 and 180)", str)
+	}
+
+	if !validLatitude(minLat) || !validLatitude(maxLat) {
+		return nil, fmt.Errorf("Invalid bbox latitude: %v (must be between -90 and 90)", str)
+	}
+
+	if minLon > maxLon || minLat > maxLat {
+		return nil, fmt.Errorf("Invalid bbox value: %v (the minimums must not be greater than the maximums)", str)
+	}
+
+	return bbox, nil
+}
+
+// gridPointCount is the number of points every step from min to max.  The tolerance keeps
+// a bbox that's a whole number of steps from losing its last point to rounding.
+func gridPointCount(min, max, step float64) float64 {
+	return math.Floor((max-min)/step+1e-9) + 1
+}
+
+// gridPoints returns the values every step from min to max.  The values are rounded so
+// adding up the steps doesn't give coordinates like 48.300000000000004.
+func gridPoints(min, max, step float64) []float64 {
+	points := make([]float64, int(gridPointCount(min, max, step)))
+
+	for inx := range points {
+		points[inx] = math.Round((min+float64(inx)*step)*1e6) / 1e6
+	}
+
+	return points
+}
+
+// getWeatherGrid fetches the weather at the points of the request's grid.  The options
+// are the same as /api/currentweather and apply to every point.
+func getWeatherGrid(request *http.Request) (*weatherGrid, error, int) {
+	queryValues := request.URL.Query()
+
+	if bboxParam.value(queryValues) == "" {
+		return nil, errors.New("missing bbox"), http.StatusBadRequest
+	}
+
+	bbox, err := parseBbox(bboxParam.value(queryValues))
+
+	if err != nil {
+		return nil, err, http.StatusBadRequest
+	}
+
+	stepStr := stepParam.value(queryValues)
+
+	if stepStr == "" {
+		return nil, errors.New("missing step"), http.StatusBadRequest
+	}
+
+	step, err := strconv.ParseFloat(stepStr, 64)
+
+	if err != nil || !(step > 0) {
+		return nil, fmt.Errorf("Invalid step value: %v (must be a number of degrees greater than 0)", stepStr), http.StatusBadRequest
+	}
+
+	// The number of points is checked before they're made so a tiny step can't allocate huge grids
+	cells := gridPointCount(bbox[1], bbox[3], step) * gridPointCount(bbox[0], bbox[2], step)
+
+	if cells > float64(maxGridCells) {
+		return nil, fmt.Errorf("Too many grid cells: %v (the maximum is %v, use a larger step or a smaller bbox)",
+			cells, maxGridCells), http.StatusBadRequest
+	}
+
+	fieldsStr := gridFieldsParam.value(queryValues)
+
+	if strings.TrimSpace(fieldsStr) == "" {
+		fieldsStr = defaultGridField
+	}
+
+	selection, err := data.ParseFieldSelection(fieldsStr, false)
+
+	if err != nil {
+		return nil, err, http.StatusBadRequest
+	}
+
+	options := queryOptionsFromQuery(queryValues)
+
+	if options.Lang == "" {
+		options.Lang = acceptLanguage(request.Header.Get("Accept-Language"))
+	}
+
+	grid := &weatherGrid{Bbox: bbox, Step: step, Latitudes: gridPoints(bbox[1], bbox[3], step),
+		Longitudes: gridPoints(bbox[0], bbox[2], step), Values: map[string][][]interface{}{}}
+	queries := []*weatherQuery{}
+
+	for _, latitude := range grid.Latitudes {
+		for _, longitude := range grid.Longitudes {
+			query, err, statusCode := newWeatherQuery(latitude, longitude, options)
+
+			if err != nil {
+				return nil, err, statusCode
+			}
+
+			queries = append(queries, query)
+		}
+	}
+
+	units := queries[0].Units
+	grid.Units = gridUnits{Temperature: string(units.Temperature), Wind: string(units.Speed),
+		Pressure: string(units.Pressure), Distance: string(units.Distance), Precipitation: string(units.Precipitation)}
+
+	for _, field := range selection.Apply(&data.SimplifiedWeather{}) {
+		matrix := make([][]interface{}, len(grid.Latitudes))
+
+		for row := range matrix {
+			matrix[row] = make([]interface{}, len(grid.Longitudes))
+		}
+
+		grid.Values[field.Name] = matrix
+		grid.fields = append(grid.fields, field.Name)
+	}
+
+	for inx, result := range fetchCurrentWeatherConcurrently(queries) {
+		row, column := inx/len(grid.Longitudes), inx%len(grid.Longitudes)
+
+		if result.Err != nil {
+			grid.Errors = append(grid.Errors, gridError{Latitude: grid.Latitudes[row],
+				Longitude: grid.Longitudes[column], Error: result.Err.Error()})
+			continue
+		}
+
+		for _, field := range selection.Apply(result.Simplified) {
+			grid.Values[field.Name][row][column] = field.Value
+		}
+	}
+
+	return grid, nil, http.StatusOK
+}
+
+// geoJSON returns the grid as a feature for each point, row by row.  The properties are the
+// selected fields or the error of a point whose weather couldn't be fetched.
+func (grid *weatherGrid) geoJSON() *data.GeoJSONFeatureCollection {
+	collection := &data.GeoJSONFeatureCollection{Bbox: grid.Bbox, Members: []data.SelectedField{
+		{Name: "step", Value: grid.Step}, {Name: "units", Value: grid.Units}}}
+	errors := map[[2]float64]string{}
+
+	for _, gridError := range grid.Errors {
+		errors[[2]float64{gridError.Latitude, gridError.Longitude}] = gridError.Error
+	}
+
+	for row, latitude := range grid.Latitudes {
+		for column, longitude := range grid.Longitudes {
+			properties := data.SelectedWeather{}
+
+			if err, found := errors[[2]float64{latitude, longitude}]; found {
+				properties = append(properties, data.SelectedField{Name: "error", Value: err})
+			} else {
+				for _, field := range grid.fields {
+					properties = append(properties, data.SelectedField{Name: field, Value: grid.Values[field][row][column]})
+				}
+			}
+
+			collection.Features = append(collection.Features, data.NewPointFeature(latitude, longitude, properties))
+		}
+	}
+
+	return collection
+}
+
+func apiGetWeatherGrid(requestNum uint64, writer http.ResponseWriter, request *http.Request) {
+	geoJSON, err, statusCode := wantsGeoJSON(request)
+
+	if err != nil {
+		logging.LogHTTPError(requestNum, err.Error(), statusCode)
+		http.Error(writer, err.Error(), statusCode)
+		return
+	}
+
+	grid, err, statusCode := getWeatherGrid(request)
+
+	if err != nil {
+		logging.LogHTTPError(requestNum, err.Error(), statusCode)
+		http.Error(writer, err.Error(), statusCode)
+		return
+	}
+
+	if geoJSON {
+		writeGeoJson(requestNum, writer, grid.geoJSON(), http.StatusOK)
+		return
+	}
+
+	writeJson(requestNum, writer, grid, http.StatusOK)
+}
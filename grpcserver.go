@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"current-weather-server/data"
+	"current-weather-server/logging"
+	"current-weather-server/weatherpb"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// The maximum number of locations in one BatchGetCurrentWeather call
+const maxBatchSize = 100
+
+// weatherServiceServer implements the gRPC WeatherService defined in weatherpb/weather.proto
+type weatherServiceServer struct {
+	weatherpb.UnimplementedWeatherServiceServer
+}
+
+// The context key under which the logging interceptors store the request number
+type requestNumKey struct{}
+
+func requestNumFromContext(ctx context.Context) uint64 {
+	requestNum, _ := ctx.Value(requestNumKey{}).(uint64)
+	return requestNum
+}
+
+func (s *weatherServiceServer) GetCurrentWeather(ctx context.Context,
+	request *weatherpb.GetCurrentWeatherRequest) (*weatherpb.GetCurrentWeatherResponse, error) {
+
+	simplifiedData, err, statusCode := getCurrentWeatherForGrpc(request)
+
+	if err != nil {
+		logging.LogHTTPError(requestNumFromContext(ctx), err.Error(), statusCode)
+		return nil, status.Error(grpcCode(statusCode), err.Error())
+	}
+
+	return &weatherpb.GetCurrentWeatherResponse{Weather: toProtoWeather(simplifiedData)}, nil
+}
+
+func (s *weatherServiceServer) BatchGetCurrentWeather(request *weatherpb.BatchGetCurrentWeatherRequest,
+	stream weatherpb.WeatherService_BatchGetCurrentWeatherServer) error {
+
+	requestNum := requestNumFromContext(stream.Context())
+	requests := request.GetRequests()
+
+	if len(requests) == 0 {
+		return status.Error(codes.InvalidArgument, "no locations requested")
+	}
+
+	if len(requests) > maxBatchSize {
+		return status.Error(codes.InvalidArgument,
+			fmt.Sprintf("Too many locations requested: %v (maximum is %v)", len(requests), maxBatchSize))
+	}
+
+	for inx, locationRequest := range requests {
+		if err := stream.Context().Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+
+		response := &weatherpb.BatchGetCurrentWeatherResponse{Index: int32(inx)}
+		simplifiedData, err, statusCode := getCurrentWeatherForGrpc(locationRequest)
+
+		if err != nil {
+			logging.LogHTTPError(requestNum, fmt.Sprintf("location %v: %v", inx, err), statusCode)
+			response.Code = int32(grpcCode(statusCode))
+			response.Error = err.Error()
+		} else {
+			response.Weather = toProtoWeather(simplifiedData)
+		}
+
+		if err := stream.Send(response); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getCurrentWeatherForGrpc validates a gRPC request the same way parseWeatherQuery
+// validates the HTTP query parameters and then calls Open Weather.
+func getCurrentWeatherForGrpc(request *weatherpb.GetCurrentWeatherRequest) (*data.SimplifiedWeather, error, int) {
+	options := queryOptions{
+		Units:          request.GetUnits(),
+		TempUnit:       request.GetTempUnit(),
+		WindUnit:       request.GetWindUnit(),
+		PressureUnit:   request.GetPressureUnit(),
+		DistanceUnit:   request.GetDistanceUnit(),
+		PrecipUnit:     request.GetPrecipUnit(),
+		Lang:           request.GetLang(),
+		SummaryStyle:   request.GetSummaryStyle(),
+		ComfortProfile: request.GetComfortProfile(),
+		ColdCoolWarm:   request.GetColdCoolWarm(),
+		TrendSummary:   strconv.FormatBool(request.GetTrendSummary()),
+	}
+
+	if request.Longitude == nil {
+		return nil, errors.New("missing longitude"), http.StatusBadRequest
+	}
+
+	if request.Latitude == nil {
+		return nil, errors.New("missing latitude"), http.StatusBadRequest
+	}
+
+	query, err, statusCode := newWeatherQuery(request.GetLatitude(), request.GetLongitude(), options)
+
+	if err != nil {
+		return nil, err, statusCode
+	}
+
+	_, simplifiedData, err, statusCode := fetchCurrentWeather(query)
+
+	if err != nil {
+		return nil, err, statusCode
+	}
+
+	return simplifiedData, nil, http.StatusOK
+}
+
+// grpcCode maps the HTTP status codes used by the HTTP handlers onto gRPC status codes
+func grpcCode(statusCode int) codes.Code {
+	switch statusCode {
+	case http.StatusOK:
+		return codes.OK
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return codes.Unavailable
+	case http.StatusInternalServerError:
+		return codes.Internal
+	default:
+		return codes.Unknown
+	}
+}
+
+func toProtoWeather(simplified *data.SimplifiedWeather) *weatherpb.SimplifiedWeather {
+	return &weatherpb.SimplifiedWeather{
+		Units:              simplified.Units,
+		DataCollectionTime: simplified.DataCollectionTime,
+		Latitude:           simplified.Lat,
+		Longitude:          simplified.Long,
+		CloudinessPercent:  simplified.CloudinessPercent,
+		HumidityPercent:    simplified.HumidityPercent,
+		Temp:               simplified.Temp,
+		TempHigh:           simplified.TempHigh,
+		TempLow:            simplified.TempLow,
+		TempFeelsLike:      simplified.TempFeelsLike,
+		WindSpeed:          simplified.WindSpeed,
+		WindGust:           simplified.WindGust,
+		WindDirection:      simplified.WindDirection,
+		WindUnit:           simplified.WindUnit,
+		Pressure:           simplified.Pressure,
+		PressureUnit:       simplified.PressureUnit,
+		Visibility:         simplified.Visibility,
+		DistanceUnit:       simplified.DistanceUnit,
+		RainLastHour:       simplified.Rain1h,
+		PrecipUnit:         simplified.PrecipUnit,
+		Derived: &weatherpb.DerivedMetrics{
+			DewPoint:         simplified.Derived.DewPoint,
+			HeatIndex:        simplified.Derived.HeatIndex,
+			WindChill:        simplified.Derived.WindChill,
+			Humidex:          simplified.Derived.Humidex,
+			AbsoluteHumidity: simplified.Derived.AbsoluteHumidity,
+		},
+		ExpectedWeather:    simplified.ExpectedWeather,
+		WeatherDescription: simplified.WeatherDescription,
+		SubjectiveTemp:     simplified.SubjectiveTemp,
+		ComfortProfile:     simplified.ComfortProfile,
+		Conditions:         simplified.Conditions,
+		Trends:             toProtoTrends(simplified.Trends),
+		Summary:            simplified.Summary,
+	}
+}
+
+func toProtoTrends(trends *data.Trends) *weatherpb.Trends {
+	if trends == nil {
+		return nil
+	}
+
+	return &weatherpb.Trends{
+		PressureTendency:  trends.PressureTendency,
+		PressureChange_3H: trends.PressureChange3h,
+		TempChange_1H:     trends.TempChange1h,
+		TempChange_3H:     trends.TempChange3h,
+		TempChange_24H:    trends.TempChange24h,
+		TempBaseline:      trends.TempBaseline,
+		TempAnomaly:       trends.TempAnomaly,
+		BaselineDays:      int32(trends.BaselineDays),
+	}
+}
+
+func peerAddress(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok {
+		return p.Addr.String()
+	}
+	return "unknown"
+}
+
+// logUnaryRequest is the gRPC equivalent of logRequest
+func logUnaryRequest(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+
+	requestNum := getNextRequestNumber()
+	logging.LogInfo(requestNum, fmt.Sprintf("Client: %v, gRPC: %v", peerAddress(ctx), info.FullMethod))
+	return handler(context.WithValue(ctx, requestNumKey{}, requestNum), request)
+}
+
+// logStreamRequest is the gRPC equivalent of logRequest for streaming calls
+func logStreamRequest(server interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+
+	requestNum := getNextRequestNumber()
+	logging.LogInfo(requestNum, fmt.Sprintf("Client: %v, gRPC: %v", peerAddress(stream.Context()), info.FullMethod))
+	return handler(server, &requestNumServerStream{ServerStream: stream, requestNum: requestNum})
+}
+
+// requestNumServerStream adds the request number to the context of a stream
+type requestNumServerStream struct {
+	grpc.ServerStream
+	requestNum uint64
+}
+
+func (s *requestNumServerStream) Context() context.Context {
+	return context.WithValue(s.ServerStream.Context(), requestNumKey{}, s.requestNum)
+}
+
+// startGrpcServer starts serving the WeatherService on the port in the background
+func startGrpcServer(port string) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%v", port))
+
+	if err != nil {
+		return err
+	}
+
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(logUnaryRequest),
+		grpc.StreamInterceptor(logStreamRequest))
+	weatherpb.RegisterWeatherServiceServer(server, &weatherServiceServer{})
+
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			logging.LogError(0, fmt.Sprintf("gRPC server stopped: %v", err))
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"current-weather-server/data"
+	"current-weather-server/history"
+	"current-weather-server/logging"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The store observations are recorded in.  It's nil when the server isn't started with -historyFile.
+var historyStore *history.Store
+
+// The most observations /api/history returns
+var maxHistoryResults = 5000
+
+// The range returned when /api/history isn't given a from time
+const defaultHistoryRange = 24 * time.Hour
+
+// historyResponse is returned by /api/history
+type historyResponse struct {
+	Latitude     float64                   `json:"latitude"`
+	Longitude    float64                   `json:"longitude"`
+	Location     string                    `json:"location"` // the key the observations are stored under
+	From         string                    `json:"from"`
+	To           string                    `json:"to"`
+	Observations []*data.SimplifiedWeather `json:"observations"` // oldest first
+	Truncated    bool                      `json:"truncated,omitempty"`
+}
+
+// recordObservation stores weather fetched from Open Weather (in metric units) when
+// history is enabled.  Errors are logged rather than failing the request.
+func recordObservation(query *weatherQuery, observation data.CurrentWeatherData) {
+	if historyStore == nil {
+		return
+	}
+
+	observation.Units = data.MetricUnits
+	location := history.LocationKey(query.Latitude, query.Longitude)
+
+	if _, err := historyStore.Record(location, &observation); err != nil {
+		logging.LogError(0, fmt.Sprintf("Error recording the observation of %v: %v", location, err))
+	}
+}
+
+// parseHistoryTime parses an RFC 3339 time or seconds since the Unix epoch
+func parseHistoryTime(name, str string, defaultTime time.Time) (time.Time, error) {
+	if str == "" {
+		return defaultTime, nil
+	}
+
+	if seconds, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, str)
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid %v value: %v (must be an RFC 3339 time or seconds since 1970)", name, str)
+	}
+
+	return parsed.UTC(), nil
+}
+
+// parseHistoryRange reads the from and to parameters.  The default is the last day.
+func parseHistoryRange(request *http.Request) (time.Time, time.Time, error) {
+	queryValues := request.URL.Query()
+	to, err := parseHistoryTime(historyToParam.Name, historyToParam.value(queryValues), time.Now().UTC())
+
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	from, err := parseHistoryTime(historyFromParam.Name, historyFromParam.value(queryValues), to.Add(-defaultHistoryRange))
+
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%v (%v) must not be after %v (%v)", historyFromParam.Name,
+			from.Format(time.RFC3339), historyToParam.Name, to.Format(time.RFC3339))
+	}
+
+	return from, to, nil
+}
+
+// getHistory returns the stored observations of a location in the units of the request
+func getHistory(request *http.Request) (*historyResponse, error, int) {
+	if historyStore == nil {
+		return nil, errors.New("History isn't enabled on this server"), http.StatusNotFound
+	}
+
+	query, err, statusCode := parseWeatherQuery(request)
+
+	if err != nil {
+		return nil, err, statusCode
+	}
+
+	from, to, err := parseHistoryRange(request)
+
+	if err != nil {
+		return nil, err, http.StatusBadRequest
+	}
+
+	location := history.LocationKey(query.Latitude, query.Longitude)
+	observations, truncated, err := historyStore.Observations(location, from, to, maxHistoryResults)
+
+	if err != nil {
+		return nil, err, http.StatusInternalServerError
+	}
+
+	response := &historyResponse{Latitude: query.Latitude, Longitude: query.Longitude, Location: location,
+		From: from.Format(time.RFC3339), To: to.Format(time.RFC3339),
+		Observations: make([]*data.SimplifiedWeather, 0, len(observations)), Truncated: truncated}
+
+	for _, observation := range observations {
+		// The weather descriptions stay in the language they were fetched in
+		observation.Lang = query.Lang
+		simplified, err := convertForQuery(query, observation, nil)
+
+		if err != nil {
+			return nil, err, http.StatusInternalServerError
+		}
+
+		response.Observations = append(response.Observations, simplified)
+	}
+
+	return response, nil, http.StatusOK
+}
+
+// geoJSON returns the observations as features.  The range and whether the observations
+// were truncated are foreign members of the collection.
+func (response *historyResponse) geoJSON() *data.GeoJSONFeatureCollection {
+	collection := &data.GeoJSONFeatureCollection{Members: []data.SelectedField{{Name: "location", Value: response.Location},
+		{Name: "from", Value: response.From}, {Name: "to", Value: response.To}}}
+
+	if response.Truncated {
+		collection.Members = append(collection.Members, data.SelectedField{Name: "truncated", Value: true})
+	}
+
+	for _, observation := range response.Observations {
+		collection.Features = append(collection.Features, data.NewWeatherFeature(observation, nil))
+	}
+
+	return collection
+}
+
+func apiGetHistory(requestNum uint64, writer http.ResponseWriter, request *http.Request) {
+	geoJSON, err, statusCode := wantsGeoJSON(request)
+
+	if err != nil {
+		logging.LogHTTPError(requestNum, err.Error(), statusCode)
+		http.Error(writer, err.Error(), statusCode)
+		return
+	}
+
+	response, err, statusCode := getHistory(request)
+
+	if err != nil {
+		logging.LogHTTPError(requestNum, err.Error(), statusCode)
+		http.Error(writer, err.Error(), statusCode)
+		return
+	}
+
+	if geoJSON {
+		writeGeoJson(requestNum, writer, response.geoJSON(), http.StatusOK)
+		return
+	}
+
+	writeJson(requestNum, writer, response, http.StatusOK)
+}
+
+// historyAggregate is an hour, day, or week of a location's history.  Start is in the
+// location's local time.  Temperatures and rain are in the units of the request.
+type historyAggregate struct {
+	Start                 string  `json:"start"`
+	Observations          int     `json:"observations"`
+	TempMin               float64 `json:"tempMin"`
+	TempMax               float64 `json:"tempMax"`
+	TempMean              float64 `json:"tempMean"`
+	RainTotal             float64 `json:"rainTotal"`
+	HumidityPercentMean   float64 `json:"humidityPercentMean"`
+	CloudinessPercentMean float64 `json:"cloudinessPercentMean"`
+}
+
+// historyAggregatesResponse is returned by /api/history/aggregate
+type historyAggregatesResponse struct {
+	Latitude   float64            `json:"latitude"`
+	Longitude  float64            `json:"longitude"`
+	Location   string             `json:"location"`
+	Interval   string             `json:"interval"`
+	From       string             `json:"from"`
+	To         string             `json:"to"`
+	Units      string             `json:"units"` // the temperature unit
+	PrecipUnit string             `json:"precipUnit"`
+	Aggregates []historyAggregate `json:"aggregates"` // oldest first
+	Truncated  bool               `json:"truncated,omitempty"`
+}
+
+// getHistoryAggregates returns the hourly, daily, or weekly aggregates of a location
+func getHistoryAggregates(request *http.Request) (*historyAggregatesResponse, error, int) {
+	if historyStore == nil {
+		return nil, errors.New("History isn't enabled on this server"), http.StatusNotFound
+	}
+
+	query, err, statusCode := parseWeatherQuery(request)
+
+	if err != nil {
+		return nil, err, statusCode
+	}
+
+	intervalStr := intervalParam.value(request.URL.Query())
+
+	if intervalStr == "" {
+		intervalStr = string(history.Day)
+	}
+
+	interval, err := history.ParseInterval(intervalStr)
+
+	if err != nil {
+		return nil, err, http.StatusBadRequest
+	}
+
+	from, to, err := parseHistoryRange(request)
+
+	if err != nil {
+		return nil, err, http.StatusBadRequest
+	}
+
+	location := history.LocationKey(query.Latitude, query.Longitude)
+	aggregates, truncated, err := historyStore.Aggregates(location, interval, from, to, maxHistoryResults)
+
+	if err != nil {
+		return nil, err, http.StatusInternalServerError
+	}
+
+	units := query.Units
+	temperature := func(c float64) float64 { return math.Round(units.Temperature.FromCelsius(c)*100) / 100 }
+	round := func(value float64) float64 { return math.Round(value*100) / 100 }
+	response := &historyAggregatesResponse{Latitude: query.Latitude, Longitude: query.Longitude, Location: location,
+		Interval: string(interval), From: from.Format(time.RFC3339), To: to.Format(time.RFC3339),
+		Units: string(units.Temperature), PrecipUnit: string(units.Precipitation),
+		Aggregates: make([]historyAggregate, len(aggregates)), Truncated: truncated}
+
+	for inx, aggregate := range aggregates {
+		response.Aggregates[inx] = historyAggregate{
+			Start:                 aggregate.StartTime().Format(time.RFC3339),
+			Observations:          aggregate.Count,
+			TempMin:               temperature(aggregate.TempMin),
+			TempMax:               temperature(aggregate.TempMax),
+			TempMean:              temperature(aggregate.TempMean()),
+			RainTotal:             math.Round(units.Precipitation.FromMillimeters(aggregate.Rain)*1000) / 1000,
+			HumidityPercentMean:   round(aggregate.HumidityMean()),
+			CloudinessPercentMean: round(aggregate.CloudinessMean()),
+		}
+	}
+
+	return response, nil, http.StatusOK
+}
+
+func apiGetHistoryAggregates(requestNum uint64, writer http.ResponseWriter, request *http.Request) {
+	geoJSON, err, statusCode := wantsGeoJSON(request)
+
+	if err != nil {
+		logging.LogHTTPError(requestNum, err.Error(), statusCode)
+		http.Error(writer, err.Error(), statusCode)
+		return
+	}
+
+	response, err, statusCode := getHistoryAggregates(request)
+
+	if err != nil {
+		logging.LogHTTPError(requestNum, err.Error(), statusCode)
+		http.Error(writer, err.Error(), statusCode)
+		return
+	}
+
+	if geoJSON {
+		writeGeoJson(requestNum, writer, data.NewPointFeature(response.Latitude, response.Longitude, response), http.StatusOK)
+		return
+	}
+
+	writeJson(requestNum, writer, response, http.StatusOK)
+}
+
+// startHistoryCompactor removes the history that's older than its retention now and then every interval
+func startHistoryCompactor(retention history.Retention, interval time.Duration) {
+	go func() {
+		for {
+			compactHistory(retention)
+			time.Sleep(interval)
+		}
+	}()
+}
+
+func compactHistory(retention history.Retention) {
+	result, err := historyStore.Compact(retention, time.Now())
+
+	if err != nil {
+		logging.LogError(0, fmt.Sprintf("Error compacting history: %v", err))
+		return
+	}
+
+	removed := []string{}
+	for _, tier := range history.RetentionTiers {
+		if count := result.Removed[tier]; count > 0 {
+			removed = append(removed, fmt.Sprintf("%v %v", count, tier))
+		}
+	}
+
+	if len(removed) == 0 {
+		removed = append(removed, "nothing")
+	}
+
+	logging.LogInfo(0, fmt.Sprintf("Compacted history in %v: removed %v, %v bytes reclaimed",
+		result.Duration.Round(time.Millisecond), strings.Join(removed, ", "), result.BytesReclaimed))
+}
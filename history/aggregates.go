@@ -0,0 +1,262 @@
+package history
+
+import (
+	"current-weather-server/data"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Interval is the length of the local time periods observations are aggregated over
+type Interval string
+
+const (
+	Hour Interval = "hour"
+	Day  Interval = "day"
+	Week Interval = "week" // weeks start on Monday
+)
+
+// Intervals are the intervals in the order they're updated
+var Intervals = []Interval{Hour, Day, Week}
+
+// The bucket holding a bucket for each interval, each holding a bucket of aggregates for each location
+var aggregatesBucket = []byte("aggregates")
+
+// The most an observation's local time can differ from UTC (Open Weather's Timezone)
+const maxTimezoneOffset = 14 * 60 * 60
+
+// Aggregate summarizes the observations of a location over an hour, day, or week of
+// its local time.  The values are metric.  Rain is the rain that fell during the
+// interval: the average of the hour's observations of the rain in the last hour
+// for an hour, and the total of the hours for a day or week.
+type Aggregate struct {
+	Start         int64   `json:"start"`    // the local wall clock time the interval starts at in seconds since 1970
+	Timezone      int     `json:"timezone"` // the UTC offset of the latest observation in seconds
+	Count         int     `json:"count"`
+	TempMin       float64 `json:"tempMin"`
+	TempMax       float64 `json:"tempMax"`
+	TempSum       float64 `json:"tempSum"`
+	HumiditySum   float64 `json:"humiditySum"`
+	CloudinessSum float64 `json:"cloudinessSum"`
+	Rain          float64 `json:"rain"`
+	RainSum       float64 `json:"rainSum,omitempty"` // the sum of the observations' rain in the last hour (hours only)
+}
+
+// ParseInterval parses hour, day, or week
+func ParseInterval(str string) (Interval, error) {
+	for _, interval := range Intervals {
+		if string(interval) == str {
+			return interval, nil
+		}
+	}
+
+	return "", fmt.Errorf("Invalid interval: %v (must be %v, %v, or %v)", str, Hour, Day, Week)
+}
+
+// start returns the local start of the interval containing a local time.  Both are
+// wall clock seconds since 1970 so DST changes don't move the start of a day.
+func (interval Interval) start(local int64) int64 {
+	switch interval {
+	case Hour:
+		return floorTo(local, 60*60)
+	case Day:
+		return floorTo(local, 24*60*60)
+	}
+
+	// 1970-01-01 was a Thursday, three days after the start of its week.  Flooring
+	// rather than taking the remainder keeps the days before 1970 in the right week.
+	days := floorTo(local, 24*60*60) / (24 * 60 * 60)
+	return (floorTo(days+3, 7) - 3) * 24 * 60 * 60
+}
+
+func floorTo(value, step int64) int64 {
+	if value < 0 {
+		return -((-value + step - 1) / step) * step
+	}
+	return value / step * step
+}
+
+// StartTime is the start of the interval in the location's time zone
+func (aggregate *Aggregate) StartTime() time.Time {
+	return time.Unix(aggregate.Start-int64(aggregate.Timezone), 0).In(time.FixedZone("", aggregate.Timezone))
+}
+
+func (aggregate *Aggregate) TempMean() float64 {
+	return aggregate.TempSum / float64(aggregate.Count)
+}
+
+func (aggregate *Aggregate) HumidityMean() float64 {
+	return aggregate.HumiditySum / float64(aggregate.Count)
+}
+
+func (aggregate *Aggregate) CloudinessMean() float64 {
+	return aggregate.CloudinessSum / float64(aggregate.Count)
+}
+
+func (aggregate *Aggregate) add(observation *data.CurrentWeatherData) {
+	temp := observation.Main.Temp
+
+	if aggregate.Count == 0 || temp < aggregate.TempMin {
+		aggregate.TempMin = temp
+	}
+
+	if aggregate.Count == 0 || temp > aggregate.TempMax {
+		aggregate.TempMax = temp
+	}
+
+	aggregate.Count++
+	aggregate.Timezone = observation.Timezone
+	aggregate.TempSum += temp
+	aggregate.HumiditySum += observation.Main.Humidity
+	aggregate.CloudinessSum += observation.Clouds.All
+}
+
+// addToAggregates adds a new observation to the aggregates of its hour, day, and week, so the
+// aggregates are kept up to date without reading the observations again.  The change in the
+// hour's rain is added to the day and week.
+func addToAggregates(tx *bolt.Tx, location string, observation *data.CurrentWeatherData) error {
+	local := int64(observation.Dt) + int64(observation.Timezone)
+	rainChange := 0.0
+
+	for _, interval := range Intervals {
+		bucket, err := aggregateBucket(tx, interval, location, true)
+
+		if err != nil {
+			return err
+		}
+
+		start := interval.start(local)
+		aggregate, err := getAggregate(bucket, start)
+
+		if err != nil {
+			return err
+		}
+
+		aggregate.add(observation)
+
+		if interval == Hour {
+			previousRain := aggregate.Rain
+			aggregate.RainSum += observation.Rain.H
+			aggregate.Rain = aggregate.RainSum / float64(aggregate.Count)
+			rainChange = aggregate.Rain - previousRain
+		} else {
+			aggregate.Rain += rainChange
+		}
+
+		value, err := json.Marshal(aggregate)
+
+		if err != nil {
+			return err
+		}
+
+		if err := bucket.Put(timeKey(start), value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// aggregateBucket returns the bucket of the location's aggregates over the interval.
+// It's nil when create is false and the location has none.
+func aggregateBucket(tx *bolt.Tx, interval Interval, location string, create bool) (*bolt.Bucket, error) {
+	intervals := tx.Bucket(aggregatesBucket)
+
+	if !create {
+		if intervalBucket := intervals.Bucket([]byte(interval)); intervalBucket != nil {
+			return intervalBucket.Bucket([]byte(location)), nil
+		}
+		return nil, nil
+	}
+
+	intervalBucket, err := intervals.CreateBucketIfNotExists([]byte(interval))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return intervalBucket.CreateBucketIfNotExists([]byte(location))
+}
+
+// getAggregate returns the aggregate starting at start or an empty one
+func getAggregate(bucket *bolt.Bucket, start int64) (*Aggregate, error) {
+	aggregate := &Aggregate{Start: start}
+	value := bucket.Get(timeKey(start))
+
+	if value == nil {
+		return aggregate, nil
+	}
+
+	if err := json.Unmarshal(value, aggregate); err != nil {
+		return nil, fmt.Errorf("Error reading aggregate at %v: %v", start, err)
+	}
+
+	return aggregate, nil
+}
+
+// Aggregates returns the location's aggregates over the interval whose start is from from
+// up to and including to, oldest first.  At most limit are returned and truncated is true
+// when there were more.
+func (store *Store) Aggregates(location string, interval Interval, from, to time.Time, limit int) (aggregates []*Aggregate, truncated bool, err error) {
+	aggregates = []*Aggregate{}
+
+	err = store.db.View(func(tx *bolt.Tx) error {
+		bucket, err := aggregateBucket(tx, interval, location, false)
+
+		if err != nil || bucket == nil {
+			return err
+		}
+
+		// The keys are local times so the range is widened by the largest UTC offset
+		// and each aggregate's start is checked against the range
+		cursor := bucket.Cursor()
+		end := timeKey(to.Unix() + maxTimezoneOffset)
+
+		for key, value := cursor.Seek(timeKey(max(from.Unix()-maxTimezoneOffset, 0))); key != nil && string(key) <= string(end); key, value = cursor.Next() {
+			aggregate := &Aggregate{}
+
+			if err := json.Unmarshal(value, aggregate); err != nil {
+				return fmt.Errorf("Error reading the %v aggregate of %v at %v: %v", interval, location,
+					binary.BigEndian.Uint64(key), err)
+			}
+
+			if start := aggregate.StartTime(); start.Before(from) || start.After(to) {
+				continue
+			}
+
+			if len(aggregates) == limit {
+				return errLimitReached
+			}
+
+			aggregates = append(aggregates, aggregate)
+		}
+
+		return nil
+	})
+
+	if err == errLimitReached {
+		return aggregates, true, nil
+	}
+
+	return aggregates, false, err
+}
+
+// buildAggregates adds every stored observation to the aggregates.  It's used once,
+// when a history file recorded before there were aggregates is opened.
+func buildAggregates(tx *bolt.Tx) error {
+	return tx.Bucket(observationsBucket).ForEachBucket(func(location []byte) error {
+		return tx.Bucket(observationsBucket).Bucket(location).ForEach(func(key, value []byte) error {
+			var observation data.CurrentWeatherData
+
+			if err := json.Unmarshal(value, &observation); err != nil {
+				return fmt.Errorf("Error reading the observation of %v at %v: %v", string(location),
+					binary.BigEndian.Uint64(key), err)
+			}
+
+			return addToAggregates(tx, string(location), &observation)
+		})
+	})
+}
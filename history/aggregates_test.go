@@ -0,0 +1,251 @@
+package history
+
+import (
+	"current-weather-server/data"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func openTestStore(t *testing.T) *Store {
+	store, err := Open(filepath.Join(t.TempDir(), "history.db"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func testObservation(dt time.Time, timezone int, temp, rain float64) *data.CurrentWeatherData {
+	observation := &data.CurrentWeatherData{Units: data.MetricUnits}
+	observation.Dt = int(dt.Unix())
+	observation.Timezone = timezone
+	observation.Main.Temp = temp
+	observation.Main.Humidity = 50 + temp
+	observation.Clouds.All = 2 * temp
+	observation.Rain.H = rain
+	return observation
+}
+
+// readAggregates returns the stored aggregates of every interval and location
+func readAggregates(t *testing.T, store *Store) map[string]string {
+	aggregates := map[string]string{}
+
+	err := store.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(aggregatesBucket).ForEachBucket(func(interval []byte) error {
+			intervalBucket := tx.Bucket(aggregatesBucket).Bucket(interval)
+
+			return intervalBucket.ForEachBucket(func(location []byte) error {
+				return intervalBucket.Bucket(location).ForEach(func(key, value []byte) error {
+					aggregates[string(interval)+"/"+string(location)+"/"+string(key)] = string(value)
+					return nil
+				})
+			})
+		})
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return aggregates
+}
+
+// The aggregates updated as each observation is recorded are the ones built from all of them
+func TestRecordedAggregatesMatchBuilt(t *testing.T) {
+	store := openTestStore(t)
+	start := time.Date(2024, 3, 30, 22, 10, 0, 0, time.UTC)
+
+	// Crosses hours, a day, a week (Monday April 1st), and a UTC offset change
+	for i := 0; i < 40; i++ {
+		timezone := 3600
+		if i >= 20 {
+			timezone = 7200
+		}
+
+		observation := testObservation(start.Add(time.Duration(i)*50*time.Minute), timezone, float64(i%7), float64(i%3)/2)
+
+		for _, location := range []string{"48.86,2.35", "-33.87,151.21"} {
+			if _, err := store.Record(location, observation); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	recorded := readAggregates(t, store)
+
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(aggregatesBucket); err != nil {
+			return err
+		}
+
+		if _, err := tx.CreateBucket(aggregatesBucket); err != nil {
+			return err
+		}
+
+		return buildAggregates(tx)
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if built := readAggregates(t, store); !reflect.DeepEqual(recorded, built) {
+		t.Errorf("Recorded aggregates %v, built %v", recorded, built)
+	}
+}
+
+// An hour's rain is the average of its observations' rain in the last hour and the days and
+// weeks total their hours, however the observations are spread over the hours
+func TestAggregateRain(t *testing.T) {
+	monday := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		minutes   []int // after midnight on Monday
+		rain      []float64
+		hourRain  []float64 // of the hours with observations, in order
+		totalRain float64   // of the day and week
+	}{
+		{"one observation", []int{10}, []float64{2}, []float64{2}, 2},
+		{"averaged hour", []int{10, 20, 30}, []float64{1, 2, 6}, []float64{3}, 3},
+		{"two hours", []int{10, 40, 70}, []float64{1, 2, 4}, []float64{1.5, 4}, 5.5},
+		{"rain stops", []int{10, 70, 80, 130}, []float64{3, 1, 0, 0}, []float64{3, 0.5, 0}, 3.5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := openTestStore(t)
+
+			for i, minute := range test.minutes {
+				observation := testObservation(monday.Add(time.Duration(minute)*time.Minute), 0, 10, test.rain[i])
+
+				if _, err := store.Record("0.00,0.00", observation); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			hours, _, err := store.Aggregates("0.00,0.00", Hour, monday, monday.Add(24*time.Hour), 100)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			hourRain := []float64{}
+			for _, hour := range hours {
+				hourRain = append(hourRain, hour.Rain)
+			}
+
+			if !reflect.DeepEqual(hourRain, test.hourRain) {
+				t.Errorf("Hourly rain = %v, want %v", hourRain, test.hourRain)
+			}
+
+			for _, interval := range []Interval{Day, Week} {
+				aggregates, _, err := store.Aggregates("0.00,0.00", interval, monday, monday, 100)
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if len(aggregates) != 1 || math.Abs(aggregates[0].Rain-test.totalRain) > 1e-9 {
+					t.Errorf("%v aggregates = %+v, want rain %v", interval, aggregates, test.totalRain)
+				}
+			}
+		})
+	}
+}
+
+func TestIntervalStart(t *testing.T) {
+	tests := []struct {
+		interval Interval
+		local    time.Time
+		want     time.Time
+	}{
+		{Hour, time.Date(2024, 4, 3, 10, 59, 59, 0, time.UTC), time.Date(2024, 4, 3, 10, 0, 0, 0, time.UTC)},
+		{Day, time.Date(2024, 4, 3, 10, 59, 59, 0, time.UTC), time.Date(2024, 4, 3, 0, 0, 0, 0, time.UTC)},
+		{Week, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{Week, time.Date(2024, 4, 7, 23, 59, 59, 0, time.UTC), time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{Week, time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(1969, 12, 29, 0, 0, 0, 0, time.UTC)},
+		{Week, time.Date(1970, 1, 4, 23, 59, 59, 0, time.UTC), time.Date(1969, 12, 29, 0, 0, 0, 0, time.UTC)},
+		{Week, time.Date(1970, 1, 5, 0, 0, 0, 0, time.UTC), time.Date(1970, 1, 5, 0, 0, 0, 0, time.UTC)},
+
+		// Local times before 1970
+		{Hour, time.Date(1969, 12, 31, 23, 30, 0, 0, time.UTC), time.Date(1969, 12, 31, 23, 0, 0, 0, time.UTC)},
+		{Day, time.Date(1969, 12, 31, 14, 0, 0, 0, time.UTC), time.Date(1969, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{Week, time.Date(1969, 12, 31, 14, 0, 0, 0, time.UTC), time.Date(1969, 12, 29, 0, 0, 0, 0, time.UTC)},
+		{Week, time.Date(1969, 12, 29, 0, 0, 0, 0, time.UTC), time.Date(1969, 12, 29, 0, 0, 0, 0, time.UTC)},
+		{Week, time.Date(1969, 12, 28, 23, 59, 59, 0, time.UTC), time.Date(1969, 12, 22, 0, 0, 0, 0, time.UTC)},
+		{Week, time.Date(1969, 12, 26, 12, 0, 0, 0, time.UTC), time.Date(1969, 12, 22, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		if got := test.interval.start(test.local.Unix()); got != test.want.Unix() {
+			t.Errorf("%v start of %v = %v, want %v", test.interval, test.local, time.Unix(got, 0).UTC(), test.want)
+		}
+	}
+}
+
+// Aggregates are keyed by local time but selected by the UTC time they start at
+func TestAggregatesTimezones(t *testing.T) {
+	tests := []struct {
+		name     string
+		timezone int
+		from, to time.Time
+		want     []time.Time // the UTC starts of the hours returned
+	}{
+		{"ahead of UTC", 10 * 3600,
+			time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 4, 1, 1, 0, 0, 0, time.UTC),
+			[]time.Time{time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 4, 1, 1, 0, 0, 0, time.UTC)}},
+		{"behind UTC", -5 * 3600,
+			time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 4, 1, 1, 0, 0, 0, time.UTC),
+			[]time.Time{time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 4, 1, 1, 0, 0, 0, time.UTC)}},
+		{"largest offset", maxTimezoneOffset,
+			time.Date(2024, 4, 1, 2, 0, 0, 0, time.UTC), time.Date(2024, 4, 1, 2, 0, 0, 0, time.UTC),
+			[]time.Time{time.Date(2024, 4, 1, 2, 0, 0, 0, time.UTC)}},
+		{"smallest offset", -12 * 3600,
+			time.Date(2024, 4, 1, 2, 0, 0, 0, time.UTC), time.Date(2024, 4, 1, 2, 0, 0, 0, time.UTC),
+			[]time.Time{time.Date(2024, 4, 1, 2, 0, 0, 0, time.UTC)}},
+		{"starts inside the range only", 10 * 3600,
+			time.Date(2024, 4, 1, 0, 30, 0, 0, time.UTC), time.Date(2024, 4, 1, 1, 30, 0, 0, time.UTC),
+			[]time.Time{time.Date(2024, 4, 1, 1, 0, 0, 0, time.UTC)}},
+		{"before the observations", 10 * 3600,
+			time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC), time.Date(2024, 3, 31, 22, 0, 0, 0, time.UTC),
+			[]time.Time{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := openTestStore(t)
+
+			// An observation in each UTC hour from midnight to 4am on April 1st
+			for hour := 0; hour < 5; hour++ {
+				dt := time.Date(2024, 4, 1, hour, 30, 0, 0, time.UTC)
+
+				if _, err := store.Record("0.00,0.00", testObservation(dt, test.timezone, 10, 0)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			aggregates, _, err := store.Aggregates("0.00,0.00", Hour, test.from, test.to, 100)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []time.Time{}
+			for _, aggregate := range aggregates {
+				got = append(got, aggregate.StartTime().UTC())
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Hours starting %v, want %v", got, test.want)
+			}
+		})
+	}
+}
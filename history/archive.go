@@ -0,0 +1,515 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"current-weather-server/data"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// The archive formats
+const (
+	NDJSON = "ndjson"
+	CSV    = "csv"
+)
+
+// How many observations are read in one transaction while exporting
+const exportBatchSize = 500
+
+// ArchivedObservation is the flat form of an observation that's exported and imported.
+// The values are metric.  The CSV columns are the json names.
+type ArchivedObservation struct {
+	Location           string   `json:"location"` // optional when importing, LocationKey of the latitude and longitude
+	Latitude           float64  `json:"latitude"`
+	Longitude          float64  `json:"longitude"`
+	Dt                 int64    `json:"dt"`       // the observation time in seconds since 1970
+	Timezone           int      `json:"timezone"` // the UTC offset of the location in seconds
+	Temp               float64  `json:"temp"`
+	FeelsLike          float64  `json:"feelsLike"`
+	TempMin            float64  `json:"tempMin"`
+	TempMax            float64  `json:"tempMax"`
+	Pressure           float64  `json:"pressure"`
+	Humidity           float64  `json:"humidity"`
+	Visibility         *float64 `json:"visibility,omitempty"`
+	WindSpeed          float64  `json:"windSpeed"`
+	WindDeg            float64  `json:"windDeg"`
+	WindGust           float64  `json:"windGust"`
+	Rain1h             float64  `json:"rain1h"`
+	Clouds             float64  `json:"clouds"`
+	WeatherId          int      `json:"weatherId"`
+	WeatherMain        string   `json:"weatherMain"`
+	WeatherDescription string   `json:"weatherDescription"`
+	Lang               string   `json:"lang"`
+	Name               string   `json:"name"`
+	Country            string   `json:"country"`
+}
+
+// The columns an import must have
+var requiredArchiveColumns = []string{"latitude", "longitude", "dt", "temp"}
+
+// NewArchivedObservation flattens a stored observation
+func NewArchivedObservation(location string, observation *data.CurrentWeatherData) *ArchivedObservation {
+	archived := &ArchivedObservation{
+		Location:   location,
+		Latitude:   observation.Coord.Lat,
+		Longitude:  observation.Coord.Lon,
+		Dt:         int64(observation.Dt),
+		Timezone:   observation.Timezone,
+		Temp:       observation.Main.Temp,
+		FeelsLike:  observation.Main.FeelsLike,
+		TempMin:    observation.Main.TempMin,
+		TempMax:    observation.Main.TempMax,
+		Pressure:   observation.Main.Pressure,
+		Humidity:   observation.Main.Humidity,
+		Visibility: observation.Visibility,
+		WindSpeed:  observation.Wind.Speed,
+		WindDeg:    observation.Wind.Deg,
+		WindGust:   observation.Wind.Gust,
+		Rain1h:     observation.Rain.H,
+		Clouds:     observation.Clouds.All,
+		Lang:       observation.Lang,
+		Name:       observation.Name,
+		Country:    observation.Sys.Country,
+	}
+
+	if len(observation.Weather) > 0 {
+		archived.WeatherId = observation.Weather[0].Id
+		archived.WeatherMain = observation.Weather[0].Main
+		archived.WeatherDescription = observation.Weather[0].Description
+	}
+
+	return archived
+}
+
+// CurrentWeatherData maps the archived observation onto the observation model
+func (archived *ArchivedObservation) CurrentWeatherData() *data.CurrentWeatherData {
+	observation := &data.CurrentWeatherData{Units: data.MetricUnits, Lang: archived.Lang}
+	observation.Coord.Lat = archived.Latitude
+	observation.Coord.Lon = archived.Longitude
+	observation.Dt = int(archived.Dt)
+	observation.Timezone = archived.Timezone
+	observation.Main.Temp = archived.Temp
+	observation.Main.FeelsLike = archived.FeelsLike
+	observation.Main.TempMin = archived.TempMin
+	observation.Main.TempMax = archived.TempMax
+	observation.Main.Pressure = archived.Pressure
+	observation.Main.Humidity = archived.Humidity
+	observation.Visibility = archived.Visibility
+	observation.Wind.Speed = archived.WindSpeed
+	observation.Wind.Deg = archived.WindDeg
+	observation.Wind.Gust = archived.WindGust
+	observation.Rain.H = archived.Rain1h
+	observation.Clouds.All = archived.Clouds
+	observation.Name = archived.Name
+	observation.Sys.Country = archived.Country
+	observation.Weather = append(observation.Weather, struct {
+		Id          int    `json:"id"`
+		Main        string `json:"main"`
+		Description string `json:"description"`
+		Icon        string `json:"icon"`
+	}{Id: archived.WeatherId, Main: archived.WeatherMain, Description: archived.WeatherDescription})
+
+	return observation
+}
+
+// Validate checks that the values are possible and fills in the location
+func (archived *ArchivedObservation) Validate() error {
+	checks := []struct {
+		name     string
+		value    float64
+		min, max float64
+	}{
+		{"latitude", archived.Latitude, -90, 90},
+		{"longitude", archived.Longitude, -180, 180},
+		{"timezone", float64(archived.Timezone), -maxTimezoneOffset, maxTimezoneOffset},
+		{"temp", archived.Temp, -100, 70},
+		{"feelsLike", archived.FeelsLike, -100, 70},
+		{"tempMin", archived.TempMin, -100, 70},
+		{"tempMax", archived.TempMax, -100, 70},
+		{"pressure", archived.Pressure, 0, 1200},
+		{"humidity", archived.Humidity, 0, 100},
+		{"windSpeed", archived.WindSpeed, 0, 150},
+		{"windDeg", archived.WindDeg, 0, 360},
+		{"windGust", archived.WindGust, 0, 150},
+		{"rain1h", archived.Rain1h, 0, 500},
+		{"clouds", archived.Clouds, 0, 100},
+	}
+
+	for _, check := range checks {
+		if !(check.value >= check.min && check.value <= check.max) {
+			return fmt.Errorf("Invalid %v: %v (must be between %v and %v)", check.name, check.value, check.min, check.max)
+		}
+	}
+
+	// Visibility is left out when Open Weather didn't report it
+	if archived.Visibility != nil && !(*archived.Visibility >= 0 && *archived.Visibility <= 100000) {
+		return fmt.Errorf("Invalid visibility: %v (must be between 0 and 100000)", *archived.Visibility)
+	}
+
+	if archived.Dt <= 0 {
+		return fmt.Errorf("Invalid dt: %v (must be seconds since 1970)", archived.Dt)
+	}
+
+	location := LocationKey(archived.Latitude, archived.Longitude)
+
+	if archived.Location != "" && archived.Location != location {
+		return fmt.Errorf("location %v doesn't match the latitude and longitude (%v)", archived.Location, location)
+	}
+
+	archived.Location = location
+	return nil
+}
+
+// archiveColumns maps the CSV column names to the ArchivedObservation fields
+var archiveColumns = func() []string {
+	archivedType := reflect.TypeOf(ArchivedObservation{})
+	columns := make([]string, archivedType.NumField())
+
+	for inx := range columns {
+		columns[inx] = strings.Split(archivedType.Field(inx).Tag.Get("json"), ",")[0]
+	}
+
+	return columns
+}()
+
+// ArchiveColumns returns the CSV columns in the order they're exported
+func ArchiveColumns() []string {
+	return append([]string{}, archiveColumns...)
+}
+
+// CSVRecord returns the values in the order of ArchiveColumns
+func (archived *ArchivedObservation) CSVRecord() []string {
+	value := reflect.ValueOf(archived).Elem()
+	record := make([]string, value.NumField())
+
+	for inx := range record {
+		switch field := value.Field(inx); field.Kind() {
+		case reflect.Float64:
+			record[inx] = strconv.FormatFloat(field.Float(), 'f', -1, 64)
+		case reflect.Ptr:
+			if !field.IsNil() {
+				record[inx] = strconv.FormatFloat(field.Elem().Float(), 'f', -1, 64)
+			}
+		default:
+			record[inx] = fmt.Sprint(field.Interface())
+		}
+	}
+
+	return record
+}
+
+// setColumn parses a CSV value into the field of the column
+func (archived *ArchivedObservation) setColumn(column int, str string) error {
+	field := reflect.ValueOf(archived).Elem().Field(column)
+	str = strings.TrimSpace(str)
+
+	if str == "" {
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.Float64, reflect.Ptr:
+		value, err := strconv.ParseFloat(str, 64)
+
+		if err != nil {
+			return fmt.Errorf("Invalid %v: %v", archiveColumns[column], str)
+		}
+
+		if field.Kind() == reflect.Ptr {
+			field.Set(reflect.ValueOf(&value))
+		} else {
+			field.SetFloat(value)
+		}
+	case reflect.Int, reflect.Int64:
+		value, err := strconv.ParseInt(str, 10, 64)
+
+		if err != nil {
+			return fmt.Errorf("Invalid %v: %v", archiveColumns[column], str)
+		}
+
+		field.SetInt(value)
+	default:
+		field.SetString(str)
+	}
+
+	return nil
+}
+
+// ReadArchive reads observations from an NDJSON or CSV file and calls fn with each one (or
+// the error reading it) and its line number.  The observations aren't validated.  CSV files
+// must start with a header of column names, which can be in any order.  Unknown columns or
+// fields are errors so misnamed columns aren't silently dropped.
+func ReadArchive(reader io.Reader, format string, fn func(line int, archived *ArchivedObservation, err error) error) error {
+	switch format {
+	case NDJSON:
+		return readNDJSON(reader, fn)
+	case CSV:
+		return readCSV(reader, fn)
+	}
+
+	return fmt.Errorf("Invalid archive format: %v (must be %v or %v)", format, NDJSON, CSV)
+}
+
+func readNDJSON(reader io.Reader, fn func(line int, archived *ArchivedObservation, err error) error) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		decoder.DisallowUnknownFields()
+		archived := &ArchivedObservation{}
+		err := decoder.Decode(archived)
+
+		if err == nil {
+			err = checkRequiredFields(scanner.Bytes())
+		}
+
+		if err != nil {
+			archived = nil
+		}
+
+		if err := fn(line, archived, err); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func isRequiredColumn(column string) bool {
+	for _, required := range requiredArchiveColumns {
+		if column == required {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRequiredFields checks that a json observation has the required columns so
+// a missing value isn't mistaken for 0
+func checkRequiredFields(line []byte) error {
+	var fields map[string]json.RawMessage
+
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return err
+	}
+
+	for _, column := range requiredArchiveColumns {
+		if value, found := fields[column]; !found || string(value) == "null" {
+			return fmt.Errorf("Missing %v", column)
+		}
+	}
+
+	return nil
+}
+
+func readCSV(reader io.Reader, fn func(line int, archived *ArchivedObservation, err error) error) error {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	header, err := csvReader.Read()
+
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	columns := make([]int, len(header))
+	found := map[string]bool{}
+
+	for inx, name := range header {
+		columns[inx] = -1
+		name = strings.TrimSpace(name)
+
+		for column, archiveColumn := range archiveColumns {
+			if name == archiveColumn {
+				columns[inx] = column
+			}
+		}
+
+		if columns[inx] == -1 {
+			return fmt.Errorf("Invalid column: %v (valid columns are %v)", name, strings.Join(archiveColumns, ", "))
+		}
+
+		found[name] = true
+	}
+
+	for _, column := range requiredArchiveColumns {
+		if !found[column] {
+			return fmt.Errorf("Missing column: %v", column)
+		}
+	}
+
+	for {
+		record, err := csvReader.Read()
+
+		if err == io.EOF {
+			return nil
+		}
+
+		var archived *ArchivedObservation
+		var line int
+
+		if parseError, ok := err.(*csv.ParseError); ok {
+			line = parseError.StartLine
+		} else if err != nil {
+			return err
+		} else {
+			line, _ = csvReader.FieldPos(0)
+		}
+
+		if err == nil && len(record) != len(header) {
+			err = fmt.Errorf("Has %v values instead of %v", len(record), len(header))
+		}
+
+		if err == nil {
+			archived = &ArchivedObservation{}
+
+			for inx, value := range record {
+				if strings.TrimSpace(value) == "" && isRequiredColumn(archiveColumns[columns[inx]]) {
+					err = fmt.Errorf("Missing %v", archiveColumns[columns[inx]])
+				} else {
+					err = archived.setColumn(columns[inx], value)
+				}
+
+				if err != nil {
+					archived = nil
+					break
+				}
+			}
+		}
+
+		if err := fn(line, archived, err); err != nil {
+			return err
+		}
+	}
+}
+
+// ExportObservations calls fn with the observations from from up to and including to of a location,
+// or of every location when location is empty, ordered by location and time.  The observations are
+// read a batch at a time so a slow client doesn't hold a transaction open.
+func (store *Store) ExportObservations(location string, from, to time.Time, fn func(*ArchivedObservation) error) error {
+	nextLocation, nextKey := append([]byte{}, location...), timeKey(max(from.Unix(), 0))
+	end := timeKey(to.Unix())
+
+	for nextLocation != nil {
+		batch := []*ArchivedObservation{}
+
+		err := store.db.View(func(tx *bolt.Tx) error {
+			locations := tx.Bucket(observationsBucket).Cursor()
+			locationKey, _ := locations.Seek(nextLocation)
+			nextLocation = nil
+
+			for ; locationKey != nil; locationKey, _ = locations.Next() {
+				if location != "" && string(locationKey) != location {
+					return nil
+				}
+
+				cursor := locations.Bucket().Bucket(locationKey).Cursor()
+
+				for key, value := cursor.Seek(nextKey); key != nil && bytes.Compare(key, end) <= 0; key, value = cursor.Next() {
+					if len(batch) == exportBatchSize {
+						nextLocation, nextKey = append([]byte{}, locationKey...), append([]byte{}, key...)
+						return nil
+					}
+
+					var observation data.CurrentWeatherData
+
+					if err := json.Unmarshal(value, &observation); err != nil {
+						return fmt.Errorf("Error reading the observation of %v at %v: %v", string(locationKey),
+							binary.BigEndian.Uint64(key), err)
+					}
+
+					batch = append(batch, NewArchivedObservation(string(locationKey), &observation))
+				}
+
+				nextKey = timeKey(max(from.Unix(), 0))
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		for _, archived := range batch {
+			if err := fn(archived); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// errDryRun rolls back an import's transaction
+var errDryRun = errors.New("dry run")
+
+// ImportResult is what Import did with the observations
+type ImportResult struct {
+	Imported        int
+	AlreadyRecorded int
+	Compacted       int // older than the observations compaction has removed, see Import
+}
+
+// Import records the observations (which must have been validated) in one transaction.
+// Observations of a location no newer than the newest one compaction removed are skipped since
+// the aggregates may already include them and the removed observation can't be compared with.
+// With dryRun nothing is changed but the result is the same.
+func (store *Store) Import(observations []*ArchivedObservation, dryRun bool) (*ImportResult, error) {
+	result := &ImportResult{}
+
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		compacted := tx.Bucket(compactedBucket)
+
+		for _, archived := range observations {
+			if bytes.Compare(timeKey(archived.Dt), compacted.Get([]byte(archived.Location))) <= 0 {
+				result.Compacted++
+				continue
+			}
+
+			isNew, err := record(tx, archived.Location, archived.CurrentWeatherData())
+
+			if err != nil {
+				return err
+			}
+
+			if isNew {
+				result.Imported++
+			} else {
+				result.AlreadyRecorded++
+			}
+		}
+
+		if dryRun {
+			return errDryRun
+		}
+
+		return nil
+	})
+
+	if err == errDryRun {
+		err = nil
+	}
+
+	return result, err
+}
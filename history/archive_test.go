@@ -0,0 +1,138 @@
+package history
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Observations no newer than the newest one compaction removed aren't imported again since the
+// aggregates already include them
+func TestImportAfterCompaction(t *testing.T) {
+	start := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	at := func(hour int) time.Time { return start.Add(time.Duration(hour) * time.Hour) }
+
+	tests := []struct {
+		name   string
+		hours  []int // of the imported observations
+		result ImportResult
+	}{
+		{"removed", []int{0, 1}, ImportResult{Compacted: 2}},
+		{"older than removed", []int{-3}, ImportResult{Compacted: 1}},
+		{"between removed", []int{0}, ImportResult{Compacted: 1}},
+		{"kept", []int{2, 3}, ImportResult{AlreadyRecorded: 2}},
+		{"newer", []int{4, 5}, ImportResult{Imported: 2}},
+		{"mixed", []int{-1, 1, 3, 6}, ImportResult{Imported: 1, AlreadyRecorded: 1, Compacted: 2}},
+		{"other location", []int{0}, ImportResult{Imported: 1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := openTestStore(t)
+
+			for hour := 0; hour < 4; hour++ {
+				if _, err := store.Record("0.00,0.00", testObservation(at(hour), 0, 10, 0)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if _, err := store.Compact(Retention{Raw: 90 * time.Minute}, at(3)); err != nil {
+				t.Fatal(err)
+			}
+
+			before := readAggregates(t, store)
+			location := "0.00,0.00"
+			if test.name == "other location" {
+				location = "1.00,1.00"
+			}
+
+			observations := []*ArchivedObservation{}
+			for _, hour := range test.hours {
+				observations = append(observations, NewArchivedObservation(location, testObservation(at(hour), 0, 20, 0)))
+			}
+
+			for _, dryRun := range []bool{true, false} {
+				result, err := store.Import(observations, dryRun)
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if *result != test.result {
+					t.Errorf("Import with dryRun %v = %+v, want %+v", dryRun, *result, test.result)
+				}
+			}
+
+			// Only the imported observations change the aggregates
+			if after := readAggregates(t, store); test.result.Imported == 0 && !reflect.DeepEqual(after, before) {
+				t.Errorf("Aggregates changed from %v to %v", before, after)
+			}
+		})
+	}
+}
+
+// Exports longer than a batch resume where the previous batch stopped, including across locations
+func TestExportBatches(t *testing.T) {
+	store := openTestStore(t)
+	start := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	counts := map[string]int{"1.00,1.00": exportBatchSize, "2.00,2.00": exportBatchSize + 1, "3.00,3.00": 3}
+	observations := []*ArchivedObservation{}
+
+	for location, count := range counts {
+		for i := 0; i < count; i++ {
+			observation := testObservation(start.Add(time.Duration(i)*time.Minute), 0, 10, 0)
+			observations = append(observations, NewArchivedObservation(location, observation))
+		}
+	}
+
+	if _, err := store.Import(observations, false); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		location string
+		from, to time.Time
+		want     []string // the locations and minutes after start exported, in order
+	}{
+		{"", start, start.Add(24 * time.Hour), nil},
+		{"2.00,2.00", start, start.Add(24 * time.Hour), nil},
+		{"2.00,2.00", start.Add(2 * time.Minute), start.Add(exportBatchSize * time.Minute), nil},
+		{"1.00,1.00", start.Add(exportBatchSize * time.Minute), start.Add(24 * time.Hour), []string{}},
+		{"4.00,4.00", start, start.Add(24 * time.Hour), []string{}},
+	}
+
+	for _, test := range tests {
+		want := test.want
+
+		if want == nil {
+			want = []string{}
+
+			for _, location := range []string{"1.00,1.00", "2.00,2.00", "3.00,3.00"} {
+				for i := 0; i < counts[location]; i++ {
+					dt := start.Add(time.Duration(i) * time.Minute)
+
+					if (test.location == "" || test.location == location) && !dt.Before(test.from) && !dt.After(test.to) {
+						want = append(want, fmt.Sprintf("%v %v", location, i))
+					}
+				}
+			}
+		}
+
+		got := []string{}
+
+		err := store.ExportObservations(test.location, test.from, test.to, func(archived *ArchivedObservation) error {
+			got = append(got, fmt.Sprintf("%v %v", archived.Location, (archived.Dt-start.Unix())/60))
+			return nil
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Export of %q from %v to %v returned %v observations, want %v", test.location, test.from, test.to,
+				len(got), len(want))
+		}
+	}
+}
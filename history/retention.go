@@ -0,0 +1,224 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Raw is the retention tier of the observations themselves.  The other tiers are the intervals.
+const Raw = "raw"
+
+// RetentionTiers are the tiers from the most to the least detailed
+var RetentionTiers = []string{Raw, string(Hour), string(Day), string(Week)}
+
+// Retention is how long each tier is kept.  Tiers that aren't in the map are kept forever.
+type Retention map[string]time.Duration
+
+// ParseRetention parses a comma separated list of tier=duration (e.g. "raw=7d,hour=90d").
+// Durations are a number of days followed by d or a Go duration (e.g. 36h).  A more detailed
+// tier can't be kept longer than a less detailed one since it's what they're built from.
+func ParseRetention(str string) (Retention, error) {
+	retention := Retention{}
+
+	for _, part := range strings.Split(str, ",") {
+		part = strings.TrimSpace(part)
+
+		if part == "" {
+			continue
+		}
+
+		tier, durationStr, found := strings.Cut(part, "=")
+		tier = strings.TrimSpace(tier)
+
+		if !found || !validTier(tier) {
+			return nil, fmt.Errorf("Invalid retention: %v (must be tier=duration where the tier is %v)", part,
+				strings.Join(RetentionTiers, ", "))
+		}
+
+		if _, duplicate := retention[tier]; duplicate {
+			return nil, fmt.Errorf("Duplicate retention tier: %v", tier)
+		}
+
+		duration, err := parseRetentionDuration(strings.TrimSpace(durationStr))
+
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("Invalid retention duration for %v: %v (must be a positive number of days "+
+				"followed by d or a duration like 36h)", tier, durationStr)
+		}
+
+		retention[tier] = duration
+	}
+
+	for inx, tier := range RetentionTiers {
+		for _, longerTier := range RetentionTiers[inx+1:] {
+			duration, found := retention[tier]
+			longerDuration, longerFound := retention[longerTier]
+
+			if longerFound && (!found || duration > longerDuration) {
+				return nil, fmt.Errorf("The %v retention must not be longer than the %v retention", tier, longerTier)
+			}
+		}
+	}
+
+	return retention, nil
+}
+
+func validTier(tier string) bool {
+	for _, validTier := range RetentionTiers {
+		if tier == validTier {
+			return true
+		}
+	}
+	return false
+}
+
+func parseRetentionDuration(str string) (time.Duration, error) {
+	if days, found := strings.CutSuffix(str, "d"); found {
+		count, err := strconv.Atoi(days)
+		return time.Duration(count) * 24 * time.Hour, err
+	}
+
+	return time.ParseDuration(str)
+}
+
+func (retention Retention) String() string {
+	parts := []string{}
+
+	for _, tier := range RetentionTiers {
+		if duration, found := retention[tier]; !found {
+			parts = append(parts, tier+"=forever")
+		} else if duration%(24*time.Hour) == 0 {
+			parts = append(parts, fmt.Sprintf("%v=%vd", tier, int(duration/(24*time.Hour))))
+		} else {
+			parts = append(parts, fmt.Sprintf("%v=%v", tier, duration))
+		}
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// CompactionResult is what a compaction removed.  Removed is the number of records removed from
+// each tier.  The bytes of the removed keys and values are reclaimed: bbolt reuses the freed pages
+// for new records rather than shrinking the file.
+type CompactionResult struct {
+	Removed        map[string]int
+	BytesReclaimed int
+	Duration       time.Duration
+}
+
+// Compact removes the observations and aggregates that are older than their tier's retention
+// and the locations that are left with nothing in a tier.
+func (store *Store) Compact(retention Retention, now time.Time) (*CompactionResult, error) {
+	started := time.Now()
+	result := &CompactionResult{Removed: map[string]int{}}
+
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		for _, tier := range RetentionTiers {
+			duration, found := retention[tier]
+
+			if !found {
+				continue
+			}
+
+			cutoff := now.Add(-duration)
+			var parent, compacted *bolt.Bucket
+
+			if tier == Raw {
+				parent = tx.Bucket(observationsBucket)
+				compacted = tx.Bucket(compactedBucket)
+			} else {
+				parent = tx.Bucket(aggregatesBucket).Bucket([]byte(tier))
+			}
+
+			if parent == nil {
+				continue
+			}
+
+			if err := compactTier(parent, compacted, tier, cutoff, result); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	result.Duration = time.Since(started)
+	return result, err
+}
+
+// compactTier removes the records of each location in the tier older than cutoff.  When
+// compacted isn't nil the time of the newest record removed from each location is kept in it.
+func compactTier(parent, compacted *bolt.Bucket, tier string, cutoff time.Time, result *CompactionResult) error {
+	emptyLocations := [][]byte{}
+
+	err := parent.ForEachBucket(func(location []byte) error {
+		bucket := parent.Bucket(location)
+		expired := [][]byte{}
+		cursor := bucket.Cursor()
+
+		// Aggregates are keyed by local time so the keys within the largest UTC offset of
+		// the cutoff are checked against the aggregate's start
+		end := timeKey(cutoff.Unix())
+		if tier != Raw {
+			end = timeKey(cutoff.Unix() + maxTimezoneOffset)
+		}
+
+		for key, value := cursor.First(); key != nil && string(key) < string(end); key, value = cursor.Next() {
+			if tier != Raw {
+				aggregate := &Aggregate{}
+
+				if err := json.Unmarshal(value, aggregate); err != nil {
+					return fmt.Errorf("Error reading the %v aggregate of %v: %v", tier, string(location), err)
+				}
+
+				if !aggregate.StartTime().Before(cutoff) {
+					continue
+				}
+			}
+
+			// Keys are deleted after the scan since deleting moves the cursor
+			expired = append(expired, append([]byte{}, key...))
+			result.BytesReclaimed += len(key) + len(value)
+		}
+
+		for _, key := range expired {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		result.Removed[tier] += len(expired)
+
+		if compacted != nil && len(expired) > 0 {
+			if newest := expired[len(expired)-1]; bytes.Compare(newest, compacted.Get(location)) > 0 {
+				if err := compacted.Put(location, newest); err != nil {
+					return err
+				}
+			}
+		}
+
+		if key, _ := bucket.Cursor().First(); key == nil {
+			emptyLocations = append(emptyLocations, append([]byte{}, location...))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for _, location := range emptyLocations {
+		if err := parent.DeleteBucket(location); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
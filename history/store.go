@@ -0,0 +1,186 @@
+package history
+
+import (
+	"current-weather-server/data"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// The bucket holding a bucket of observations for each location
+var observationsBucket = []byte("observations")
+
+// The bucket holding the time of the newest observation of each location removed by compaction.
+// The aggregates include the removed observations so older observations can't be recorded again.
+var compactedBucket = []byte("compacted")
+
+// Store keeps the observations fetched from Open Weather in a bbolt file.  Each location
+// has a bucket keyed by the observation time (Open Weather's Dt), so an observation
+// fetched more than once (e.g. by several clients before Open Weather updates) is stored once.
+// The hourly, daily, and weekly aggregates of each location are updated as observations are stored.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens (or creates) the store.  Only one process can have the file open.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+
+	if err != nil {
+		return nil, fmt.Errorf("Error opening history file %v: %v", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(observationsBucket); err != nil {
+			return err
+		}
+
+		if _, err := tx.CreateBucketIfNotExists(compactedBucket); err != nil {
+			return err
+		}
+
+		if tx.Bucket(aggregatesBucket) != nil {
+			return nil
+		}
+
+		if _, err := tx.CreateBucket(aggregatesBucket); err != nil {
+			return err
+		}
+
+		return buildAggregates(tx)
+	})
+
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("Error initializing history file %v: %v", path, err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+func (store *Store) Close() error {
+	return store.db.Close()
+}
+
+// LocationKey is the key observations are stored under.  Coordinates are rounded to
+// two decimals (about 1 km) so nearby requests share their history.
+func LocationKey(latitude, longitude float64) string {
+	return roundCoordinate(latitude) + "," + roundCoordinate(longitude)
+}
+
+func roundCoordinate(value float64) string {
+	value = math.Round(value*100) / 100
+
+	// Avoids -0.00
+	if value == 0 {
+		value = 0
+	}
+
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+// timeKey is the key of an observation in its location's bucket.  Big endian keys sort by time.
+func timeKey(dt int64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(dt))
+	return key
+}
+
+// Record stores an observation unless the location already has one with the same Dt.
+// It returns true when the observation was stored.  Observations must be in metric units.
+func (store *Store) Record(location string, observation *data.CurrentWeatherData) (bool, error) {
+	stored := false
+
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		stored, err = record(tx, location, observation)
+		return err
+	})
+
+	return stored, err
+}
+
+// record stores an observation and adds it to the aggregates in a write transaction
+func record(tx *bolt.Tx, location string, observation *data.CurrentWeatherData) (bool, error) {
+	if observation.Units != data.MetricUnits {
+		return false, fmt.Errorf("Observations must be stored in metric units, not %v", observation.Units)
+	}
+
+	if observation.Dt <= 0 {
+		return false, fmt.Errorf("Invalid observation time: %v", observation.Dt)
+	}
+
+	bucket, err := tx.Bucket(observationsBucket).CreateBucketIfNotExists([]byte(location))
+
+	if err != nil {
+		return false, err
+	}
+
+	key := timeKey(int64(observation.Dt))
+
+	if bucket.Get(key) != nil {
+		return false, nil
+	}
+
+	value, err := json.Marshal(observation)
+
+	if err != nil {
+		return false, err
+	}
+
+	if err := bucket.Put(key, value); err != nil {
+		return false, err
+	}
+
+	return true, addToAggregates(tx, location, observation)
+}
+
+// errLimitReached stops a scan once enough observations have been read
+var errLimitReached = errors.New("limit reached")
+
+// Observations returns the observations of a location from from up to and including to,
+// oldest first.  At most limit observations are returned and truncated is true when
+// there were more.
+func (store *Store) Observations(location string, from, to time.Time, limit int) (observations []*data.CurrentWeatherData, truncated bool, err error) {
+	observations = []*data.CurrentWeatherData{}
+
+	err = store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(observationsBucket).Bucket([]byte(location))
+
+		if bucket == nil {
+			return nil
+		}
+
+		cursor := bucket.Cursor()
+		end := timeKey(to.Unix())
+
+		for key, value := cursor.Seek(timeKey(max(from.Unix(), 0))); key != nil && string(key) <= string(end); key, value = cursor.Next() {
+			if len(observations) == limit {
+				return errLimitReached
+			}
+
+			var observation data.CurrentWeatherData
+
+			if err := json.Unmarshal(value, &observation); err != nil {
+				return fmt.Errorf("Error reading the observation of %v at %v: %v", location,
+					binary.BigEndian.Uint64(key), err)
+			}
+
+			observations = append(observations, &observation)
+		}
+
+		return nil
+	})
+
+	if err == errLimitReached {
+		return observations, true, nil
+	}
+
+	return observations, false, err
+}
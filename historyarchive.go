@@ -0,0 +1,363 @@
+package main
+
+import (
+	"current-weather-server/history"
+	"current-weather-server/logging"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How many observations the import command records in one transaction
+const importBatchSize = 1000
+
+// How many invalid observations the import command lists
+const maxImportErrors = 20
+
+// historyExport is what /api/history/export streams
+type historyExport struct {
+	format   string
+	location string // empty for every location
+	from, to time.Time
+}
+
+// parseHistoryExport reads the export parameters.  The latitude and longitude are optional and
+// unlike the other history endpoints the default range is everything.
+func parseHistoryExport(request *http.Request) (*historyExport, error, int) {
+	if historyStore == nil {
+		return nil, errors.New("History isn't enabled on this server"), http.StatusNotFound
+	}
+
+	queryValues := request.URL.Query()
+	export := &historyExport{format: exportFormatParam.value(queryValues)}
+
+	if export.format == "" {
+		export.format = history.NDJSON
+	}
+
+	if export.format != history.NDJSON && export.format != history.CSV {
+		return nil, fmt.Errorf("Invalid format value: %v (must be %v or %v)", export.format, history.NDJSON,
+			history.CSV), http.StatusBadRequest
+	}
+
+	latitudeStr := exportLatitudeParam.value(queryValues)
+	longitudeStr := exportLongitudeParam.value(queryValues)
+
+	if (latitudeStr == "") != (longitudeStr == "") {
+		return nil, errors.New("latitude and longitude must be given together"), http.StatusBadRequest
+	}
+
+	if latitudeStr != "" {
+		latitude, err := strconv.ParseFloat(latitudeStr, 64)
+
+		if err != nil || !validLatitude(latitude) {
+			return nil, fmt.Errorf("Invalid latitude value: %v", latitudeStr), http.StatusBadRequest
+		}
+
+		longitude, err := strconv.ParseFloat(longitudeStr, 64)
+
+		if err != nil || !validLongitude(longitude) {
+			return nil, fmt.Errorf("Invalid longitude value: %v", longitudeStr), http.StatusBadRequest
+		}
+
+		export.location = history.LocationKey(latitude, longitude)
+	}
+
+	var err error
+	export.to, err = parseHistoryTime(historyToParam.Name, historyToParam.value(queryValues), time.Now().UTC())
+
+	if err != nil {
+		return nil, err, http.StatusBadRequest
+	}
+
+	export.from, err = parseHistoryTime(exportFromParam.Name, exportFromParam.value(queryValues), time.Unix(0, 0).UTC())
+
+	if err != nil {
+		return nil, err, http.StatusBadRequest
+	}
+
+	if export.from.After(export.to) {
+		return nil, fmt.Errorf("%v (%v) must not be after %v (%v)", exportFromParam.Name,
+			export.from.Format(time.RFC3339), historyToParam.Name, export.to.Format(time.RFC3339)), http.StatusBadRequest
+	}
+
+	return export, nil, http.StatusOK
+}
+
+// apiExportHistory streams the recorded observations as NDJSON or CSV.  The values are metric
+// and in the form the import command reads, so an export can be imported into another server.
+func apiExportHistory(requestNum uint64, writer http.ResponseWriter, request *http.Request) {
+	export, err, statusCode := parseHistoryExport(request)
+
+	if err != nil {
+		logging.LogHTTPError(requestNum, err.Error(), statusCode)
+		http.Error(writer, err.Error(), statusCode)
+		return
+	}
+
+	writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="observations.%v"`, export.format))
+
+	var write func(*history.ArchivedObservation) error
+	flush := func() error { return nil }
+
+	if export.format == history.CSV {
+		writer.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		csvWriter := csv.NewWriter(writer)
+		write = func(archived *history.ArchivedObservation) error { return csvWriter.Write(archived.CSVRecord()) }
+		flush = func() error {
+			csvWriter.Flush()
+			return csvWriter.Error()
+		}
+
+		// The header is written even when there are no observations
+		if err := csvWriter.Write(history.ArchiveColumns()); err != nil {
+			logging.LogError(requestNum, fmt.Sprintf("Error exporting history: %v", err))
+			return
+		}
+	} else {
+		writer.Header().Set("Content-Type", "application/x-ndjson")
+		encoder := json.NewEncoder(writer)
+		write = func(archived *history.ArchivedObservation) error { return encoder.Encode(archived) }
+	}
+
+	count := 0
+
+	err = historyStore.ExportObservations(export.location, export.from, export.to, func(archived *history.ArchivedObservation) error {
+		count++
+		return write(archived)
+	})
+
+	if err == nil {
+		err = flush()
+	}
+
+	if err != nil && count == 0 {
+		logging.LogHTTPError(requestNum, err.Error(), http.StatusInternalServerError)
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Once observations have been written the status has been sent so the export is cut short
+	if err != nil {
+		logging.LogError(requestNum, fmt.Sprintf("Error exporting history after %v observations: %v", count, err))
+		return
+	}
+
+	logging.LogInfo(requestNum, fmt.Sprintf("Exported %v observations as %v", count, export.format))
+}
+
+// importReport counts what the import command did with the observations it read
+type importReport struct {
+	files           int
+	read            int
+	invalid         int
+	errors          []string // the first maxImportErrors invalid observations as file:line: error
+	duplicates      int      // observations of the same location and time as an earlier one in the files
+	alreadyRecorded int
+	compacted       int // older than the observations compaction removed
+	imported        int
+	seen            map[string]bool
+	batch           []*history.ArchivedObservation
+}
+
+// runImport is the import command.  It records the observations in NDJSON or CSV files (e.g. from
+// /api/history/export) in a history file and returns the exit status.  The server can't be running
+// with the same history file since only one process can open it.  Every file is checked before
+// anything is recorded so nothing is imported when any observation is invalid.
+func runImport(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	historyFile := flags.String("historyFile", "", "The history file to import into")
+	format := flags.String("format", "", "The format of the files, ndjson or csv (empty=from each file's extension)")
+	dryRun := flags.Bool("dryRun", false, "Report what would be imported without changing the history file")
+
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %v import -historyFile file [-format ndjson|csv] [-dryRun] file...\n", filepath.Base(os.Args[0]))
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *historyFile == "" || flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	report := &importReport{seen: map[string]bool{}}
+
+	for _, path := range flags.Args() {
+		if err := report.checkFile(path, *format); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %v: %v\n", path, err)
+			return 1
+		}
+	}
+
+	if report.invalid > 0 {
+		report.print(os.Stdout, *dryRun)
+		return 1
+	}
+
+	store, err := history.Open(*historyFile)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	defer store.Close()
+
+	for _, path := range flags.Args() {
+		if err := report.importFile(store, path, *format, *dryRun); err != nil {
+			fmt.Fprintf(os.Stderr, "Error importing %v: %v\n", path, err)
+			return 1
+		}
+	}
+
+	if err := report.flush(store, *dryRun); err != nil {
+		fmt.Fprintf(os.Stderr, "Error importing: %v\n", err)
+		return 1
+	}
+
+	report.print(os.Stdout, *dryRun)
+	return 0
+}
+
+// archiveFormat returns the format of a file from its extension
+func archiveFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return history.CSV, nil
+	case ".ndjson", ".jsonl":
+		return history.NDJSON, nil
+	}
+
+	return "", fmt.Errorf("Unknown format of %v (use -format)", path)
+}
+
+// readArchiveFile calls fn with each validated observation in a file or the error that made it invalid
+func readArchiveFile(path, format string, fn func(line int, archived *history.ArchivedObservation, err error) error) error {
+	var err error
+
+	if format == "" {
+		if format, err = archiveFormat(path); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.Open(path)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	return history.ReadArchive(file, format, func(line int, archived *history.ArchivedObservation, err error) error {
+		if err == nil {
+			err = archived.Validate()
+		}
+
+		return fn(line, archived, err)
+	})
+}
+
+// checkFile counts the observations of a file and lists the invalid ones
+func (report *importReport) checkFile(path, format string) error {
+	report.files++
+
+	return readArchiveFile(path, format, func(line int, archived *history.ArchivedObservation, err error) error {
+		report.read++
+
+		if err != nil {
+			report.invalid++
+
+			if len(report.errors) < maxImportErrors {
+				report.errors = append(report.errors, fmt.Sprintf("%v:%v: %v", path, line, err))
+			}
+		}
+
+		return nil
+	})
+}
+
+// importFile records the observations of a file that checkFile found valid
+func (report *importReport) importFile(store *history.Store, path, format string, dryRun bool) error {
+	return readArchiveFile(path, format, func(line int, archived *history.ArchivedObservation, err error) error {
+		// Only when the file changed since it was checked
+		if err != nil {
+			return fmt.Errorf("line %v: %v", line, err)
+		}
+
+		key := fmt.Sprintf("%v@%v", archived.Location, archived.Dt)
+
+		if report.seen[key] {
+			report.duplicates++
+			return nil
+		}
+
+		report.seen[key] = true
+		report.batch = append(report.batch, archived)
+
+		if len(report.batch) == importBatchSize {
+			return report.flush(store, dryRun)
+		}
+
+		return nil
+	})
+}
+
+// flush records the batch of valid observations
+func (report *importReport) flush(store *history.Store, dryRun bool) error {
+	if len(report.batch) == 0 {
+		return nil
+	}
+
+	result, err := store.Import(report.batch, dryRun)
+
+	if err != nil {
+		return err
+	}
+
+	report.imported += result.Imported
+	report.alreadyRecorded += result.AlreadyRecorded
+	report.compacted += result.Compacted
+	report.batch = report.batch[:0]
+	return nil
+}
+
+func (report *importReport) print(output *os.File, dryRun bool) {
+	fmt.Fprintf(output, "Read %v observations from %v files\n", report.read, report.files)
+	fmt.Fprintf(output, "Invalid: %v\n", report.invalid)
+
+	for _, err := range report.errors {
+		fmt.Fprintf(output, "  %v\n", err)
+	}
+
+	if report.invalid > len(report.errors) {
+		fmt.Fprintf(output, "  ... and %v more\n", report.invalid-len(report.errors))
+	}
+
+	if report.invalid > 0 {
+		fmt.Fprintln(output, "Nothing was imported, fix or remove the invalid observations and import the files again")
+		return
+	}
+
+	fmt.Fprintf(output, "Duplicates in the files: %v\n", report.duplicates)
+	fmt.Fprintf(output, "Already recorded: %v\n", report.alreadyRecorded)
+	fmt.Fprintf(output, "Older than the compacted history: %v\n", report.compacted)
+
+	if dryRun {
+		fmt.Fprintf(output, "Would import: %v (dry run, nothing was changed)\n", report.imported)
+	} else {
+		fmt.Fprintf(output, "Imported: %v\n", report.imported)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"current-weather-server/data"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// requestLanguage picks the language of a response: the lang parameter when it's
+// given, otherwise the preferred supported language in the Accept-Language header.
+// An unsupported lang parameter is an error but an unsupported Accept-Language
+// header just falls back to the default language.
+func requestLanguage(request *http.Request) (string, error, int) {
+	if lang := langParam.value(request.URL.Query()); lang != "" {
+		return validateLanguage(lang)
+	}
+
+	return acceptLanguage(request.Header.Get("Accept-Language")), nil, http.StatusOK
+}
+
+// validateLanguage checks a language given by the client and returns the default if it's empty
+func validateLanguage(lang string) (string, error, int) {
+	if lang == "" {
+		return data.DefaultLanguage, nil, http.StatusOK
+	}
+
+	lang, err := data.ParseLanguage(lang)
+
+	if err != nil {
+		return "", err, http.StatusBadRequest
+	}
+
+	return lang, nil, http.StatusOK
+}
+
+// acceptLanguage returns the supported language with the highest quality
+// in an Accept-Language header (e.g. "fr-CH, fr;q=0.9, en;q=0.8")
+func acceptLanguage(header string) string {
+	type languageRange struct {
+		tag     string
+		quality float64
+	}
+
+	ranges := []languageRange{}
+
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+
+		if qualityStr, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			var err error
+			if quality, err = strconv.ParseFloat(qualityStr, 64); err != nil {
+				continue
+			}
+		}
+
+		if tag != "" && quality > 0 {
+			ranges = append(ranges, languageRange{tag, quality})
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].quality > ranges[j].quality })
+
+	for _, languageRange := range ranges {
+		if lang, err := data.ParseLanguage(languageRange.tag); err == nil {
+			return lang
+		}
+	}
+
+	return data.DefaultLanguage
+}
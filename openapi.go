@@ -0,0 +1,521 @@
+package main
+
+import (
+	"current-weather-server/data"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// apiParam describes a query parameter.  Handlers read their parameters with the
+// value method of these definitions and the OpenAPI spec is generated from the same
+// definitions.  The tests check that each handler reads the parameters of its route.
+type apiParam struct {
+	Name        string
+	Type        string // string, number, integer, or boolean
+	Description string
+	Required    bool
+	Enum        []string
+	Minimum     *float64
+	Maximum     *float64
+}
+
+// apiOperation describes one method of a route
+type apiOperation struct {
+	Method       string
+	Summary      string
+	Params       []apiParam
+	RequestBody  reflect.Type // the json request body, if there is one
+	RequestTypes []string     // the media types of a request body that isn't json
+	Response     reflect.Type // the json response, nil when ContentType isn't json
+	ContentType  string
+}
+
+// apiRoute is one entry of the route table used both to set up the
+// server's ServeMux and to generate the OpenAPI spec
+type apiRoute struct {
+	Path       string
+	Handler    func(requestNum uint64, writer http.ResponseWriter, request *http.Request)
+	Operations []apiOperation
+}
+
+// paramReadHook is called with the name of each parameter a handler reads.  Only
+// the tests set it.
+var paramReadHook func(name string)
+
+// value returns the parameter's value in the query
+func (param apiParam) value(query url.Values) string {
+	if paramReadHook != nil {
+		paramReadHook(param.Name)
+	}
+	return query.Get(param.Name)
+}
+
+func float64Ptr(value float64) *float64 {
+	return &value
+}
+
+var (
+	latitudeParam = apiParam{Name: "latitude", Type: "number", Required: true,
+		Description: "A floating point value between -90 and 90 (inclusive)",
+		Minimum:     float64Ptr(-90), Maximum: float64Ptr(90)}
+	longitudeParam = apiParam{Name: "longitude", Type: "number", Required: true,
+		Description: "A floating point value between -180 and 180 (inclusive)",
+		Minimum:     float64Ptr(-180), Maximum: float64Ptr(180)}
+	unitsParam = apiParam{Name: "units", Type: "string",
+		Description: "imperial (Fahrenheit), metric (Celsius), or standard (Kelvin).  The default is metric.",
+		Enum:        []string{"imperial", "metric", "standard"}}
+	tempUnitParam = apiParam{Name: "tempUnit", Type: "string",
+		Description: "C, F, or K.  Overrides the temperature unit of units.",
+		Enum:        []string{"C", "F", "K"}}
+	windUnitParam = apiParam{Name: "windUnit", Type: "string",
+		Description: "m/s, km/h, mph, kn (knots), or Bft (Beaufort).  Overrides the wind speed unit of units.",
+		Enum:        []string{"m/s", "km/h", "mph", "kn", "Bft"}}
+	pressureUnitParam = apiParam{Name: "pressureUnit", Type: "string",
+		Description: "hPa, inHg, or mmHg.  The default is hPa.",
+		Enum:        []string{"hPa", "inHg", "mmHg"}}
+	distanceUnitParam = apiParam{Name: "distanceUnit", Type: "string",
+		Description: "m, km, or mi.  The unit of visibility.  The default is m.",
+		Enum:        []string{"m", "km", "mi"}}
+	precipUnitParam = apiParam{Name: "precipUnit", Type: "string",
+		Description: "mm or in.  The unit of rainfall.  The default is mm.",
+		Enum:        []string{"mm", "in"}}
+	langParam = apiParam{Name: "lang", Type: "string",
+		Description: "The language of the summary and weather description: " +
+			strings.Join(data.SupportedLanguages(), ", ") + ".  The default is picked from the Accept-Language header.",
+		Enum: data.SupportedLanguages()}
+	summaryStyleParam = apiParam{Name: "summaryStyle", Type: "string",
+		Description: "The summary template to use.  The default is the built in summary.  " +
+			"The styles are loaded from -summaryTemplateDir when the server starts."}
+	comfortProfileParam = apiParam{Name: "comfortProfile", Type: "string",
+		Description: "The comfort profile (subjective temperature scale) used for subjectiveTemp.  " +
+			"The profiles are set with -comfortProfiles when the server starts.  The default is the -subjectiveTempScale " +
+			"or -coldCoolWarmF temperatures."}
+	trendSummaryParam = apiParam{Name: "trendSummary", Type: "boolean",
+		Description: "Add the notable trends (a rising or falling pressure or an unusual temperature for the hour) to the default summary"}
+	coldCoolWarmParam = apiParam{Name: "coldCoolWarm", Type: "string",
+		Description: "The subjective temperature scale used for subjectiveTemp instead of a comfort profile.  Either comma " +
+			"separated cold, cool, warm temperatures optionally followed by C, F, or K (e.g. 40,60,77F) or " +
+			"[feelsLike,]label:max,...,label bands (e.g. freezing:0C,cold:5C,mild:18C,warm:25C,hot).  " +
+			"Temperatures without a unit are in the response's temperature unit."}
+	fieldsParam = apiParam{Name: "fields", Type: "string",
+		Description: "Comma separated list of the fields to return.  The default is all fields.  Valid fields are " +
+			strings.Join(data.WeatherFieldNames(), ", ")}
+	compactParam = apiParam{Name: "compact", Type: "boolean",
+		Description: "Use short field names in the response.  The default is false."}
+	alertIdParam = apiParam{Name: "id", Type: "string", Required: true,
+		Description: "The id of the alert rule"}
+	alertRuleIdParam = apiParam{Name: "ruleId", Type: "string",
+		Description: "Only return the deliveries of this alert rule"}
+	graphqlQueryParam = apiParam{Name: "query", Type: "string", Required: true,
+		Description: "The GraphQL query"}
+	graphqlVariablesParam = apiParam{Name: "variables", Type: "string",
+		Description: "The query variables as a json object"}
+	graphqlOperationNameParam = apiParam{Name: "operationName", Type: "string",
+		Description: "The operation to run when the query has more than one"}
+	historyFromParam = apiParam{Name: "from", Type: "string",
+		Description: "The start of the history as an RFC 3339 time or seconds since 1970.  The default is a day before to."}
+	historyToParam = apiParam{Name: "to", Type: "string",
+		Description: "The end of the history as an RFC 3339 time or seconds since 1970.  The default is now."}
+	intervalParam = apiParam{Name: "interval", Type: "string", Enum: []string{"hour", "day", "week"},
+		Description: "The local time interval the observations are aggregated over.  The default is day."}
+	exportFormatParam = apiParam{Name: "format", Type: "string", Enum: []string{"ndjson", "csv"},
+		Description: "The format of the export.  The default is ndjson."}
+	exportFromParam = apiParam{Name: "from", Type: "string",
+		Description: "The start of the export as an RFC 3339 time or seconds since 1970.  The default is the oldest observation."}
+	exportLatitudeParam = apiParam{Name: "latitude", Type: "number",
+		Description: "The latitude of the location to export.  Omit the latitude and longitude to export every location."}
+	exportLongitudeParam = apiParam{Name: "longitude", Type: "number",
+		Description: "The longitude of the location to export.  Omit the latitude and longitude to export every location."}
+	geoFormatParam = apiParam{Name: "format", Type: "string", Enum: []string{"json", "geojson"},
+		Description: "json or geojson (a GeoJSON Feature or FeatureCollection).  The default is geojson when the Accept " +
+			"header has application/geo+json and json otherwise."}
+	bboxParam = apiParam{Name: "bbox", Type: "string", Required: true,
+		Description: "The area of the grid as minLon,minLat,maxLon,maxLat (e.g. 2,48,3,49)"}
+	stepParam = apiParam{Name: "step", Type: "number", Required: true, Minimum: float64Ptr(0),
+		Description: "The degrees of latitude and longitude between the points of the grid"}
+	gridFieldsParam = apiParam{Name: "fields", Type: "string",
+		Description: "Comma separated list of the fields returned for each point (e.g. temp,windSpeed).  The default is temp."}
+	spacingParam = apiParam{Name: "spacing", Type: "number", Minimum: float64Ptr(0),
+		Description: "The kilometers between the weather along the route (the length of its segments).  The default is 10."}
+	polylinePrecisionParam = apiParam{Name: "polylinePrecision", Type: "integer", Minimum: float64Ptr(1), Maximum: float64Ptr(7),
+		Description: "The decimals an encoded polyline route was encoded with (6 for OSRM's polyline6).  The default is 5."}
+	locationsParam = apiParam{Name: "locations", Type: "string", Required: true,
+		Description: "| separated list of the locations to compare as latitude,longitude optionally preceded " +
+			"by name= (e.g. Paris=48.86,2.35|New York=40.71,-74.01)"}
+)
+
+// The options accepted by every endpoint that reads them with parseWeatherQuery or queryOptionsFromQuery
+var weatherOptionParams = []apiParam{unitsParam, tempUnitParam, windUnitParam, pressureUnitParam, distanceUnitParam,
+	precipUnitParam, langParam, summaryStyleParam, comfortProfileParam, coldCoolWarmParam, trendSummaryParam}
+
+// withOptions returns the parameters followed by the weather options
+func withOptions(params ...apiParam) []apiParam {
+	return append(params, weatherOptionParams...)
+}
+
+// The parameters accepted wherever the current weather at one location is returned
+var currentWeatherParams = append(withOptions(latitudeParam, longitudeParam), fieldsParam, compactParam)
+
+// The parameters of the recommendations for a location
+var recommendationParams = withOptions(latitudeParam, longitudeParam)
+
+// The parameters of a comparison.  The options apply to every location.
+var compareParams = withOptions(locationsParam)
+
+// The parameters of a grid.  The options apply to every point.
+var gridParams = withOptions(bboxParam, stepParam, gridFieldsParam)
+
+// The parameters of the weather along a route.  The options apply to every segment.
+var routeParams = withOptions(spacingParam, polylinePrecisionParam)
+
+// The parameters of the history of a location.  Stored observations have no trends, so
+// trendSummary is accepted but doesn't change the summaries.
+var historyParams = withOptions(latitudeParam, longitudeParam, historyFromParam, historyToParam)
+
+// The parameters of the aggregated history of a location.  The options are checked like
+// any other request but only the temperature and precipitation units are used.
+var historyAggregateParams = withOptions(latitudeParam, longitudeParam, intervalParam, historyFromParam, historyToParam)
+
+// The parameters of a history export
+var historyExportParams = []apiParam{exportFormatParam, exportLatitudeParam, exportLongitudeParam, exportFromParam,
+	historyToParam}
+
+// withGeoFormat returns the parameters of an endpoint that can also return GeoJSON
+func withGeoFormat(params []apiParam) []apiParam {
+	return append(append([]apiParam{}, params...), geoFormatParam)
+}
+
+// apiRoutes returns the route table.  It's a function rather than a variable
+// because the OpenAPI handler refers to the route table itself.
+func apiRoutes() []apiRoute {
+	html := "text/html"
+	jsonType := "application/json"
+
+	return []apiRoute{
+		{Path: "/", Handler: versionHandler, Operations: []apiOperation{
+			{Method: http.MethodGet, Summary: "Version page", Params: []apiParam{langParam}, ContentType: html}}},
+		{Path: "/version", Handler: versionHandler, Operations: []apiOperation{
+			{Method: http.MethodGet, Summary: "Version page", Params: []apiParam{langParam}, ContentType: html}}},
+		{Path: "/getcurrentweather.html", Handler: getCurrentWeatherForm, Operations: []apiOperation{
+			{Method: http.MethodGet, Summary: "Form for choosing a location", Params: []apiParam{langParam},
+				ContentType: html}}},
+		{Path: "/displaycurrentweather.html", Handler: displayCurrentWeatherForm, Operations: []apiOperation{
+			{Method: http.MethodGet, Summary: "Page showing the current weather at a location",
+				Params: currentWeatherParams, ContentType: html}}},
+		{Path: "/compareweather.html", Handler: compareWeatherPage, Operations: []apiOperation{
+			{Method: http.MethodGet, Summary: "Page comparing the current weather at several locations",
+				Params: compareParams, ContentType: html}}},
+		{Path: "/api/currentweather", Handler: apiGetCurrentWeather, Operations: []apiOperation{
+			{Method: http.MethodGet, Summary: "The current weather at a location", Params: withGeoFormat(currentWeatherParams),
+				Response: reflect.TypeOf(data.SimplifiedWeather{}), ContentType: jsonType}}},
+		{Path: "/api/recommendations", Handler: apiGetRecommendations, Operations: []apiOperation{
+			{Method: http.MethodGet, Summary: "Activity scores and clothing for the weather at a location",
+				Params:   withGeoFormat(recommendationParams),
+				Response: reflect.TypeOf(data.Recommendations{}), ContentType: jsonType}}},
+		{Path: "/api/compare", Handler: apiCompare, Operations: []apiOperation{
+			{Method: http.MethodGet, Summary: "The current weather at several locations with their differences and rankings",
+				Params: withGeoFormat(compareParams), Response: reflect.TypeOf(comparison{}), ContentType: jsonType}}},
+		{Path: "/api/grid", Handler: apiGetWeatherGrid, Operations: []apiOperation{
+			{Method: http.MethodGet, Summary: "The current weather at the points of a grid over an area as a matrix of each field",
+				Params: withGeoFormat(gridParams), Response: reflect.TypeOf(weatherGrid{}), ContentType: jsonType}}},
+		{Path: "/api/route", Handler: apiGetRouteWeather, Operations: []apiOperation{
+			{Method: http.MethodPost, Summary: "The weather along a route sent as GPX, a GeoJSON LineString, or an encoded polyline",
+				Params: withGeoFormat(routeParams), RequestTypes: []string{gpxType, data.GeoJSONType, polylineType},
+				Response: reflect.TypeOf(routeWeather{}), ContentType: jsonType}}},
+		{Path: "/api/history", Handler: apiGetHistory, Operations: []apiOperation{
+			{Method: http.MethodGet, Summary: "The recorded observations at a location",
+				Params: withGeoFormat(historyParams), Response: reflect.TypeOf(historyResponse{}), ContentType: jsonType}}},
+		{Path: "/api/history/aggregate", Handler: apiGetHistoryAggregates, Operations: []apiOperation{
+			{Method: http.MethodGet, Summary: "Hourly, daily, or weekly aggregates of the recorded observations at a location",
+				Params:   withGeoFormat(historyAggregateParams),
+				Response: reflect.TypeOf(historyAggregatesResponse{}), ContentType: jsonType}}},
+		{Path: "/api/history/export", Handler: apiExportHistory, Operations: []apiOperation{
+			{Method: http.MethodGet, Summary: "Stream the recorded observations in metric units as NDJSON or CSV",
+				Params: historyExportParams, ContentType: "application/x-ndjson"}}},
+		{Path: "/api/currentweather/stream", Handler: apiStreamCurrentWeather, Operations: []apiOperation{
+			{Method: http.MethodGet, Summary: "Server-Sent Events with each new observation at a location",
+				Params: currentWeatherParams, ContentType: "text/event-stream"}}},
+		{Path: "/api/ws", Handler: apiWebSocket, Operations: []apiOperation{
+			{Method: http.MethodGet, Summary: "WebSocket for subscribing to the weather at many locations",
+				ContentType: jsonType}}},
+		{Path: "/api/alerts", Handler: apiAlerts, Operations: []apiOperation{
+			{Method: http.MethodGet, Summary: "List the alert rules",
+				Response: reflect.TypeOf([]alertRule{}), ContentType: jsonType},
+			{Method: http.MethodPost, Summary: "Register an alert rule", RequestBody: reflect.TypeOf(alertRule{}),
+				Response: reflect.TypeOf(alertRule{}), ContentType: jsonType},
+			{Method: http.MethodDelete, Summary: "Remove an alert rule", Params: []apiParam{alertIdParam}}}},
+		{Path: "/api/alerts/deliveries", Handler: apiAlertDeliveries, Operations: []apiOperation{
+			{Method: http.MethodGet, Summary: "The most recent webhook deliveries, newest first",
+				Params: []apiParam{alertRuleIdParam}, Response: reflect.TypeOf([]alertDelivery{}), ContentType: jsonType}}},
+		{Path: "/graphql", Handler: apiGraphql, Operations: []apiOperation{
+			{Method: http.MethodGet, Summary: "Run a GraphQL query",
+				Params:      []apiParam{graphqlQueryParam, graphqlVariablesParam, graphqlOperationNameParam},
+				ContentType: jsonType},
+			{Method: http.MethodPost, Summary: "Run a GraphQL query", RequestBody: reflect.TypeOf(graphqlRequest{}),
+				ContentType: jsonType}}},
+		{Path: "/metrics", Handler: metricsHandler, Operations: []apiOperation{
+			{Method: http.MethodGet, Summary: "Prometheus metrics for the exporter locations", ContentType: "text/plain"}}},
+		{Path: "/api/openapi.json", Handler: apiOpenAPI, Operations: []apiOperation{
+			{Method: http.MethodGet, Summary: "This OpenAPI specification", ContentType: jsonType}}},
+		{Path: "/api/docs", Handler: apiDocs, Operations: []apiOperation{
+			{Method: http.MethodGet, Summary: "Interactive API documentation", ContentType: html}}},
+	}
+}
+
+// validateApiRoutes checks the route table when the server starts
+func validateApiRoutes(routes []apiRoute) error {
+	paths := map[string]bool{}
+
+	for _, route := range routes {
+		if paths[route.Path] {
+			return fmt.Errorf("Route %v is defined more than once", route.Path)
+		}
+
+		paths[route.Path] = true
+
+		if route.Handler == nil || len(route.Operations) == 0 {
+			return fmt.Errorf("Route %v has no handler or operations", route.Path)
+		}
+
+		for _, operation := range route.Operations {
+			paramNames := map[string]bool{}
+
+			for _, param := range operation.Params {
+				if paramNames[param.Name] {
+					return fmt.Errorf("%v %v: parameter %v is defined more than once", operation.Method, route.Path, param.Name)
+				}
+
+				paramNames[param.Name] = true
+
+				switch param.Type {
+				case "string", "number", "integer", "boolean":
+				default:
+					return fmt.Errorf("%v %v: parameter %v has invalid type %v", operation.Method, route.Path,
+						param.Name, param.Type)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+var openAPISpecOnce sync.Once
+var openAPISpec map[string]interface{}
+
+// buildOpenAPISpec generates the OpenAPI 3 document from the route table
+// and the json tags of the request and response types
+func buildOpenAPISpec(routes []apiRoute) map[string]interface{} {
+	schemas := map[string]interface{}{}
+	paths := map[string]interface{}{}
+
+	for _, route := range routes {
+		pathItem := map[string]interface{}{}
+
+		for _, operation := range route.Operations {
+			pathItem[strings.ToLower(operation.Method)] = openAPIOperation(operation, schemas)
+		}
+
+		paths[route.Path] = pathItem
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Current Weather Server",
+			"description": "A summary of the current weather at a longitude and latitude (using the Open Weather API)",
+			"version":     VERSION,
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": schemas},
+	}
+}
+
+func openAPIOperation(operation apiOperation, schemas map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{"summary": operation.Summary}
+
+	if len(operation.Params) > 0 {
+		params := make([]interface{}, len(operation.Params))
+
+		for inx, param := range operation.Params {
+			schema := map[string]interface{}{"type": param.Type}
+
+			if len(param.Enum) > 0 {
+				schema["enum"] = param.Enum
+			}
+
+			if param.Minimum != nil {
+				schema["minimum"] = *param.Minimum
+			}
+
+			if param.Maximum != nil {
+				schema["maximum"] = *param.Maximum
+			}
+
+			params[inx] = map[string]interface{}{
+				"name":        param.Name,
+				"in":          "query",
+				"description": param.Description,
+				"required":    param.Required,
+				"schema":      schema,
+			}
+		}
+
+		result["parameters"] = params
+	}
+
+	if operation.RequestTypes != nil {
+		content := map[string]interface{}{}
+
+		for _, mediaType := range operation.RequestTypes {
+			content[mediaType] = map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}
+		}
+
+		result["requestBody"] = map[string]interface{}{"required": true, "content": content}
+	}
+
+	if operation.RequestBody != nil {
+		result["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": openAPISchema(operation.RequestBody, schemas)},
+			},
+		}
+	}
+
+	response := map[string]interface{}{"description": "OK"}
+
+	if operation.ContentType != "" {
+		media := map[string]interface{}{}
+
+		if operation.Response != nil {
+			media["schema"] = openAPISchema(operation.Response, schemas)
+		}
+
+		response["content"] = map[string]interface{}{operation.ContentType: media}
+	}
+
+	result["responses"] = map[string]interface{}{
+		"200": response,
+		"400": map[string]interface{}{"description": "Invalid parameters"},
+		"500": map[string]interface{}{"description": "Error calling Open Weather"},
+	}
+
+	return result
+}
+
+// openAPISchema returns the schema for a type.  Named structs are added to
+// the component schemas and referred to by name.
+func openAPISchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := openAPISchema(t.Elem(), schemas)
+		if _, isRef := schema["$ref"]; isRef {
+			return map[string]interface{}{"allOf": []interface{}{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": openAPISchema(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": openAPISchema(t.Elem(), schemas)}
+	case reflect.Struct:
+		name := openAPISchemaName(t)
+
+		if name == "" {
+			return openAPIStructSchema(t, schemas)
+		}
+
+		if _, found := schemas[name]; !found {
+			// Reserve the name first in case the struct refers to itself
+			schemas[name] = map[string]interface{}{}
+			schemas[name] = openAPIStructSchema(t, schemas)
+		}
+
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+
+	// interface{} and anything else can hold any value
+	return map[string]interface{}{}
+}
+
+func openAPIStructSchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+
+	for inx := 0; inx < t.NumField(); inx++ {
+		structField := t.Field(inx)
+
+		if !structField.IsExported() {
+			continue
+		}
+
+		tagParts := strings.Split(structField.Tag.Get("json"), ",")
+		name := tagParts[0]
+
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = structField.Name
+		}
+
+		omitEmpty := false
+		for _, option := range tagParts[1:] {
+			omitEmpty = omitEmpty || option == "omitempty"
+		}
+
+		properties[name] = openAPISchema(structField.Type, schemas)
+
+		if !omitEmpty && structField.Type.Kind() != reflect.Ptr {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+// openAPISchemaName turns a Go type name into a schema name (e.g. alertRule -> AlertRule)
+func openAPISchemaName(t reflect.Type) string {
+	name := t.Name()
+
+	if name == "" {
+		return ""
+	}
+
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func apiOpenAPI(requestNum uint64, writer http.ResponseWriter, request *http.Request) {
+	openAPISpecOnce.Do(func() {
+		openAPISpec = buildOpenAPISpec(apiRoutes())
+	})
+
+	writeJson(requestNum, writer, openAPISpec, http.StatusOK)
+}
+
+func apiDocs(requestNum uint64, writer http.ResponseWriter, request *http.Request) {
+	templates[data.DefaultLanguage].ExecuteTemplate(writer, "api_docs", "")
+}
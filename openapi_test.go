@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"current-weather-server/history"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// fakeOpenWeather answers every Open Weather call with the same observation
+type fakeOpenWeather struct{}
+
+func (fakeOpenWeather) RoundTrip(request *http.Request) (*http.Response, error) {
+	body := `{"coord":{"lon":2.35,"lat":48.86},"weather":[{"id":804,"main":"Clouds","description":"overcast clouds",` +
+		`"icon":"04d"}],"main":{"temp":11,"feels_like":9.2,"temp_min":10,"temp_max":12,"pressure":1012,"humidity":40},` +
+		`"visibility":10000,"wind":{"speed":5,"deg":200,"gust":7},"clouds":{"all":97},"dt":1711482248,` +
+		`"sys":{"country":"FR","sunrise":1711431000,"sunset":1711476000},"timezone":3600,"name":"Paris","cod":200}`
+	return &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Header: http.Header{},
+		Body: io.NopCloser(strings.NewReader(body)), Request: request}, nil
+}
+
+// TestMain answers every Open Weather call with fakeOpenWeather, including the calls of
+// watchers that are still stopping when a test ends
+func TestMain(m *testing.M) {
+	http.DefaultTransport = fakeOpenWeather{}
+	os.Exit(m.Run())
+}
+
+// The values of the required parameters that get the handlers past their validation
+var requiredParamValues = map[string]string{
+	latitudeParam.Name:     "48.86",
+	longitudeParam.Name:    "2.35",
+	locationsParam.Name:    "Paris=48.86,2.35|London=51.51,-0.13",
+	bboxParam.Name:         "2,48,3,49",
+	stepParam.Name:         "1",
+	alertIdParam.Name:      "unknown",
+	graphqlQueryParam.Name: "{__typename}",
+}
+
+// The request bodies of the operations that take one
+var operationBodies = map[string]string{
+	"/api/route":  `{"type":"LineString","coordinates":[[2.35,48.86],[2.4,48.9]]}`,
+	"/api/alerts": `{}`,
+	"/graphql":    `{"query":"{__typename}"}`,
+}
+
+// readParams runs the handler of an operation and returns the names of the parameters it read
+func readParams(route apiRoute, operation apiOperation) []string {
+	query := url.Values{}
+
+	for _, param := range operation.Params {
+		if param.Required {
+			query.Set(param.Name, requiredParamValues[param.Name])
+		}
+	}
+
+	read := map[string]bool{}
+	paramReadHook = func(name string) { read[name] = true }
+	defer func() { paramReadHook = nil }()
+
+	// The context is already done so the streaming handlers return once they've started
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	request := httptest.NewRequest(operation.Method, route.Path+"?"+query.Encode(),
+		strings.NewReader(operationBodies[route.Path])).WithContext(ctx)
+	route.Handler(1, httptest.NewRecorder(), request)
+
+	names := []string{}
+
+	for name := range read {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// TestHandlersReadTheirParams drives each operation of the route table and checks the handler
+// reads every parameter listed for it and no others, so the OpenAPI spec matches the handlers
+func TestHandlersReadTheirParams(t *testing.T) {
+	// The history endpoints return 404 before reading their parameters when history isn't enabled
+	store, err := history.Open(filepath.Join(t.TempDir(), "history.db"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	historyStore = store
+	defer func() {
+		historyStore = nil
+		store.Close()
+	}()
+
+	for _, route := range apiRoutes() {
+		for _, operation := range route.Operations {
+			listed := map[string]bool{}
+
+			for _, param := range operation.Params {
+				listed[param.Name] = true
+			}
+
+			read := readParams(route, operation)
+
+			for _, name := range read {
+				if !listed[name] {
+					t.Errorf("%v %v reads %v, which isn't one of its parameters", operation.Method, route.Path, name)
+				}
+				delete(listed, name)
+			}
+
+			for name := range listed {
+				t.Errorf("%v %v has parameter %v, which it doesn't read", operation.Method, route.Path, name)
+			}
+		}
+	}
+}
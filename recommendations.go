@@ -0,0 +1,65 @@
+package main
+
+import (
+	"current-weather-server/data"
+	"current-weather-server/logging"
+	"net/http"
+)
+
+// apiGetRecommendations scores the activities and picks the clothing for the
+// weather at a location.  It takes the location and lang parameters of /api/currentweather.
+func apiGetRecommendations(requestNum uint64, writer http.ResponseWriter, request *http.Request) {
+	geoJSON, err, statusCode := wantsGeoJSON(request)
+
+	if err != nil {
+		logging.LogHTTPError(requestNum, err.Error(), statusCode)
+		http.Error(writer, err.Error(), statusCode)
+		return
+	}
+
+	query, err, statusCode := parseWeatherQuery(request)
+
+	if err != nil {
+		logging.LogHTTPError(requestNum, err.Error(), statusCode)
+		http.Error(writer, err.Error(), statusCode)
+		return
+	}
+
+	_, recommendations, err, statusCode := fetchRecommendations(query)
+
+	if err != nil {
+		logging.LogHTTPError(requestNum, err.Error(), statusCode)
+		http.Error(writer, err.Error(), statusCode)
+		return
+	}
+
+	writer.Header().Set("Content-Language", query.Lang)
+
+	if geoJSON {
+		writeGeoJson(requestNum, writer, data.NewPointFeature(recommendations.Lat, recommendations.Long, recommendations),
+			http.StatusOK)
+		return
+	}
+
+	writeJson(requestNum, writer, recommendations, http.StatusOK)
+}
+
+// fetchRecommendations fetches the weather for a query and returns it converted to the query's units
+// with the recommendations, which are made from the metric observation since the rules are metric
+func fetchRecommendations(query *weatherQuery) (*data.SimplifiedWeather, *data.Recommendations, error, int) {
+	currentWeatherData, trends, err, statusCode := fetchMetricWeather(query)
+
+	if err != nil {
+		return nil, nil, err, statusCode
+	}
+
+	currentWeatherData.DataCollectionTime = unixEpochTimeToString(int64(currentWeatherData.Dt))
+	recommendations := data.Recommend(currentWeatherData)
+	simplifiedData, err := convertForQuery(query, currentWeatherData, trends)
+
+	if err != nil {
+		return nil, nil, err, http.StatusInternalServerError
+	}
+
+	return simplifiedData, recommendations, nil, http.StatusOK
+}
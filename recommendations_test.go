@@ -0,0 +1,37 @@
+package main
+
+import (
+	"current-weather-server/data"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// The rules are metric so the units of the request don't change the recommendations
+func TestRecommendationsIgnoreUnits(t *testing.T) {
+	var metric *data.Recommendations
+
+	for _, units := range []string{"metric", "imperial", "standard"} {
+		recorder := httptest.NewRecorder()
+		apiGetRecommendations(1, recorder,
+			httptest.NewRequest(http.MethodGet, "/api/recommendations?latitude=48.86&longitude=2.35&units="+units, nil))
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("units=%v: status %v: %v", units, recorder.Code, recorder.Body.String())
+		}
+
+		recommendations := &data.Recommendations{}
+
+		if err := json.Unmarshal(recorder.Body.Bytes(), recommendations); err != nil {
+			t.Fatal(err)
+		}
+
+		if metric == nil {
+			metric = recommendations
+		} else if !reflect.DeepEqual(recommendations, metric) {
+			t.Errorf("units=%v: recommendations = %+v, want %+v", units, recommendations, metric)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"current-weather-server/data"
+	"current-weather-server/logging"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+// The largest route file /api/route reads
+const maxRouteBodyBytes = 4 << 20
+
+// The most segments (each one a fetch from Open Weather) a route can be split into
+var maxRouteSegments = 50
+
+// The kilometers between the weather along a route when the request doesn't say
+const defaultRouteSpacingKm = 10.0
+
+// The media types of the route formats.  GPX files are often sent as plain XML.
+const (
+	gpxType      = "application/gpx+xml"
+	polylineType = "text/plain"
+)
+
+// routeSegment is a stretch of a route with the weather at its middle
+type routeSegment struct {
+	StartKm   float64                 `json:"startKm"` // the distance along the route
+	EndKm     float64                 `json:"endKm"`
+	Latitude  float64                 `json:"latitude"`
+	Longitude float64                 `json:"longitude"`
+	Weather   *data.SimplifiedWeather `json:"weather,omitempty"`
+	Error     string                  `json:"error,omitempty"`
+}
+
+// routeExtreme is the worst value of a field along a route and the position of its segment
+type routeExtreme struct {
+	Value   float64 `json:"value"`
+	Segment int     `json:"segment"`
+}
+
+// routeWorst is the worst weather along a route over the segments whose weather was fetched
+type routeWorst struct {
+	Units        string        `json:"units"` // the temperature unit
+	WindUnit     string        `json:"windUnit"`
+	MinTemp      *routeExtreme `json:"minTemp"`
+	MinFeelsLike *routeExtreme `json:"minFeelsLike"`
+	MaxWind      *routeExtreme `json:"maxWind"`
+	MaxGust      *routeExtreme `json:"maxGust"`
+	Rain         bool          `json:"rain"`         // whether it's raining on any segment
+	RainSegments []int         `json:"rainSegments"` // the positions of the segments where it's raining
+}
+
+// routeWeather is returned by /api/route
+type routeWeather struct {
+	LengthKm  float64        `json:"lengthKm"`
+	SpacingKm float64        `json:"spacingKm"`
+	Segments  []routeSegment `json:"segments"`
+	Worst     *routeWorst    `json:"worst"` // null when no segment's weather could be fetched
+}
+
+// parseRoute reads the points of the route in the request body.  The format is given by the
+// Content-Type or, when that isn't one of the formats, by the start of the body.
+func parseRoute(request *http.Request) ([]data.RoutePoint, error, int) {
+	body, err := io.ReadAll(io.LimitReader(request.Body, maxRouteBodyBytes+1))
+
+	if err != nil {
+		return nil, fmt.Errorf("Error reading request body: %v", err), http.StatusBadRequest
+	}
+
+	if len(body) > maxRouteBodyBytes {
+		return nil, fmt.Errorf("The route is too large (the maximum is %v bytes)", maxRouteBodyBytes),
+			http.StatusRequestEntityTooLarge
+	}
+
+	body = bytes.TrimSpace(body)
+
+	if len(body) == 0 {
+		return nil, errors.New("missing route"), http.StatusBadRequest
+	}
+
+	precisionStr := polylinePrecisionParam.value(request.URL.Query())
+	precision := 5
+
+	if precisionStr != "" {
+		precision, err = strconv.Atoi(precisionStr)
+
+		if err != nil || precision < 1 || precision > 7 {
+			return nil, fmt.Errorf("Invalid polylinePrecision value: %v (must be between 1 and 7)", precisionStr),
+				http.StatusBadRequest
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	var points []data.RoutePoint
+
+	switch {
+	case mediaType == gpxType || mediaType == "application/xml" || mediaType == "text/xml":
+		points, err = data.ParseGPX(body)
+	case mediaType == data.GeoJSONType || mediaType == "application/json":
+		points, err = data.ParseGeoJSONLine(body)
+	case mediaType == polylineType:
+		points, err = data.DecodePolyline(string(body), precision)
+	case body[0] == '<':
+		points, err = data.ParseGPX(body)
+	case body[0] == '{':
+		points, err = data.ParseGeoJSONLine(body)
+	default:
+		points, err = data.DecodePolyline(string(body), precision)
+	}
+
+	if err != nil {
+		return nil, err, http.StatusBadRequest
+	}
+
+	return points, nil, http.StatusOK
+}
+
+// getRouteWeather splits the route in the request into segments and fetches the weather at
+// the middle of each one.  The options are the same as /api/currentweather and apply to every segment.
+func getRouteWeather(request *http.Request) (*routeWeather, error, int) {
+	if request.Method != http.MethodPost {
+		return nil, fmt.Errorf("Unsupported method: %v", request.Method), http.StatusMethodNotAllowed
+	}
+
+	queryValues := request.URL.Query()
+	spacing := defaultRouteSpacingKm
+
+	if spacingStr := spacingParam.value(queryValues); spacingStr != "" {
+		var err error
+		spacing, err = strconv.ParseFloat(spacingStr, 64)
+
+		if err != nil || !(spacing > 0) || math.IsInf(spacing, 1) {
+			return nil, fmt.Errorf("Invalid spacing value: %v (must be a number of kilometers greater than 0)",
+				spacingStr), http.StatusBadRequest
+		}
+	}
+
+	options := queryOptionsFromQuery(queryValues)
+
+	if options.Lang == "" {
+		options.Lang = acceptLanguage(request.Header.Get("Accept-Language"))
+	}
+
+	points, err, statusCode := parseRoute(request)
+
+	if err != nil {
+		return nil, err, statusCode
+	}
+
+	// The number of segments is checked before they're made so a tiny spacing can't allocate huge routes
+	length := data.RouteLengthKm(points)
+	count := data.RouteSegmentCount(length, spacing)
+
+	if count > float64(maxRouteSegments) {
+		return nil, fmt.Errorf("Too many route segments: %v (the maximum is %v, use a larger spacing)",
+			count, maxRouteSegments), http.StatusBadRequest
+	}
+
+	result := &routeWeather{LengthKm: roundKm(length), SpacingKm: spacing}
+	queries := []*weatherQuery{}
+
+	for _, segment := range data.SplitRoute(points, spacing) {
+		query, err, statusCode := newWeatherQuery(segment.Middle.Latitude, segment.Middle.Longitude, options)
+
+		if err != nil {
+			return nil, err, statusCode
+		}
+
+		queries = append(queries, query)
+		result.Segments = append(result.Segments, routeSegment{StartKm: roundKm(segment.StartKm),
+			EndKm: roundKm(segment.EndKm), Latitude: segment.Middle.Latitude, Longitude: segment.Middle.Longitude})
+	}
+
+	for inx, fetched := range fetchCurrentWeatherConcurrently(queries) {
+		if fetched.Err != nil {
+			result.Segments[inx].Error = fetched.Err.Error()
+		} else {
+			result.Segments[inx].Weather = fetched.Simplified
+		}
+	}
+
+	result.Worst = worstRouteWeather(result.Segments)
+	return result, nil, http.StatusOK
+}
+
+// worstRouteWeather finds the worst weather of the segments.  Ties go to the first segment.
+func worstRouteWeather(segments []routeSegment) *routeWorst {
+	var worst *routeWorst
+
+	// record keeps the value when it's worse than the extreme so far
+	record := func(extreme **routeExtreme, value float64, inx int, worse func(a, b float64) bool) {
+		if *extreme == nil || worse(value, (*extreme).Value) {
+			*extreme = &routeExtreme{Value: value, Segment: inx}
+		}
+	}
+
+	lower := func(a, b float64) bool { return a < b }
+	higher := func(a, b float64) bool { return a > b }
+
+	for inx, segment := range segments {
+		weather := segment.Weather
+
+		if weather == nil {
+			continue
+		}
+
+		if worst == nil {
+			worst = &routeWorst{Units: weather.Units, WindUnit: weather.WindUnit, RainSegments: []int{}}
+		}
+
+		record(&worst.MinTemp, weather.Temp, inx, lower)
+		record(&worst.MinFeelsLike, weather.TempFeelsLike, inx, lower)
+		record(&worst.MaxWind, weather.WindSpeed, inx, higher)
+		record(&worst.MaxGust, weather.WindGust, inx, higher)
+
+		if weather.Rain1h > 0 {
+			worst.Rain = true
+			worst.RainSegments = append(worst.RainSegments, inx)
+		}
+	}
+
+	return worst
+}
+
+func roundKm(km float64) float64 {
+	return math.Round(km*1000) / 1000
+}
+
+// geoJSON returns the route as a feature for the middle of each segment.  The properties are
+// the segment's distances and its weather or error.
+func (result *routeWeather) geoJSON() *data.GeoJSONFeatureCollection {
+	collection := &data.GeoJSONFeatureCollection{Members: []data.SelectedField{{Name: "lengthKm", Value: result.LengthKm},
+		{Name: "spacingKm", Value: result.SpacingKm}, {Name: "worst", Value: result.Worst}}}
+
+	for _, segment := range result.Segments {
+		properties := data.SelectedWeather{{Name: "startKm", Value: segment.StartKm}, {Name: "endKm", Value: segment.EndKm}}
+
+		if segment.Weather == nil {
+			properties = append(properties, data.SelectedField{Name: "error", Value: segment.Error})
+			collection.Features = append(collection.Features,
+				data.NewPointFeature(segment.Latitude, segment.Longitude, properties))
+			continue
+		}
+
+		collection.Features = append(collection.Features, data.NewWeatherFeature(segment.Weather, nil, properties...))
+	}
+
+	return collection
+}
+
+func apiGetRouteWeather(requestNum uint64, writer http.ResponseWriter, request *http.Request) {
+	geoJSON, err, statusCode := wantsGeoJSON(request)
+
+	if err != nil {
+		logging.LogHTTPError(requestNum, err.Error(), statusCode)
+		http.Error(writer, err.Error(), statusCode)
+		return
+	}
+
+	result, err, statusCode := getRouteWeather(request)
+
+	if err != nil {
+		logging.LogHTTPError(requestNum, err.Error(), statusCode)
+		http.Error(writer, err.Error(), statusCode)
+		return
+	}
+
+	if geoJSON {
+		writeGeoJson(requestNum, writer, result.geoJSON(), http.StatusOK)
+		return
+	}
+
+	writeJson(requestNum, writer, result, http.StatusOK)
+}
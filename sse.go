@@ -0,0 +1,93 @@
+package main
+
+import (
+	"current-weather-server/data"
+	"current-weather-server/logging"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// How often a comment is sent on an idle stream so proxies don't close it
+const streamKeepAliveInterval = 30 * time.Second
+
+// apiStreamCurrentWeather sends a Server-Sent Event each time the observation
+// for the location changes.  It takes the same parameters as /api/currentweather.
+func apiStreamCurrentWeather(requestNum uint64, writer http.ResponseWriter, request *http.Request) {
+	selection, err, statusCode := getFieldSelection(request)
+
+	if err != nil {
+		logging.LogHTTPError(requestNum, err.Error(), statusCode)
+		http.Error(writer, err.Error(), statusCode)
+		return
+	}
+
+	query, err, statusCode := parseWeatherQuery(request)
+
+	if err != nil {
+		logging.LogHTTPError(requestNum, err.Error(), statusCode)
+		http.Error(writer, err.Error(), statusCode)
+		return
+	}
+
+	flusher, ok := writer.(http.Flusher)
+
+	if !ok {
+		msg := "Streaming is not supported"
+		logging.LogHTTPError(requestNum, msg, http.StatusInternalServerError)
+		http.Error(writer, msg, http.StatusInternalServerError)
+		return
+	}
+
+	updates, unsubscribe := weatherWatchers.subscribe(*query)
+	defer unsubscribe()
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Content-Language", query.Lang)
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.Header().Set("Connection", "keep-alive")
+	writer.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-request.Context().Done():
+			logging.LogInfo(requestNum, "Client closed weather stream")
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(writer, ": keep-alive\n\n")
+		case update := <-updates:
+			err = writeWeatherEvent(writer, update, selection)
+
+			if err != nil {
+				logging.LogError(requestNum, fmt.Sprintf("Error writing weather event: %v", err))
+				return
+			}
+		}
+
+		flusher.Flush()
+	}
+}
+
+// writeWeatherEvent writes an update as a "weather" event (with the upstream
+// Dt as its id) or, if fetching the weather failed, as an "error" event.
+func writeWeatherEvent(writer http.ResponseWriter, update *weatherUpdate, selection *data.FieldSelection) error {
+	if update.Err != nil {
+		_, err := fmt.Fprintf(writer, "event: error\ndata: %v\n\n", strings.ReplaceAll(update.Err.Error(), "\n", " "))
+		return err
+	}
+
+	jsonBytes, err := json.Marshal(selection.Select(update.Simplified))
+
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(writer, "id: %v\nevent: weather\ndata: %s\n\n", update.Data.Dt, jsonBytes)
+	return err
+}
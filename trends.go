@@ -0,0 +1,250 @@
+package main
+
+import (
+	"current-weather-server/data"
+	"current-weather-server/history"
+	"current-weather-server/logging"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// How long the observations of a location are kept for its trends and baseline
+const trendWindow = 7 * 24 * time.Hour
+
+// The most observations kept for a location (one every 5 minutes over the window)
+const maxTrendSamples = 7 * 24 * 12
+
+// The most locations observations are kept for.  The location observed longest ago is dropped first.
+var maxTrendLocations = 1000
+
+// The fewest previous days with an observation at the local hour that make a baseline
+const minBaselineDays = 3
+
+// trendSpan is a change reported in the trends.  The change is measured against the observation
+// closest to span before the latest one if it's within tolerance of that time.
+type trendSpan struct {
+	span      time.Duration
+	tolerance time.Duration
+}
+
+var (
+	trendSpan1h  = trendSpan{time.Hour, 15 * time.Minute}
+	trendSpan3h  = trendSpan{3 * time.Hour, 45 * time.Minute}
+	trendSpan24h = trendSpan{24 * time.Hour, 2 * time.Hour}
+)
+
+// trendSample is what the trends need of an observation.  The values are metric.
+type trendSample struct {
+	Dt       int64
+	Timezone int
+	Temp     float64
+	Pressure float64
+}
+
+// localHour is the hour of the day at the location (0-23)
+func (sample trendSample) localHour() int64 {
+	return (sample.Dt + int64(sample.Timezone)) / 3600 % 24
+}
+
+// localDay is the day at the location in days since 1970
+func (sample trendSample) localDay() int64 {
+	return (sample.Dt + int64(sample.Timezone)) / (24 * 3600)
+}
+
+// observationWindow is the rolling window of a location's recent observations, oldest first
+type observationWindow struct {
+	samples  []trendSample
+	observed time.Time
+}
+
+var trendWindows = struct {
+	sync.Mutex
+	locations map[string]*observationWindow
+}{locations: map[string]*observationWindow{}}
+
+// trackObservation adds weather fetched from Open Weather (in metric units) to the window of its
+// location and returns the location's trends.  The trends are nil until there are earlier observations.
+func trackObservation(query *weatherQuery, observation *data.CurrentWeatherData) *data.Trends {
+	if observation.Dt <= 0 {
+		return nil
+	}
+
+	location := history.LocationKey(query.Latitude, query.Longitude)
+	sample := trendSample{Dt: int64(observation.Dt), Timezone: observation.Timezone, Temp: observation.Main.Temp,
+		Pressure: observation.Main.Pressure}
+
+	trendWindows.Lock()
+	window, found := trendWindows.locations[location]
+	trendWindows.Unlock()
+
+	// The first time a location is observed (e.g. after a restart) its window starts with
+	// its recorded history.  The history is read without holding the lock.
+	if !found {
+		window = &observationWindow{samples: recordedSamples(location, time.Unix(sample.Dt, 0))}
+	}
+
+	trendWindows.Lock()
+	defer trendWindows.Unlock()
+
+	if existing, found := trendWindows.locations[location]; found {
+		window = existing
+	} else {
+		if len(trendWindows.locations) >= maxTrendLocations {
+			dropOldestTrendWindow()
+		}
+		trendWindows.locations[location] = window
+	}
+
+	window.observed = time.Now()
+	window.add(sample)
+	trends := window.trends(sample)
+
+	if trends.IsEmpty() {
+		return nil
+	}
+
+	return trends
+}
+
+// recordedSamples returns the observations of the location in the history store over the window before now
+func recordedSamples(location string, now time.Time) []trendSample {
+	if historyStore == nil {
+		return nil
+	}
+
+	observations, _, err := historyStore.Observations(location, now.Add(-trendWindow), now, maxTrendSamples)
+
+	if err != nil {
+		logging.LogError(0, fmt.Sprintf("Error reading the history of %v for its trends: %v", location, err))
+		return nil
+	}
+
+	samples := make([]trendSample, len(observations))
+
+	for inx, observation := range observations {
+		samples[inx] = trendSample{Dt: int64(observation.Dt), Timezone: observation.Timezone,
+			Temp: observation.Main.Temp, Pressure: observation.Main.Pressure}
+	}
+
+	return samples
+}
+
+// dropOldestTrendWindow removes the location observed longest ago.  trendWindows must be locked.
+func dropOldestTrendWindow() {
+	oldestLocation := ""
+	var oldest time.Time
+
+	for location, window := range trendWindows.locations {
+		if oldestLocation == "" || window.observed.Before(oldest) {
+			oldestLocation, oldest = location, window.observed
+		}
+	}
+
+	delete(trendWindows.locations, oldestLocation)
+}
+
+// add inserts a sample in time order unless there's already one at its time (an observation
+// fetched more than once) and drops the samples that have left the window
+func (window *observationWindow) add(sample trendSample) {
+	inx := sort.Search(len(window.samples), func(inx int) bool { return window.samples[inx].Dt >= sample.Dt })
+
+	if inx < len(window.samples) && window.samples[inx].Dt == sample.Dt {
+		return
+	}
+
+	window.samples = append(window.samples, trendSample{})
+	copy(window.samples[inx+1:], window.samples[inx:])
+	window.samples[inx] = sample
+
+	latest := window.samples[len(window.samples)-1].Dt
+	start := sort.Search(len(window.samples), func(inx int) bool {
+		return window.samples[inx].Dt >= latest-int64(trendWindow/time.Second)
+	})
+	start = max(start, len(window.samples)-maxTrendSamples)
+
+	if start > 0 {
+		window.samples = append(window.samples[:0], window.samples[start:]...)
+	}
+}
+
+// closest returns the sample closest to the span before the sample's time if it's within the span's tolerance
+func (window *observationWindow) closest(sample trendSample, span trendSpan) (trendSample, bool) {
+	target := sample.Dt - int64(span.span/time.Second)
+	tolerance := int64(span.tolerance / time.Second)
+	var closest trendSample
+	found := false
+
+	for _, candidate := range window.samples {
+		distance := abs64(candidate.Dt - target)
+
+		if distance <= tolerance && (!found || distance < abs64(closest.Dt-target)) {
+			closest, found = candidate, true
+		}
+	}
+
+	return closest, found
+}
+
+// trends returns the metric trends of a sample in the window
+func (window *observationWindow) trends(sample trendSample) *data.Trends {
+	trends := &data.Trends{}
+
+	change := func(span trendSpan, value func(trendSample) float64) *float64 {
+		if earlier, found := window.closest(sample, span); found {
+			difference := value(sample) - value(earlier)
+			return &difference
+		}
+		return nil
+	}
+
+	temp := func(sample trendSample) float64 { return sample.Temp }
+	pressure := func(sample trendSample) float64 { return sample.Pressure }
+
+	trends.TempChange1h = change(trendSpan1h, temp)
+	trends.TempChange3h = change(trendSpan3h, temp)
+	trends.TempChange24h = change(trendSpan24h, temp)
+	trends.PressureChange3h = change(trendSpan3h, pressure)
+
+	if trends.PressureChange3h != nil {
+		trends.PressureTendency = data.PressureTendency(*trends.PressureChange3h)
+	}
+
+	// The baseline is the average of each previous day's mean temperature at the same local
+	// hour so days with more observations don't count more
+	dayTemps := map[int64][]float64{}
+
+	for _, earlier := range window.samples {
+		if earlier.localHour() == sample.localHour() && earlier.localDay() < sample.localDay() {
+			dayTemps[earlier.localDay()] = append(dayTemps[earlier.localDay()], earlier.Temp)
+		}
+	}
+
+	if len(dayTemps) >= minBaselineDays {
+		baseline := 0.0
+
+		for _, temps := range dayTemps {
+			sum := 0.0
+			for _, temp := range temps {
+				sum += temp
+			}
+			baseline += sum / float64(len(temps))
+		}
+
+		baseline /= float64(len(dayTemps))
+		anomaly := sample.Temp - baseline
+		trends.TempBaseline = &baseline
+		trends.TempAnomaly = &anomaly
+		trends.BaselineDays = len(dayTemps)
+	}
+
+	return trends
+}
+
+func abs64(value int64) int64 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,1146 @@
+// The gRPC interface to the Current Weather Server.
+//
+// The Go code in this directory is generated from this file with:
+//
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//          --go-grpc_out=. --go-grpc_opt=paths=source_relative weather.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        v4.25.3
+// source: weather.proto
+
+package weatherpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetCurrentWeatherRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Between -90 and 90 (inclusive).  REQUIRED.
+	Latitude *float64 `protobuf:"fixed64,1,opt,name=latitude,proto3,oneof" json:"latitude,omitempty"`
+	// Between -180 and 180 (inclusive).  REQUIRED.
+	Longitude *float64 `protobuf:"fixed64,2,opt,name=longitude,proto3,oneof" json:"longitude,omitempty"`
+	// imperial, metric, or standard.  The default is metric.
+	Units string `protobuf:"bytes,3,opt,name=units,proto3" json:"units,omitempty"`
+	// C, F, or K.  Overrides the temperature unit of units.
+	TempUnit string `protobuf:"bytes,4,opt,name=temp_unit,json=tempUnit,proto3" json:"temp_unit,omitempty"`
+	// m/s, km/h, mph, kn, or Bft.  Overrides the wind speed unit of units.
+	WindUnit string `protobuf:"bytes,5,opt,name=wind_unit,json=windUnit,proto3" json:"wind_unit,omitempty"`
+	// hPa, inHg, or mmHg.  The default is hPa.
+	PressureUnit string `protobuf:"bytes,6,opt,name=pressure_unit,json=pressureUnit,proto3" json:"pressure_unit,omitempty"`
+	// m, km, or mi.  The unit of visibility.  The default is m.
+	DistanceUnit string `protobuf:"bytes,7,opt,name=distance_unit,json=distanceUnit,proto3" json:"distance_unit,omitempty"`
+	// mm or in.  The unit of rainfall.  The default is mm.
+	PrecipUnit string `protobuf:"bytes,8,opt,name=precip_unit,json=precipUnit,proto3" json:"precip_unit,omitempty"`
+	// en, es, fr, or de.  The language of the descriptions and summary.  The default is en.
+	Lang string `protobuf:"bytes,9,opt,name=lang,proto3" json:"lang,omitempty"`
+	// The name of an operator defined summary template.  The default is the built in summary.
+	SummaryStyle string `protobuf:"bytes,10,opt,name=summary_style,json=summaryStyle,proto3" json:"summary_style,omitempty"`
+	// The name of an operator defined comfort profile used for subjective_temp.  The default is the
+	// -subjectiveTempScale or -coldCoolWarmF temperatures.
+	ComfortProfile string `protobuf:"bytes,11,opt,name=comfort_profile,json=comfortProfile,proto3" json:"comfort_profile,omitempty"`
+	// The subjective temperature scale used instead of a comfort profile.  Either cold, cool, warm
+	// temperatures optionally followed by C, F, or K (e.g. "40,60,77F") or [feelsLike,]label:max,...,label
+	// bands (e.g. "freezing:0C,cold:5C,mild:18C,warm:25C,hot").  Temperatures without a unit are in the
+	// response's temperature unit.
+	ColdCoolWarm string `protobuf:"bytes,12,opt,name=cold_cool_warm,json=coldCoolWarm,proto3" json:"cold_cool_warm,omitempty"`
+	// Add the notable trends (a rising or falling pressure or an unusual temperature for the hour)
+	// to the default summary.
+	TrendSummary bool `protobuf:"varint,13,opt,name=trend_summary,json=trendSummary,proto3" json:"trend_summary,omitempty"`
+}
+
+func (x *GetCurrentWeatherRequest) Reset() {
+	*x = GetCurrentWeatherRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_weather_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetCurrentWeatherRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCurrentWeatherRequest) ProtoMessage() {}
+
+func (x *GetCurrentWeatherRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCurrentWeatherRequest.ProtoReflect.Descriptor instead.
+func (*GetCurrentWeatherRequest) Descriptor() ([]byte, []int) {
+	return file_weather_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GetCurrentWeatherRequest) GetLatitude() float64 {
+	if x != nil && x.Latitude != nil {
+		return *x.Latitude
+	}
+	return 0
+}
+
+func (x *GetCurrentWeatherRequest) GetLongitude() float64 {
+	if x != nil && x.Longitude != nil {
+		return *x.Longitude
+	}
+	return 0
+}
+
+func (x *GetCurrentWeatherRequest) GetUnits() string {
+	if x != nil {
+		return x.Units
+	}
+	return ""
+}
+
+func (x *GetCurrentWeatherRequest) GetTempUnit() string {
+	if x != nil {
+		return x.TempUnit
+	}
+	return ""
+}
+
+func (x *GetCurrentWeatherRequest) GetWindUnit() string {
+	if x != nil {
+		return x.WindUnit
+	}
+	return ""
+}
+
+func (x *GetCurrentWeatherRequest) GetPressureUnit() string {
+	if x != nil {
+		return x.PressureUnit
+	}
+	return ""
+}
+
+func (x *GetCurrentWeatherRequest) GetDistanceUnit() string {
+	if x != nil {
+		return x.DistanceUnit
+	}
+	return ""
+}
+
+func (x *GetCurrentWeatherRequest) GetPrecipUnit() string {
+	if x != nil {
+		return x.PrecipUnit
+	}
+	return ""
+}
+
+func (x *GetCurrentWeatherRequest) GetLang() string {
+	if x != nil {
+		return x.Lang
+	}
+	return ""
+}
+
+func (x *GetCurrentWeatherRequest) GetSummaryStyle() string {
+	if x != nil {
+		return x.SummaryStyle
+	}
+	return ""
+}
+
+func (x *GetCurrentWeatherRequest) GetComfortProfile() string {
+	if x != nil {
+		return x.ComfortProfile
+	}
+	return ""
+}
+
+func (x *GetCurrentWeatherRequest) GetColdCoolWarm() string {
+	if x != nil {
+		return x.ColdCoolWarm
+	}
+	return ""
+}
+
+func (x *GetCurrentWeatherRequest) GetTrendSummary() bool {
+	if x != nil {
+		return x.TrendSummary
+	}
+	return false
+}
+
+type GetCurrentWeatherResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Weather *SimplifiedWeather `protobuf:"bytes,1,opt,name=weather,proto3" json:"weather,omitempty"`
+}
+
+func (x *GetCurrentWeatherResponse) Reset() {
+	*x = GetCurrentWeatherResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_weather_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetCurrentWeatherResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCurrentWeatherResponse) ProtoMessage() {}
+
+func (x *GetCurrentWeatherResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCurrentWeatherResponse.ProtoReflect.Descriptor instead.
+func (*GetCurrentWeatherResponse) Descriptor() ([]byte, []int) {
+	return file_weather_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetCurrentWeatherResponse) GetWeather() *SimplifiedWeather {
+	if x != nil {
+		return x.Weather
+	}
+	return nil
+}
+
+type BatchGetCurrentWeatherRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Requests []*GetCurrentWeatherRequest `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
+}
+
+func (x *BatchGetCurrentWeatherRequest) Reset() {
+	*x = BatchGetCurrentWeatherRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_weather_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BatchGetCurrentWeatherRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchGetCurrentWeatherRequest) ProtoMessage() {}
+
+func (x *BatchGetCurrentWeatherRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchGetCurrentWeatherRequest.ProtoReflect.Descriptor instead.
+func (*BatchGetCurrentWeatherRequest) Descriptor() ([]byte, []int) {
+	return file_weather_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BatchGetCurrentWeatherRequest) GetRequests() []*GetCurrentWeatherRequest {
+	if x != nil {
+		return x.Requests
+	}
+	return nil
+}
+
+type BatchGetCurrentWeatherResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The position of the request in BatchGetCurrentWeatherRequest.requests
+	Index int32 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	// Set when the weather was retrieved
+	Weather *SimplifiedWeather `protobuf:"bytes,2,opt,name=weather,proto3" json:"weather,omitempty"`
+	// The gRPC status code (google.rpc.Code) for this location.  0 (OK) on success.
+	Code int32 `protobuf:"varint,3,opt,name=code,proto3" json:"code,omitempty"`
+	// The error when code isn't OK
+	Error string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *BatchGetCurrentWeatherResponse) Reset() {
+	*x = BatchGetCurrentWeatherResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_weather_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BatchGetCurrentWeatherResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchGetCurrentWeatherResponse) ProtoMessage() {}
+
+func (x *BatchGetCurrentWeatherResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchGetCurrentWeatherResponse.ProtoReflect.Descriptor instead.
+func (*BatchGetCurrentWeatherResponse) Descriptor() ([]byte, []int) {
+	return file_weather_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BatchGetCurrentWeatherResponse) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *BatchGetCurrentWeatherResponse) GetWeather() *SimplifiedWeather {
+	if x != nil {
+		return x.Weather
+	}
+	return nil
+}
+
+func (x *BatchGetCurrentWeatherResponse) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *BatchGetCurrentWeatherResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// SimplifiedWeather mirrors the json returned by /api/currentweather
+type SimplifiedWeather struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Units              string          `protobuf:"bytes,1,opt,name=units,proto3" json:"units,omitempty"`
+	DataCollectionTime string          `protobuf:"bytes,2,opt,name=data_collection_time,json=dataCollectionTime,proto3" json:"data_collection_time,omitempty"`
+	Latitude           float64         `protobuf:"fixed64,3,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude          float64         `protobuf:"fixed64,4,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	CloudinessPercent  float64         `protobuf:"fixed64,5,opt,name=cloudiness_percent,json=cloudinessPercent,proto3" json:"cloudiness_percent,omitempty"`
+	HumidityPercent    float64         `protobuf:"fixed64,6,opt,name=humidity_percent,json=humidityPercent,proto3" json:"humidity_percent,omitempty"`
+	Temp               float64         `protobuf:"fixed64,7,opt,name=temp,proto3" json:"temp,omitempty"`
+	TempHigh           float64         `protobuf:"fixed64,8,opt,name=temp_high,json=tempHigh,proto3" json:"temp_high,omitempty"`
+	TempLow            float64         `protobuf:"fixed64,9,opt,name=temp_low,json=tempLow,proto3" json:"temp_low,omitempty"`
+	TempFeelsLike      float64         `protobuf:"fixed64,10,opt,name=temp_feels_like,json=tempFeelsLike,proto3" json:"temp_feels_like,omitempty"`
+	ExpectedWeather    string          `protobuf:"bytes,11,opt,name=expected_weather,json=expectedWeather,proto3" json:"expected_weather,omitempty"`
+	WeatherDescription string          `protobuf:"bytes,12,opt,name=weather_description,json=weatherDescription,proto3" json:"weather_description,omitempty"`
+	SubjectiveTemp     string          `protobuf:"bytes,13,opt,name=subjective_temp,json=subjectiveTemp,proto3" json:"subjective_temp,omitempty"`
+	Summary            string          `protobuf:"bytes,14,opt,name=summary,proto3" json:"summary,omitempty"`
+	WindSpeed          float64         `protobuf:"fixed64,15,opt,name=wind_speed,json=windSpeed,proto3" json:"wind_speed,omitempty"`
+	WindGust           float64         `protobuf:"fixed64,16,opt,name=wind_gust,json=windGust,proto3" json:"wind_gust,omitempty"`
+	WindDirection      float64         `protobuf:"fixed64,17,opt,name=wind_direction,json=windDirection,proto3" json:"wind_direction,omitempty"`
+	WindUnit           string          `protobuf:"bytes,18,opt,name=wind_unit,json=windUnit,proto3" json:"wind_unit,omitempty"`
+	Pressure           float64         `protobuf:"fixed64,19,opt,name=pressure,proto3" json:"pressure,omitempty"`
+	PressureUnit       string          `protobuf:"bytes,20,opt,name=pressure_unit,json=pressureUnit,proto3" json:"pressure_unit,omitempty"`
+	Visibility         float64         `protobuf:"fixed64,21,opt,name=visibility,proto3" json:"visibility,omitempty"`
+	DistanceUnit       string          `protobuf:"bytes,22,opt,name=distance_unit,json=distanceUnit,proto3" json:"distance_unit,omitempty"`
+	RainLastHour       float64         `protobuf:"fixed64,23,opt,name=rain_last_hour,json=rainLastHour,proto3" json:"rain_last_hour,omitempty"`
+	PrecipUnit         string          `protobuf:"bytes,24,opt,name=precip_unit,json=precipUnit,proto3" json:"precip_unit,omitempty"`
+	Derived            *DerivedMetrics `protobuf:"bytes,25,opt,name=derived,proto3" json:"derived,omitempty"`
+	// The comfort profile subjective_temp is based on ("custom" for cold_cool_warm)
+	ComfortProfile string `protobuf:"bytes,26,opt,name=comfort_profile,json=comfortProfile,proto3" json:"comfort_profile,omitempty"`
+	// The tags of the condition rules the weather matches (e.g. muggy), highest priority first
+	Conditions []string `protobuf:"bytes,27,rep,name=conditions,proto3" json:"conditions,omitempty"`
+	// Left out until the location has recent observations
+	Trends *Trends `protobuf:"bytes,28,opt,name=trends,proto3" json:"trends,omitempty"`
+}
+
+func (x *SimplifiedWeather) Reset() {
+	*x = SimplifiedWeather{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_weather_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SimplifiedWeather) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SimplifiedWeather) ProtoMessage() {}
+
+func (x *SimplifiedWeather) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SimplifiedWeather.ProtoReflect.Descriptor instead.
+func (*SimplifiedWeather) Descriptor() ([]byte, []int) {
+	return file_weather_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SimplifiedWeather) GetUnits() string {
+	if x != nil {
+		return x.Units
+	}
+	return ""
+}
+
+func (x *SimplifiedWeather) GetDataCollectionTime() string {
+	if x != nil {
+		return x.DataCollectionTime
+	}
+	return ""
+}
+
+func (x *SimplifiedWeather) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *SimplifiedWeather) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *SimplifiedWeather) GetCloudinessPercent() float64 {
+	if x != nil {
+		return x.CloudinessPercent
+	}
+	return 0
+}
+
+func (x *SimplifiedWeather) GetHumidityPercent() float64 {
+	if x != nil {
+		return x.HumidityPercent
+	}
+	return 0
+}
+
+func (x *SimplifiedWeather) GetTemp() float64 {
+	if x != nil {
+		return x.Temp
+	}
+	return 0
+}
+
+func (x *SimplifiedWeather) GetTempHigh() float64 {
+	if x != nil {
+		return x.TempHigh
+	}
+	return 0
+}
+
+func (x *SimplifiedWeather) GetTempLow() float64 {
+	if x != nil {
+		return x.TempLow
+	}
+	return 0
+}
+
+func (x *SimplifiedWeather) GetTempFeelsLike() float64 {
+	if x != nil {
+		return x.TempFeelsLike
+	}
+	return 0
+}
+
+func (x *SimplifiedWeather) GetExpectedWeather() string {
+	if x != nil {
+		return x.ExpectedWeather
+	}
+	return ""
+}
+
+func (x *SimplifiedWeather) GetWeatherDescription() string {
+	if x != nil {
+		return x.WeatherDescription
+	}
+	return ""
+}
+
+func (x *SimplifiedWeather) GetSubjectiveTemp() string {
+	if x != nil {
+		return x.SubjectiveTemp
+	}
+	return ""
+}
+
+func (x *SimplifiedWeather) GetSummary() string {
+	if x != nil {
+		return x.Summary
+	}
+	return ""
+}
+
+func (x *SimplifiedWeather) GetWindSpeed() float64 {
+	if x != nil {
+		return x.WindSpeed
+	}
+	return 0
+}
+
+func (x *SimplifiedWeather) GetWindGust() float64 {
+	if x != nil {
+		return x.WindGust
+	}
+	return 0
+}
+
+func (x *SimplifiedWeather) GetWindDirection() float64 {
+	if x != nil {
+		return x.WindDirection
+	}
+	return 0
+}
+
+func (x *SimplifiedWeather) GetWindUnit() string {
+	if x != nil {
+		return x.WindUnit
+	}
+	return ""
+}
+
+func (x *SimplifiedWeather) GetPressure() float64 {
+	if x != nil {
+		return x.Pressure
+	}
+	return 0
+}
+
+func (x *SimplifiedWeather) GetPressureUnit() string {
+	if x != nil {
+		return x.PressureUnit
+	}
+	return ""
+}
+
+func (x *SimplifiedWeather) GetVisibility() float64 {
+	if x != nil {
+		return x.Visibility
+	}
+	return 0
+}
+
+func (x *SimplifiedWeather) GetDistanceUnit() string {
+	if x != nil {
+		return x.DistanceUnit
+	}
+	return ""
+}
+
+func (x *SimplifiedWeather) GetRainLastHour() float64 {
+	if x != nil {
+		return x.RainLastHour
+	}
+	return 0
+}
+
+func (x *SimplifiedWeather) GetPrecipUnit() string {
+	if x != nil {
+		return x.PrecipUnit
+	}
+	return ""
+}
+
+func (x *SimplifiedWeather) GetDerived() *DerivedMetrics {
+	if x != nil {
+		return x.Derived
+	}
+	return nil
+}
+
+func (x *SimplifiedWeather) GetComfortProfile() string {
+	if x != nil {
+		return x.ComfortProfile
+	}
+	return ""
+}
+
+func (x *SimplifiedWeather) GetConditions() []string {
+	if x != nil {
+		return x.Conditions
+	}
+	return nil
+}
+
+func (x *SimplifiedWeather) GetTrends() *Trends {
+	if x != nil {
+		return x.Trends
+	}
+	return nil
+}
+
+// DerivedMetrics mirrors the derived section of /api/currentweather
+type DerivedMetrics struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	DewPoint  float64 `protobuf:"fixed64,1,opt,name=dew_point,json=dewPoint,proto3" json:"dew_point,omitempty"`
+	HeatIndex float64 `protobuf:"fixed64,2,opt,name=heat_index,json=heatIndex,proto3" json:"heat_index,omitempty"`
+	WindChill float64 `protobuf:"fixed64,3,opt,name=wind_chill,json=windChill,proto3" json:"wind_chill,omitempty"`
+	Humidex   float64 `protobuf:"fixed64,4,opt,name=humidex,proto3" json:"humidex,omitempty"`
+	// Grams of water vapor per cubic meter of air
+	AbsoluteHumidity float64 `protobuf:"fixed64,5,opt,name=absolute_humidity,json=absoluteHumidity,proto3" json:"absolute_humidity,omitempty"`
+}
+
+func (x *DerivedMetrics) Reset() {
+	*x = DerivedMetrics{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_weather_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DerivedMetrics) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DerivedMetrics) ProtoMessage() {}
+
+func (x *DerivedMetrics) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DerivedMetrics.ProtoReflect.Descriptor instead.
+func (*DerivedMetrics) Descriptor() ([]byte, []int) {
+	return file_weather_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DerivedMetrics) GetDewPoint() float64 {
+	if x != nil {
+		return x.DewPoint
+	}
+	return 0
+}
+
+func (x *DerivedMetrics) GetHeatIndex() float64 {
+	if x != nil {
+		return x.HeatIndex
+	}
+	return 0
+}
+
+func (x *DerivedMetrics) GetWindChill() float64 {
+	if x != nil {
+		return x.WindChill
+	}
+	return 0
+}
+
+func (x *DerivedMetrics) GetHumidex() float64 {
+	if x != nil {
+		return x.Humidex
+	}
+	return 0
+}
+
+func (x *DerivedMetrics) GetAbsoluteHumidity() float64 {
+	if x != nil {
+		return x.AbsoluteHumidity
+	}
+	return 0
+}
+
+// Trends mirrors the trends section of /api/currentweather.  The changes are left out
+// when there wasn't an observation around the right time.
+type Trends struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// risingFast, rising, steady, falling, or fallingFast over 3 hours
+	PressureTendency  string   `protobuf:"bytes,1,opt,name=pressure_tendency,json=pressureTendency,proto3" json:"pressure_tendency,omitempty"`
+	PressureChange_3H *float64 `protobuf:"fixed64,2,opt,name=pressure_change_3h,json=pressureChange3h,proto3,oneof" json:"pressure_change_3h,omitempty"`
+	TempChange_1H     *float64 `protobuf:"fixed64,3,opt,name=temp_change_1h,json=tempChange1h,proto3,oneof" json:"temp_change_1h,omitempty"`
+	TempChange_3H     *float64 `protobuf:"fixed64,4,opt,name=temp_change_3h,json=tempChange3h,proto3,oneof" json:"temp_change_3h,omitempty"`
+	TempChange_24H    *float64 `protobuf:"fixed64,5,opt,name=temp_change_24h,json=tempChange24h,proto3,oneof" json:"temp_change_24h,omitempty"`
+	// The mean temperature at this local hour on the previous days
+	TempBaseline *float64 `protobuf:"fixed64,6,opt,name=temp_baseline,json=tempBaseline,proto3,oneof" json:"temp_baseline,omitempty"`
+	// How much warmer (or colder when negative) it is than the baseline
+	TempAnomaly *float64 `protobuf:"fixed64,7,opt,name=temp_anomaly,json=tempAnomaly,proto3,oneof" json:"temp_anomaly,omitempty"`
+	// The number of days in the baseline
+	BaselineDays int32 `protobuf:"varint,8,opt,name=baseline_days,json=baselineDays,proto3" json:"baseline_days,omitempty"`
+}
+
+func (x *Trends) Reset() {
+	*x = Trends{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_weather_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Trends) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Trends) ProtoMessage() {}
+
+func (x *Trends) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Trends.ProtoReflect.Descriptor instead.
+func (*Trends) Descriptor() ([]byte, []int) {
+	return file_weather_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Trends) GetPressureTendency() string {
+	if x != nil {
+		return x.PressureTendency
+	}
+	return ""
+}
+
+func (x *Trends) GetPressureChange_3H() float64 {
+	if x != nil && x.PressureChange_3H != nil {
+		return *x.PressureChange_3H
+	}
+	return 0
+}
+
+func (x *Trends) GetTempChange_1H() float64 {
+	if x != nil && x.TempChange_1H != nil {
+		return *x.TempChange_1H
+	}
+	return 0
+}
+
+func (x *Trends) GetTempChange_3H() float64 {
+	if x != nil && x.TempChange_3H != nil {
+		return *x.TempChange_3H
+	}
+	return 0
+}
+
+func (x *Trends) GetTempChange_24H() float64 {
+	if x != nil && x.TempChange_24H != nil {
+		return *x.TempChange_24H
+	}
+	return 0
+}
+
+func (x *Trends) GetTempBaseline() float64 {
+	if x != nil && x.TempBaseline != nil {
+		return *x.TempBaseline
+	}
+	return 0
+}
+
+func (x *Trends) GetTempAnomaly() float64 {
+	if x != nil && x.TempAnomaly != nil {
+		return *x.TempAnomaly
+	}
+	return 0
+}
+
+func (x *Trends) GetBaselineDays() int32 {
+	if x != nil {
+		return x.BaselineDays
+	}
+	return 0
+}
+
+var File_weather_proto protoreflect.FileDescriptor
+
+var file_weather_proto_rawDesc = []byte{
+	0x0a, 0x0d, 0x77, 0x65, 0x61, 0x74, 0x68, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
+	0x07, 0x77, 0x65, 0x61, 0x74, 0x68, 0x65, 0x72, 0x22, 0xe1, 0x03, 0x0a, 0x18, 0x47, 0x65, 0x74,
+	0x43, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x57, 0x65, 0x61, 0x74, 0x68, 0x65, 0x72, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1f, 0x0a, 0x08, 0x6c, 0x61, 0x74, 0x69, 0x74, 0x75, 0x64,
+	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x48, 0x00, 0x52, 0x08, 0x6c, 0x61, 0x74, 0x69, 0x74,
+	0x75, 0x64, 0x65, 0x88, 0x01, 0x01, 0x12, 0x21, 0x0a, 0x09, 0x6c, 0x6f, 0x6e, 0x67, 0x69, 0x74,
+	0x75, 0x64, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x48, 0x01, 0x52, 0x09, 0x6c, 0x6f, 0x6e,
+	0x67, 0x69, 0x74, 0x75, 0x64, 0x65, 0x88, 0x01, 0x01, 0x12, 0x14, 0x0a, 0x05, 0x75, 0x6e, 0x69,
+	0x74, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x75, 0x6e, 0x69, 0x74, 0x73, 0x12,
+	0x1b, 0x0a, 0x09, 0x74, 0x65, 0x6d, 0x70, 0x5f, 0x75, 0x6e, 0x69, 0x74, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x74, 0x65, 0x6d, 0x70, 0x55, 0x6e, 0x69, 0x74, 0x12, 0x1b, 0x0a, 0x09,
+	0x77, 0x69, 0x6e, 0x64, 0x5f, 0x75, 0x6e, 0x69, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x08, 0x77, 0x69, 0x6e, 0x64, 0x55, 0x6e, 0x69, 0x74, 0x12, 0x23, 0x0a, 0x0d, 0x70, 0x72, 0x65,
+	0x73, 0x73, 0x75, 0x72, 0x65, 0x5f, 0x75, 0x6e, 0x69, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0c, 0x70, 0x72, 0x65, 0x73, 0x73, 0x75, 0x72, 0x65, 0x55, 0x6e, 0x69, 0x74, 0x12, 0x23,
+	0x0a, 0x0d, 0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x5f, 0x75, 0x6e, 0x69, 0x74, 0x18,
+	0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x55,
+	0x6e, 0x69, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x72, 0x65, 0x63, 0x69, 0x70, 0x5f, 0x75, 0x6e,
+	0x69, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x70, 0x72, 0x65, 0x63, 0x69, 0x70,
+	0x55, 0x6e, 0x69, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6c, 0x61, 0x6e, 0x67, 0x18, 0x09, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x6c, 0x61, 0x6e, 0x67, 0x12, 0x23, 0x0a, 0x0d, 0x73, 0x75, 0x6d, 0x6d,
+	0x61, 0x72, 0x79, 0x5f, 0x73, 0x74, 0x79, 0x6c, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0c, 0x73, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x53, 0x74, 0x79, 0x6c, 0x65, 0x12, 0x27, 0x0a,
+	0x0f, 0x63, 0x6f, 0x6d, 0x66, 0x6f, 0x72, 0x74, 0x5f, 0x70, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65,
+	0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x63, 0x6f, 0x6d, 0x66, 0x6f, 0x72, 0x74, 0x50,
+	0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x12, 0x24, 0x0a, 0x0e, 0x63, 0x6f, 0x6c, 0x64, 0x5f, 0x63,
+	0x6f, 0x6f, 0x6c, 0x5f, 0x77, 0x61, 0x72, 0x6d, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c,
+	0x63, 0x6f, 0x6c, 0x64, 0x43, 0x6f, 0x6f, 0x6c, 0x57, 0x61, 0x72, 0x6d, 0x12, 0x23, 0x0a, 0x0d,
+	0x74, 0x72, 0x65, 0x6e, 0x64, 0x5f, 0x73, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x18, 0x0d, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x0c, 0x74, 0x72, 0x65, 0x6e, 0x64, 0x53, 0x75, 0x6d, 0x6d, 0x61, 0x72,
+	0x79, 0x42, 0x0b, 0x0a, 0x09, 0x5f, 0x6c, 0x61, 0x74, 0x69, 0x74, 0x75, 0x64, 0x65, 0x42, 0x0c,
+	0x0a, 0x0a, 0x5f, 0x6c, 0x6f, 0x6e, 0x67, 0x69, 0x74, 0x75, 0x64, 0x65, 0x22, 0x51, 0x0a, 0x19,
+	0x47, 0x65, 0x74, 0x43, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x57, 0x65, 0x61, 0x74, 0x68, 0x65,
+	0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x34, 0x0a, 0x07, 0x77, 0x65, 0x61,
+	0x74, 0x68, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x77, 0x65, 0x61,
+	0x74, 0x68, 0x65, 0x72, 0x2e, 0x53, 0x69, 0x6d, 0x70, 0x6c, 0x69, 0x66, 0x69, 0x65, 0x64, 0x57,
+	0x65, 0x61, 0x74, 0x68, 0x65, 0x72, 0x52, 0x07, 0x77, 0x65, 0x61, 0x74, 0x68, 0x65, 0x72, 0x22,
+	0x5e, 0x0a, 0x1d, 0x42, 0x61, 0x74, 0x63, 0x68, 0x47, 0x65, 0x74, 0x43, 0x75, 0x72, 0x72, 0x65,
+	0x6e, 0x74, 0x57, 0x65, 0x61, 0x74, 0x68, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x12, 0x3d, 0x0a, 0x08, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x21, 0x2e, 0x77, 0x65, 0x61, 0x74, 0x68, 0x65, 0x72, 0x2e, 0x47, 0x65, 0x74,
+	0x43, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x57, 0x65, 0x61, 0x74, 0x68, 0x65, 0x72, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x52, 0x08, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73, 0x22,
+	0x96, 0x01, 0x0a, 0x1e, 0x42, 0x61, 0x74, 0x63, 0x68, 0x47, 0x65, 0x74, 0x43, 0x75, 0x72, 0x72,
+	0x65, 0x6e, 0x74, 0x57, 0x65, 0x61, 0x74, 0x68, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x34, 0x0a, 0x07, 0x77, 0x65, 0x61, 0x74,
+	0x68, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x77, 0x65, 0x61, 0x74,
+	0x68, 0x65, 0x72, 0x2e, 0x53, 0x69, 0x6d, 0x70, 0x6c, 0x69, 0x66, 0x69, 0x65, 0x64, 0x57, 0x65,
+	0x61, 0x74, 0x68, 0x65, 0x72, 0x52, 0x07, 0x77, 0x65, 0x61, 0x74, 0x68, 0x65, 0x72, 0x12, 0x12,
+	0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f,
+	0x64, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0xf4, 0x07, 0x0a, 0x11, 0x53, 0x69, 0x6d,
+	0x70, 0x6c, 0x69, 0x66, 0x69, 0x65, 0x64, 0x57, 0x65, 0x61, 0x74, 0x68, 0x65, 0x72, 0x12, 0x14,
+	0x0a, 0x05, 0x75, 0x6e, 0x69, 0x74, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x75,
+	0x6e, 0x69, 0x74, 0x73, 0x12, 0x30, 0x0a, 0x14, 0x64, 0x61, 0x74, 0x61, 0x5f, 0x63, 0x6f, 0x6c,
+	0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x12, 0x64, 0x61, 0x74, 0x61, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x6c, 0x61, 0x74, 0x69, 0x74, 0x75,
+	0x64, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x08, 0x6c, 0x61, 0x74, 0x69, 0x74, 0x75,
+	0x64, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x6c, 0x6f, 0x6e, 0x67, 0x69, 0x74, 0x75, 0x64, 0x65, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x09, 0x6c, 0x6f, 0x6e, 0x67, 0x69, 0x74, 0x75, 0x64, 0x65,
+	0x12, 0x2d, 0x0a, 0x12, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x69, 0x6e, 0x65, 0x73, 0x73, 0x5f, 0x70,
+	0x65, 0x72, 0x63, 0x65, 0x6e, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x01, 0x52, 0x11, 0x63, 0x6c,
+	0x6f, 0x75, 0x64, 0x69, 0x6e, 0x65, 0x73, 0x73, 0x50, 0x65, 0x72, 0x63, 0x65, 0x6e, 0x74, 0x12,
+	0x29, 0x0a, 0x10, 0x68, 0x75, 0x6d, 0x69, 0x64, 0x69, 0x74, 0x79, 0x5f, 0x70, 0x65, 0x72, 0x63,
+	0x65, 0x6e, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0f, 0x68, 0x75, 0x6d, 0x69, 0x64,
+	0x69, 0x74, 0x79, 0x50, 0x65, 0x72, 0x63, 0x65, 0x6e, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x65,
+	0x6d, 0x70, 0x18, 0x07, 0x20, 0x01, 0x28, 0x01, 0x52, 0x04, 0x74, 0x65, 0x6d, 0x70, 0x12, 0x1b,
+	0x0a, 0x09, 0x74, 0x65, 0x6d, 0x70, 0x5f, 0x68, 0x69, 0x67, 0x68, 0x18, 0x08, 0x20, 0x01, 0x28,
+	0x01, 0x52, 0x08, 0x74, 0x65, 0x6d, 0x70, 0x48, 0x69, 0x67, 0x68, 0x12, 0x19, 0x0a, 0x08, 0x74,
+	0x65, 0x6d, 0x70, 0x5f, 0x6c, 0x6f, 0x77, 0x18, 0x09, 0x20, 0x01, 0x28, 0x01, 0x52, 0x07, 0x74,
+	0x65, 0x6d, 0x70, 0x4c, 0x6f, 0x77, 0x12, 0x26, 0x0a, 0x0f, 0x74, 0x65, 0x6d, 0x70, 0x5f, 0x66,
+	0x65, 0x65, 0x6c, 0x73, 0x5f, 0x6c, 0x69, 0x6b, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x01, 0x52,
+	0x0d, 0x74, 0x65, 0x6d, 0x70, 0x46, 0x65, 0x65, 0x6c, 0x73, 0x4c, 0x69, 0x6b, 0x65, 0x12, 0x29,
+	0x0a, 0x10, 0x65, 0x78, 0x70, 0x65, 0x63, 0x74, 0x65, 0x64, 0x5f, 0x77, 0x65, 0x61, 0x74, 0x68,
+	0x65, 0x72, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x65, 0x78, 0x70, 0x65, 0x63, 0x74,
+	0x65, 0x64, 0x57, 0x65, 0x61, 0x74, 0x68, 0x65, 0x72, 0x12, 0x2f, 0x0a, 0x13, 0x77, 0x65, 0x61,
+	0x74, 0x68, 0x65, 0x72, 0x5f, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e,
+	0x18, 0x0c, 0x20, 0x01, 0x28, 0x09, 0x52, 0x12, 0x77, 0x65, 0x61, 0x74, 0x68, 0x65, 0x72, 0x44,
+	0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x27, 0x0a, 0x0f, 0x73, 0x75,
+	0x62, 0x6a, 0x65, 0x63, 0x74, 0x69, 0x76, 0x65, 0x5f, 0x74, 0x65, 0x6d, 0x70, 0x18, 0x0d, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0e, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x69, 0x76, 0x65, 0x54,
+	0x65, 0x6d, 0x70, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x18, 0x0e,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x73, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x12, 0x1d, 0x0a,
+	0x0a, 0x77, 0x69, 0x6e, 0x64, 0x5f, 0x73, 0x70, 0x65, 0x65, 0x64, 0x18, 0x0f, 0x20, 0x01, 0x28,
+	0x01, 0x52, 0x09, 0x77, 0x69, 0x6e, 0x64, 0x53, 0x70, 0x65, 0x65, 0x64, 0x12, 0x1b, 0x0a, 0x09,
+	0x77, 0x69, 0x6e, 0x64, 0x5f, 0x67, 0x75, 0x73, 0x74, 0x18, 0x10, 0x20, 0x01, 0x28, 0x01, 0x52,
+	0x08, 0x77, 0x69, 0x6e, 0x64, 0x47, 0x75, 0x73, 0x74, 0x12, 0x25, 0x0a, 0x0e, 0x77, 0x69, 0x6e,
+	0x64, 0x5f, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x11, 0x20, 0x01, 0x28,
+	0x01, 0x52, 0x0d, 0x77, 0x69, 0x6e, 0x64, 0x44, 0x69, 0x72, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x12, 0x1b, 0x0a, 0x09, 0x77, 0x69, 0x6e, 0x64, 0x5f, 0x75, 0x6e, 0x69, 0x74, 0x18, 0x12, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x77, 0x69, 0x6e, 0x64, 0x55, 0x6e, 0x69, 0x74, 0x12, 0x1a, 0x0a,
+	0x08, 0x70, 0x72, 0x65, 0x73, 0x73, 0x75, 0x72, 0x65, 0x18, 0x13, 0x20, 0x01, 0x28, 0x01, 0x52,
+	0x08, 0x70, 0x72, 0x65, 0x73, 0x73, 0x75, 0x72, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x70, 0x72, 0x65,
+	0x73, 0x73, 0x75, 0x72, 0x65, 0x5f, 0x75, 0x6e, 0x69, 0x74, 0x18, 0x14, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0c, 0x70, 0x72, 0x65, 0x73, 0x73, 0x75, 0x72, 0x65, 0x55, 0x6e, 0x69, 0x74, 0x12, 0x1e,
+	0x0a, 0x0a, 0x76, 0x69, 0x73, 0x69, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x18, 0x15, 0x20, 0x01,
+	0x28, 0x01, 0x52, 0x0a, 0x76, 0x69, 0x73, 0x69, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x12, 0x23,
+	0x0a, 0x0d, 0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x5f, 0x75, 0x6e, 0x69, 0x74, 0x18,
+	0x16, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x55,
+	0x6e, 0x69, 0x74, 0x12, 0x24, 0x0a, 0x0e, 0x72, 0x61, 0x69, 0x6e, 0x5f, 0x6c, 0x61, 0x73, 0x74,
+	0x5f, 0x68, 0x6f, 0x75, 0x72, 0x18, 0x17, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0c, 0x72, 0x61, 0x69,
+	0x6e, 0x4c, 0x61, 0x73, 0x74, 0x48, 0x6f, 0x75, 0x72, 0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x72, 0x65,
+	0x63, 0x69, 0x70, 0x5f, 0x75, 0x6e, 0x69, 0x74, 0x18, 0x18, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a,
+	0x70, 0x72, 0x65, 0x63, 0x69, 0x70, 0x55, 0x6e, 0x69, 0x74, 0x12, 0x31, 0x0a, 0x07, 0x64, 0x65,
+	0x72, 0x69, 0x76, 0x65, 0x64, 0x18, 0x19, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x77, 0x65,
+	0x61, 0x74, 0x68, 0x65, 0x72, 0x2e, 0x44, 0x65, 0x72, 0x69, 0x76, 0x65, 0x64, 0x4d, 0x65, 0x74,
+	0x72, 0x69, 0x63, 0x73, 0x52, 0x07, 0x64, 0x65, 0x72, 0x69, 0x76, 0x65, 0x64, 0x12, 0x27, 0x0a,
+	0x0f, 0x63, 0x6f, 0x6d, 0x66, 0x6f, 0x72, 0x74, 0x5f, 0x70, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65,
+	0x18, 0x1a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x63, 0x6f, 0x6d, 0x66, 0x6f, 0x72, 0x74, 0x50,
+	0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x63, 0x6f, 0x6e, 0x64, 0x69, 0x74,
+	0x69, 0x6f, 0x6e, 0x73, 0x18, 0x1b, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0a, 0x63, 0x6f, 0x6e, 0x64,
+	0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x27, 0x0a, 0x06, 0x74, 0x72, 0x65, 0x6e, 0x64, 0x73,
+	0x18, 0x1c, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x77, 0x65, 0x61, 0x74, 0x68, 0x65, 0x72,
+	0x2e, 0x54, 0x72, 0x65, 0x6e, 0x64, 0x73, 0x52, 0x06, 0x74, 0x72, 0x65, 0x6e, 0x64, 0x73, 0x22,
+	0xb2, 0x01, 0x0a, 0x0e, 0x44, 0x65, 0x72, 0x69, 0x76, 0x65, 0x64, 0x4d, 0x65, 0x74, 0x72, 0x69,
+	0x63, 0x73, 0x12, 0x1b, 0x0a, 0x09, 0x64, 0x65, 0x77, 0x5f, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x01, 0x52, 0x08, 0x64, 0x65, 0x77, 0x50, 0x6f, 0x69, 0x6e, 0x74, 0x12,
+	0x1d, 0x0a, 0x0a, 0x68, 0x65, 0x61, 0x74, 0x5f, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x01, 0x52, 0x09, 0x68, 0x65, 0x61, 0x74, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x1d,
+	0x0a, 0x0a, 0x77, 0x69, 0x6e, 0x64, 0x5f, 0x63, 0x68, 0x69, 0x6c, 0x6c, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x01, 0x52, 0x09, 0x77, 0x69, 0x6e, 0x64, 0x43, 0x68, 0x69, 0x6c, 0x6c, 0x12, 0x18, 0x0a,
+	0x07, 0x68, 0x75, 0x6d, 0x69, 0x64, 0x65, 0x78, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x07,
+	0x68, 0x75, 0x6d, 0x69, 0x64, 0x65, 0x78, 0x12, 0x2b, 0x0a, 0x11, 0x61, 0x62, 0x73, 0x6f, 0x6c,
+	0x75, 0x74, 0x65, 0x5f, 0x68, 0x75, 0x6d, 0x69, 0x64, 0x69, 0x74, 0x79, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x01, 0x52, 0x10, 0x61, 0x62, 0x73, 0x6f, 0x6c, 0x75, 0x74, 0x65, 0x48, 0x75, 0x6d, 0x69,
+	0x64, 0x69, 0x74, 0x79, 0x22, 0xd6, 0x03, 0x0a, 0x06, 0x54, 0x72, 0x65, 0x6e, 0x64, 0x73, 0x12,
+	0x2b, 0x0a, 0x11, 0x70, 0x72, 0x65, 0x73, 0x73, 0x75, 0x72, 0x65, 0x5f, 0x74, 0x65, 0x6e, 0x64,
+	0x65, 0x6e, 0x63, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x10, 0x70, 0x72, 0x65, 0x73,
+	0x73, 0x75, 0x72, 0x65, 0x54, 0x65, 0x6e, 0x64, 0x65, 0x6e, 0x63, 0x79, 0x12, 0x31, 0x0a, 0x12,
+	0x70, 0x72, 0x65, 0x73, 0x73, 0x75, 0x72, 0x65, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x5f,
+	0x33, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x48, 0x00, 0x52, 0x10, 0x70, 0x72, 0x65, 0x73,
+	0x73, 0x75, 0x72, 0x65, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x33, 0x68, 0x88, 0x01, 0x01, 0x12,
+	0x29, 0x0a, 0x0e, 0x74, 0x65, 0x6d, 0x70, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x5f, 0x31,
+	0x68, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x48, 0x01, 0x52, 0x0c, 0x74, 0x65, 0x6d, 0x70, 0x43,
+	0x68, 0x61, 0x6e, 0x67, 0x65, 0x31, 0x68, 0x88, 0x01, 0x01, 0x12, 0x29, 0x0a, 0x0e, 0x74, 0x65,
+	0x6d, 0x70, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x5f, 0x33, 0x68, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x01, 0x48, 0x02, 0x52, 0x0c, 0x74, 0x65, 0x6d, 0x70, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65,
+	0x33, 0x68, 0x88, 0x01, 0x01, 0x12, 0x2b, 0x0a, 0x0f, 0x74, 0x65, 0x6d, 0x70, 0x5f, 0x63, 0x68,
+	0x61, 0x6e, 0x67, 0x65, 0x5f, 0x32, 0x34, 0x68, 0x18, 0x05, 0x20, 0x01, 0x28, 0x01, 0x48, 0x03,
+	0x52, 0x0d, 0x74, 0x65, 0x6d, 0x70, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x32, 0x34, 0x68, 0x88,
+	0x01, 0x01, 0x12, 0x28, 0x0a, 0x0d, 0x74, 0x65, 0x6d, 0x70, 0x5f, 0x62, 0x61, 0x73, 0x65, 0x6c,
+	0x69, 0x6e, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x01, 0x48, 0x04, 0x52, 0x0c, 0x74, 0x65, 0x6d,
+	0x70, 0x42, 0x61, 0x73, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x88, 0x01, 0x01, 0x12, 0x26, 0x0a, 0x0c,
+	0x74, 0x65, 0x6d, 0x70, 0x5f, 0x61, 0x6e, 0x6f, 0x6d, 0x61, 0x6c, 0x79, 0x18, 0x07, 0x20, 0x01,
+	0x28, 0x01, 0x48, 0x05, 0x52, 0x0b, 0x74, 0x65, 0x6d, 0x70, 0x41, 0x6e, 0x6f, 0x6d, 0x61, 0x6c,
+	0x79, 0x88, 0x01, 0x01, 0x12, 0x23, 0x0a, 0x0d, 0x62, 0x61, 0x73, 0x65, 0x6c, 0x69, 0x6e, 0x65,
+	0x5f, 0x64, 0x61, 0x79, 0x73, 0x18, 0x08, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0c, 0x62, 0x61, 0x73,
+	0x65, 0x6c, 0x69, 0x6e, 0x65, 0x44, 0x61, 0x79, 0x73, 0x42, 0x15, 0x0a, 0x13, 0x5f, 0x70, 0x72,
+	0x65, 0x73, 0x73, 0x75, 0x72, 0x65, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x5f, 0x33, 0x68,
+	0x42, 0x11, 0x0a, 0x0f, 0x5f, 0x74, 0x65, 0x6d, 0x70, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65,
+	0x5f, 0x31, 0x68, 0x42, 0x11, 0x0a, 0x0f, 0x5f, 0x74, 0x65, 0x6d, 0x70, 0x5f, 0x63, 0x68, 0x61,
+	0x6e, 0x67, 0x65, 0x5f, 0x33, 0x68, 0x42, 0x12, 0x0a, 0x10, 0x5f, 0x74, 0x65, 0x6d, 0x70, 0x5f,
+	0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x5f, 0x32, 0x34, 0x68, 0x42, 0x10, 0x0a, 0x0e, 0x5f, 0x74,
+	0x65, 0x6d, 0x70, 0x5f, 0x62, 0x61, 0x73, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x42, 0x0f, 0x0a, 0x0d,
+	0x5f, 0x74, 0x65, 0x6d, 0x70, 0x5f, 0x61, 0x6e, 0x6f, 0x6d, 0x61, 0x6c, 0x79, 0x32, 0xd9, 0x01,
+	0x0a, 0x0e, 0x57, 0x65, 0x61, 0x74, 0x68, 0x65, 0x72, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
+	0x12, 0x5a, 0x0a, 0x11, 0x47, 0x65, 0x74, 0x43, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x57, 0x65,
+	0x61, 0x74, 0x68, 0x65, 0x72, 0x12, 0x21, 0x2e, 0x77, 0x65, 0x61, 0x74, 0x68, 0x65, 0x72, 0x2e,
+	0x47, 0x65, 0x74, 0x43, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x57, 0x65, 0x61, 0x74, 0x68, 0x65,
+	0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x22, 0x2e, 0x77, 0x65, 0x61, 0x74, 0x68,
+	0x65, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x43, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x57, 0x65, 0x61,
+	0x74, 0x68, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x6b, 0x0a, 0x16,
+	0x42, 0x61, 0x74, 0x63, 0x68, 0x47, 0x65, 0x74, 0x43, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x57,
+	0x65, 0x61, 0x74, 0x68, 0x65, 0x72, 0x12, 0x26, 0x2e, 0x77, 0x65, 0x61, 0x74, 0x68, 0x65, 0x72,
+	0x2e, 0x42, 0x61, 0x74, 0x63, 0x68, 0x47, 0x65, 0x74, 0x43, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74,
+	0x57, 0x65, 0x61, 0x74, 0x68, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x27,
+	0x2e, 0x77, 0x65, 0x61, 0x74, 0x68, 0x65, 0x72, 0x2e, 0x42, 0x61, 0x74, 0x63, 0x68, 0x47, 0x65,
+	0x74, 0x43, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x57, 0x65, 0x61, 0x74, 0x68, 0x65, 0x72, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x42, 0x22, 0x5a, 0x20, 0x63, 0x75, 0x72,
+	0x72, 0x65, 0x6e, 0x74, 0x2d, 0x77, 0x65, 0x61, 0x74, 0x68, 0x65, 0x72, 0x2d, 0x73, 0x65, 0x72,
+	0x76, 0x65, 0x72, 0x2f, 0x77, 0x65, 0x61, 0x74, 0x68, 0x65, 0x72, 0x70, 0x62, 0x62, 0x06, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_weather_proto_rawDescOnce sync.Once
+	file_weather_proto_rawDescData = file_weather_proto_rawDesc
+)
+
+func file_weather_proto_rawDescGZIP() []byte {
+	file_weather_proto_rawDescOnce.Do(func() {
+		file_weather_proto_rawDescData = protoimpl.X.CompressGZIP(file_weather_proto_rawDescData)
+	})
+	return file_weather_proto_rawDescData
+}
+
+var file_weather_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_weather_proto_goTypes = []any{
+	(*GetCurrentWeatherRequest)(nil),       // 0: weather.GetCurrentWeatherRequest
+	(*GetCurrentWeatherResponse)(nil),      // 1: weather.GetCurrentWeatherResponse
+	(*BatchGetCurrentWeatherRequest)(nil),  // 2: weather.BatchGetCurrentWeatherRequest
+	(*BatchGetCurrentWeatherResponse)(nil), // 3: weather.BatchGetCurrentWeatherResponse
+	(*SimplifiedWeather)(nil),              // 4: weather.SimplifiedWeather
+	(*DerivedMetrics)(nil),                 // 5: weather.DerivedMetrics
+	(*Trends)(nil),                         // 6: weather.Trends
+}
+var file_weather_proto_depIdxs = []int32{
+	4, // 0: weather.GetCurrentWeatherResponse.weather:type_name -> weather.SimplifiedWeather
+	0, // 1: weather.BatchGetCurrentWeatherRequest.requests:type_name -> weather.GetCurrentWeatherRequest
+	4, // 2: weather.BatchGetCurrentWeatherResponse.weather:type_name -> weather.SimplifiedWeather
+	5, // 3: weather.SimplifiedWeather.derived:type_name -> weather.DerivedMetrics
+	6, // 4: weather.SimplifiedWeather.trends:type_name -> weather.Trends
+	0, // 5: weather.WeatherService.GetCurrentWeather:input_type -> weather.GetCurrentWeatherRequest
+	2, // 6: weather.WeatherService.BatchGetCurrentWeather:input_type -> weather.BatchGetCurrentWeatherRequest
+	1, // 7: weather.WeatherService.GetCurrentWeather:output_type -> weather.GetCurrentWeatherResponse
+	3, // 8: weather.WeatherService.BatchGetCurrentWeather:output_type -> weather.BatchGetCurrentWeatherResponse
+	7, // [7:9] is the sub-list for method output_type
+	5, // [5:7] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_weather_proto_init() }
+func file_weather_proto_init() {
+	if File_weather_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_weather_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*GetCurrentWeatherRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_weather_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*GetCurrentWeatherResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_weather_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*BatchGetCurrentWeatherRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_weather_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*BatchGetCurrentWeatherResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_weather_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*SimplifiedWeather); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_weather_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*DerivedMetrics); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_weather_proto_msgTypes[6].Exporter = func(v any, i int) any {
+			switch v := v.(*Trends); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_weather_proto_msgTypes[0].OneofWrappers = []any{}
+	file_weather_proto_msgTypes[6].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_weather_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_weather_proto_goTypes,
+		DependencyIndexes: file_weather_proto_depIdxs,
+		MessageInfos:      file_weather_proto_msgTypes,
+	}.Build()
+	File_weather_proto = out.File
+	file_weather_proto_rawDesc = nil
+	file_weather_proto_goTypes = nil
+	file_weather_proto_depIdxs = nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"current-weather-server/data"
+	"current-weather-server/history"
 	"current-weather-server/logging"
 	"encoding/json"
 	"errors"
@@ -10,9 +11,11 @@ import (
 	"html/template"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -32,8 +35,10 @@ func getNextRequestNumber() uint64 {
 	return requestNumber
 }
 
-// The templates used to serve files
-var templates *template.Template
+// The templates used to serve files.  They're parsed once per language
+// with the t (translate), num (format number), and lang functions bound to the
+// language's catalog.
+var templates map[string]*template.Template
 
 func init() {
 	defer func() {
@@ -43,7 +48,31 @@ func init() {
 		}
 	}()
 
-	templates = template.Must(template.New("templateFiles").Parse(data.TEMPLATE_FILES))
+	templates = map[string]*template.Template{}
+
+	for _, lang := range data.SupportedLanguages() {
+		catalog := data.CatalogFor(lang)
+		funcs := template.FuncMap{
+			"t":    catalog.Text,
+			"num":  catalog.FormatNumber,
+			"list": catalog.TextList,
+			"lang": func() string { return catalog.Lang },
+		}
+		templates[lang] = template.Must(template.New("templateFiles").Funcs(funcs).Parse(data.TEMPLATE_FILES))
+	}
+}
+
+// pageTemplates returns the templates in the language of the request and sets the
+// Content-Language header.  Pages fall back to the default language if lang is invalid.
+func pageTemplates(writer http.ResponseWriter, request *http.Request) *template.Template {
+	lang, err, _ := requestLanguage(request)
+
+	if err != nil {
+		lang = data.DefaultLanguage
+	}
+
+	writer.Header().Set("Content-Language", lang)
+	return templates[lang]
 }
 
 func unixEpochTimeToString(epochTime int64) string {
@@ -51,14 +80,54 @@ func unixEpochTimeToString(epochTime int64) string {
 }
 
 func versionHandler(requestNum uint64, writer http.ResponseWriter, request *http.Request) {
-	templates.ExecuteTemplate(writer, "version_page", VERSION)
+	pageTemplates(writer, request).ExecuteTemplate(writer, "version_page", VERSION)
 }
 
 func getCurrentWeatherForm(requestNum uint64, writer http.ResponseWriter, request *http.Request) {
-	templates.ExecuteTemplate(writer, "get_longitude_latitude", "")
+	pageTemplates(writer, request).ExecuteTemplate(writer, "get_longitude_latitude", "")
+}
+
+// getFieldSelection reads the optional fields and compact parameters
+// that limit which SimplifiedWeather fields are returned and how they're named.
+func getFieldSelection(request *http.Request) (*data.FieldSelection, error, int) {
+	queryValues := request.URL.Query()
+	compact := false
+
+	if compactStr := compactParam.value(queryValues); compactStr != "" {
+		var err error
+		compact, err = strconv.ParseBool(compactStr)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid compact value: %v", compactStr), http.StatusBadRequest
+		}
+	}
+
+	selection, err := data.ParseFieldSelection(fieldsParam.value(queryValues), compact)
+
+	if err != nil {
+		return nil, err, http.StatusBadRequest
+	}
+
+	return selection, nil, http.StatusOK
 }
 
 func apiGetCurrentWeather(requestNum uint64, writer http.ResponseWriter, request *http.Request) {
+	selection, err, statusCode := getFieldSelection(request)
+
+	if err != nil {
+		logging.LogHTTPError(requestNum, err.Error(), statusCode)
+		http.Error(writer, err.Error(), statusCode)
+		return
+	}
+
+	geoJSON, err, statusCode := wantsGeoJSON(request)
+
+	if err != nil {
+		logging.LogHTTPError(requestNum, err.Error(), statusCode)
+		http.Error(writer, err.Error(), statusCode)
+		return
+	}
+
 	_, simplifiedData, err, statusCode := getCurrentWeather(request)
 
 	if err != nil {
@@ -73,7 +142,16 @@ func apiGetCurrentWeather(requestNum uint64, writer http.ResponseWriter, request
 		return
 	}
 
-	jsonBytes, err := json.Marshal(simplifiedData)
+	// The language has already been validated by getCurrentWeather
+	lang, _, _ := requestLanguage(request)
+	writer.Header().Set("Content-Language", lang)
+
+	if geoJSON {
+		writeGeoJson(requestNum, writer, data.NewWeatherFeature(simplifiedData, selection), http.StatusOK)
+		return
+	}
+
+	jsonBytes, err := json.Marshal(selection.Select(simplifiedData))
 
 	if err != nil {
 		msg := fmt.Sprintf("Error marshing response: %v", err)
@@ -85,44 +163,242 @@ func apiGetCurrentWeather(requestNum uint64, writer http.ResponseWriter, request
 	writer.Write(jsonBytes)
 }
 
+// weatherQuery holds the validated parameters of a request for the current weather.
+// It's shared by the HTTP and gRPC APIs so both validate requests the same way.
+type weatherQuery struct {
+	Latitude     float64
+	Longitude    float64
+	Units        data.UnitSystem
+	Lang         string              // the language of the descriptions and summary
+	SummaryStyle string              // the operator defined template used for the summary
+	Comfort      data.ComfortProfile // the subjective temperature scale used for subjectiveTemp
+	TrendSummary bool                // whether the default summary mentions the notable trends
+}
+
+// queryOptions are the options besides the location accepted by all the APIs.
+// Units picks one of Open Weather's unit systems (metric, imperial, or standard)
+// and the other unit options replace the unit of a single quantity.
+type queryOptions struct {
+	Units        string `json:"units,omitempty"`
+	TempUnit     string `json:"tempUnit,omitempty"`
+	WindUnit     string `json:"windUnit,omitempty"`
+	PressureUnit string `json:"pressureUnit,omitempty"`
+	DistanceUnit string `json:"distanceUnit,omitempty"`
+	PrecipUnit   string `json:"precipUnit,omitempty"`
+	Lang         string `json:"lang,omitempty"`
+	SummaryStyle string `json:"summaryStyle,omitempty"`
+	// ComfortProfile names an operator defined profile.  ColdCoolWarm gives the
+	// temperatures instead (e.g. "40,60,77F").  Only one of them can be given.
+	ComfortProfile string `json:"comfortProfile,omitempty"`
+	ColdCoolWarm   string `json:"coldCoolWarm,omitempty"`
+	TrendSummary   string `json:"trendSummary,omitempty"` // true or false
+}
+
+// queryOptionsFromQuery reads the options from the query parameters
+func queryOptionsFromQuery(queryValues url.Values) queryOptions {
+	return queryOptions{
+		Units:          unitsParam.value(queryValues),
+		TempUnit:       tempUnitParam.value(queryValues),
+		WindUnit:       windUnitParam.value(queryValues),
+		PressureUnit:   pressureUnitParam.value(queryValues),
+		DistanceUnit:   distanceUnitParam.value(queryValues),
+		PrecipUnit:     precipUnitParam.value(queryValues),
+		Lang:           langParam.value(queryValues),
+		SummaryStyle:   summaryStyleParam.value(queryValues),
+		ComfortProfile: comfortProfileParam.value(queryValues),
+		ColdCoolWarm:   coldCoolWarmParam.value(queryValues),
+		TrendSummary:   trendSummaryParam.value(queryValues),
+	}
+}
+
+// unitSystem validates the unit options.  The default is metric.
+func (options queryOptions) unitSystem() (data.UnitSystem, error, int) {
+	units, err := data.ParseUnitSystem(options.Units, options.TempUnit, options.WindUnit,
+		options.PressureUnit, options.DistanceUnit, options.PrecipUnit)
+
+	if err != nil {
+		return data.UnitSystem{}, err, http.StatusBadRequest
+	}
+
+	return units, nil, http.StatusOK
+}
+
+// comfortProfile validates the comfort options.  Temperatures in coldCoolWarm
+// without a unit are in the temperature unit of the response.
+func (options queryOptions) comfortProfile(temperatureUnit data.TemperatureUnit) (data.ComfortProfile, error, int) {
+	if options.ColdCoolWarm != "" {
+		if options.ComfortProfile != "" {
+			return data.ComfortProfile{}, fmt.Errorf("Only one of %v and %v can be given",
+				comfortProfileParam.Name, coldCoolWarmParam.Name), http.StatusBadRequest
+		}
+
+		profile, err := data.ParseComfortTemperatures(data.CustomComfortProfile, options.ColdCoolWarm, temperatureUnit)
+
+		if err != nil {
+			return data.ComfortProfile{}, err, http.StatusBadRequest
+		}
+
+		return profile, nil, http.StatusOK
+	}
+
+	profile, err := data.LookupComfortProfile(options.ComfortProfile)
+
+	if err != nil {
+		return data.ComfortProfile{}, err, http.StatusBadRequest
+	}
+
+	return profile, nil, http.StatusOK
+}
+
+// writeJson writes the value as a json response
+func writeJson(requestNum uint64, writer http.ResponseWriter, value interface{}, statusCode int) {
+	writeJsonAs(requestNum, writer, "application/json", value, statusCode)
+}
+
+// writeJsonAs writes a json response with a json based Content-Type (e.g. GeoJSON)
+func writeJsonAs(requestNum uint64, writer http.ResponseWriter, contentType string, value interface{}, statusCode int) {
+	jsonBytes, err := json.Marshal(value)
+
+	if err != nil {
+		msg := fmt.Sprintf("Error marshing response: %v", err)
+		logging.LogError(requestNum, msg)
+		http.Error(writer, msg, http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", contentType)
+	writer.WriteHeader(statusCode)
+	writer.Write(jsonBytes)
+}
+
 func getCurrentWeather(request *http.Request) (*data.CurrentWeatherData, *data.SimplifiedWeather, error, int) {
+	query, err, statusCode := parseWeatherQuery(request)
+
+	if err != nil {
+		return nil, nil, err, statusCode
+	}
+
+	return fetchCurrentWeather(query)
+}
+
+// parseWeatherQuery validates the longitude, latitude and option query parameters.
+// The language is picked from the Accept-Language header if there's no lang parameter.
+func parseWeatherQuery(request *http.Request) (*weatherQuery, error, int) {
 	queryValues := request.URL.Query()
-	longitudeStr := queryValues.Get("longitude")
-	latitudeStr := queryValues.Get("latitude")
-	units := queryValues.Get("units")
+	longitudeStr := longitudeParam.value(queryValues)
+	latitudeStr := latitudeParam.value(queryValues)
+
+	options := queryOptionsFromQuery(queryValues)
 
-	switch units {
-	case "metric": // celsius, meters/sec
-	case "imperial": // fahrenheit, miles/hour
-	case "standard": // kelvin, meters/sec
-	case "":
-		units = "metric" // celsius, meters/sec
-	default:
-		return nil, nil, fmt.Errorf("Invalid units value: %v", units), http.StatusBadRequest
+	if options.Lang == "" {
+		options.Lang = acceptLanguage(request.Header.Get("Accept-Language"))
 	}
 
 	if longitudeStr == "" {
-		return nil, nil, errors.New("missing longitude"), http.StatusBadRequest
+		return nil, errors.New("missing longitude"), http.StatusBadRequest
 	}
 
 	if latitudeStr == "" {
-		return nil, nil, errors.New("missing latitude"), http.StatusBadRequest
+		return nil, errors.New("missing latitude"), http.StatusBadRequest
 	}
 
 	longitude, err := strconv.ParseFloat(longitudeStr, 64)
 
-	if err != nil || longitude < -180 || longitude > 180 {
-		return nil, nil, fmt.Errorf("Invalid longitude value: %v", longitudeStr), http.StatusBadRequest
+	if err != nil {
+		return nil, fmt.Errorf("Invalid longitude value: %v", longitudeStr), http.StatusBadRequest
 	}
 
 	latitude, err := strconv.ParseFloat(latitudeStr, 64)
 
-	if err != nil || latitude < -90 || latitude > 90 {
-		return nil, nil, fmt.Errorf("Invalid latitude value: %v", latitudeStr), http.StatusBadRequest
+	if err != nil {
+		return nil, fmt.Errorf("Invalid latitude value: %v", latitudeStr), http.StatusBadRequest
 	}
 
-	requestStr := fmt.Sprintf("https://api.openweathermap.org/data/2.5/weather?lat=%v&lon=%v&appid=%v&units=%v",
-		latitude, longitude, openWeatherApiKey, units)
+	return newWeatherQuery(latitude, longitude, options)
+}
+
+// newWeatherQuery validates a location and its options.  It's used for the HTTP query parameters
+// and for locations that didn't come from query parameters (gRPC, GraphQL, etc.) so they're all
+// validated the same way.  Empty options get their defaults.
+func newWeatherQuery(latitude, longitude float64, options queryOptions) (*weatherQuery, error, int) {
+	units, err, statusCode := options.unitSystem()
+
+	if err != nil {
+		return nil, err, statusCode
+	}
+
+	lang, err, statusCode := validateLanguage(options.Lang)
+
+	if err != nil {
+		return nil, err, statusCode
+	}
+
+	summaryStyle, err := data.ValidateSummaryStyle(options.SummaryStyle)
+
+	if err != nil {
+		return nil, err, http.StatusBadRequest
+	}
+
+	comfort, err, statusCode := options.comfortProfile(units.Temperature)
+
+	if err != nil {
+		return nil, err, statusCode
+	}
+
+	trendSummary := false
+
+	if options.TrendSummary != "" {
+		if trendSummary, err = strconv.ParseBool(options.TrendSummary); err != nil {
+			return nil, fmt.Errorf("Invalid trendSummary value: %v", options.TrendSummary), http.StatusBadRequest
+		}
+	}
+
+	if !validLongitude(longitude) {
+		return nil, fmt.Errorf("Invalid longitude value: %v", longitude), http.StatusBadRequest
+	}
+
+	if !validLatitude(latitude) {
+		return nil, fmt.Errorf("Invalid latitude value: %v", latitude), http.StatusBadRequest
+	}
+
+	return &weatherQuery{Latitude: latitude, Longitude: longitude, Units: units, Lang: lang,
+		SummaryStyle: summaryStyle, Comfort: comfort, TrendSummary: trendSummary}, nil, http.StatusOK
+}
+
+func validLongitude(longitude float64) bool {
+	return longitude >= -180 && longitude <= 180
+}
+
+func validLatitude(latitude float64) bool {
+	return latitude >= -90 && latitude <= 90
+}
+
+// fetchCurrentWeather calls Open Weather for an already validated query.
+// The weather is always fetched in metric units and converted to the query's units.
+func fetchCurrentWeather(query *weatherQuery) (*data.CurrentWeatherData, *data.SimplifiedWeather, error, int) {
+	currentWeatherDate, trends, err, statusCode := fetchMetricWeather(query)
+
+	if err != nil {
+		return nil, nil, err, statusCode
+	}
+
+	simplifiedData, err := convertForQuery(query, currentWeatherDate, trends)
+
+	if err != nil {
+		return nil, nil, err, http.StatusInternalServerError
+	}
+
+	return currentWeatherDate, simplifiedData, nil, http.StatusOK
+}
+
+// fetchMetricWeather calls Open Weather for the location and language of a query, records the
+// observation, and returns it with its trends, both still in metric units.  Only the location
+// and language of the query are used.
+func fetchMetricWeather(query *weatherQuery) (*data.CurrentWeatherData, *data.Trends, error, int) {
+	latitude, longitude, lang := query.Latitude, query.Longitude, query.Lang
+
+	requestStr := fmt.Sprintf("https://api.openweathermap.org/data/2.5/weather?lat=%v&lon=%v&appid=%v&units=metric&lang=%v",
+		latitude, longitude, openWeatherApiKey, lang)
 
 	response, err := http.Get(requestStr)
 
@@ -160,16 +436,94 @@ func getCurrentWeather(request *http.Request) (*data.CurrentWeatherData, *data.S
 		return nil, nil, fmt.Errorf("Error unmarshalling json response body"), http.StatusInternalServerError
 	}
 
-	currentWeatherDate.Units = units
+	// The weather can't be simplified (or recorded) without its conditions
+	if len(currentWeatherDate.Weather) == 0 {
+		return nil, nil, errors.New("No weather data returned by Open Weather API"), http.StatusInternalServerError
+	}
+
+	currentWeatherDate.Lang = lang
+	recordObservation(query, currentWeatherDate)
+	trends := trackObservation(query, &currentWeatherDate)
+	return &currentWeatherDate, trends, nil, http.StatusOK
+}
+
+// convertForQuery converts weather and its trends in Open Weather's metric units to the query's
+// units and simplifies it.  It's used for both fetched and stored observations.  Stored observations
+// have no trends.
+func convertForQuery(query *weatherQuery, currentWeatherDate *data.CurrentWeatherData, trends *data.Trends) (*data.SimplifiedWeather, error) {
+	currentWeatherDate.Comfort = query.Comfort
 	currentWeatherDate.DataCollectionTime = unixEpochTimeToString(int64(currentWeatherDate.Dt))
-	simplifiedData := data.SimplifyCurrentWeatherData(&currentWeatherDate)
+	simplifiedData := data.SimplifyCurrentWeatherData(currentWeatherDate, query.Units)
+
+	if simplifiedData != nil && trends != nil {
+		simplifiedData.Trends = trends.ConvertFromMetric(query.Units)
 
-	return &currentWeatherDate, simplifiedData, nil, http.StatusOK
+		if summary := simplifiedData.Trends.Summary(query.Lang, query.Units.Temperature); query.TrendSummary && summary != "" {
+			simplifiedData.Summary += "  " + summary
+		}
+	}
+
+	if err := data.RenderSummary(query.SummaryStyle, currentWeatherDate, simplifiedData); err != nil {
+		return nil, err
+	}
+
+	return simplifiedData, nil
+}
+
+// The maximum number of Open Weather calls made at the same time for one request
+const maxConcurrentFetches = 8
+
+// fetchResult is the result of fetching the weather for one weatherQuery
+type fetchResult struct {
+	Data       *data.CurrentWeatherData
+	Simplified *data.SimplifiedWeather
+	Err        error
+	StatusCode int
+}
+
+// fetchCurrentWeatherConcurrently fetches the weather for each query, at most
+// maxConcurrentFetches at a time.  The results are in the same order as the queries.
+func fetchCurrentWeatherConcurrently(queries []*weatherQuery) []fetchResult {
+	results := make([]fetchResult, len(queries))
+	semaphore := make(chan struct{}, maxConcurrentFetches)
+	var waitGroup sync.WaitGroup
+
+	for inx, query := range queries {
+		waitGroup.Add(1)
+		semaphore <- struct{}{}
+
+		go func(inx int, query *weatherQuery) {
+			defer func() {
+				<-semaphore
+				waitGroup.Done()
+			}()
+
+			result := &results[inx]
+			result.Data, result.Simplified, result.Err, result.StatusCode = fetchCurrentWeather(query)
+		}(inx, query)
+	}
+
+	waitGroup.Wait()
+	return results
+}
+
+// displayedWeather is what the display_current_weather page is rendered over
+type displayedWeather struct {
+	*data.SimplifiedWeather
+	Recommendations *data.Recommendations
 }
 
 func displayCurrentWeatherForm(requestNum uint64, writer http.ResponseWriter, request *http.Request) {
+	templates := pageTemplates(writer, request)
+	selection, err, statusCode := getFieldSelection(request)
 
-	_, simplifiedData, err, statusCode := getCurrentWeather(request)
+	if err != nil {
+		logging.LogHTTPError(requestNum, err.Error(), statusCode)
+		templates.ExecuteTemplate(writer, "display_current_weather_error", err.Error())
+		return
+	}
+
+	query, err, statusCode := parseWeatherQuery(request)
 
 	if err != nil {
 		logging.LogHTTPError(requestNum, err.Error(), statusCode)
@@ -177,7 +531,20 @@ func displayCurrentWeatherForm(requestNum uint64, writer http.ResponseWriter, re
 		return
 	}
 
-	templates.ExecuteTemplate(writer, "display_current_weather", simplifiedData)
+	simplifiedData, recommendations, err, statusCode := fetchRecommendations(query)
+
+	if err != nil {
+		logging.LogHTTPError(requestNum, err.Error(), statusCode)
+		templates.ExecuteTemplate(writer, "display_current_weather_error", err.Error())
+		return
+	}
+
+	if !selection.IsDefault() {
+		templates.ExecuteTemplate(writer, "display_current_weather_fields", selection.Apply(simplifiedData))
+		return
+	}
+
+	templates.ExecuteTemplate(writer, "display_current_weather", displayedWeather{simplifiedData, recommendations})
 }
 
 func logRequest(h func(requestNum uint64, w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
@@ -190,6 +557,11 @@ func logRequest(h func(requestNum uint64, w http.ResponseWriter, r *http.Request
 }
 
 func main() {
+	// The import command records archived observations in a history file instead of starting the server
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		os.Exit(runImport(os.Args[2:]))
+	}
+
 	currentWorkingDir, err := os.Getwd()
 	if err != nil {
 		fmt.Printf("Error getting current working directory: %v\n", err)
@@ -202,9 +574,33 @@ func main() {
 		logDir        = flag.String("logDir", ".", "Log directory")
 		logFilePrefix = flag.String("logFilePrefix", "weatherserver", "The prefix for log files")
 		port          = flag.String("port", "8000", "The port on which to run the server")
+		grpcPort      = flag.String("grpcPort", "", "The port on which to run the gRPC server (empty=disabled)")
 		apiKey        = flag.String("apiKey", "", "The key to use for API calls to Open Weather")
 		//coldCoolWarmC = flag.String("coldCoolWarmC", "4.5,15.5,25", "Comma separated list of cold/cool/warm temperatures in Celsius")
+		streamRefresh = flag.Int("streamRefreshSeconds", int(watchRefreshInterval/time.Second), "How often streamed locations are refreshed from Open Weather")
+		wsMaxSubs     = flag.Int("wsMaxSubscriptions", maxWebSocketSubscriptions, "The maximum number of locations one WebSocket connection can subscribe to")
+		alertSecret   = flag.String("alertWebhookSecret", "", "The secret used to sign alert webhooks for rules without their own secret")
+		alertAttempts = flag.Int("alertMaxAttempts", alertMaxAttempts, "How many times an alert webhook is attempted before giving up")
+		alertRules    = flag.Int("alertMaxRules", alertMaxRules, "The maximum number of alert rules that can be registered at the same time")
+		alertTok      = flag.String("alertToken", "", "The token operators send as \"Authorization: Bearer <token>\" to use /api/alerts (empty=none)")
+		alertHosts    = flag.String("alertWebhookHosts", "", "Comma separated list of the hosts alert webhooks can be sent to (empty=any host)")
+		alertPrivate  = flag.Bool("alertAllowPrivateWebhooks", false, "Allow alert webhooks to loopback, link-local, and private addresses")
+		exporterLocs  = flag.String("exporterLocations", "", "Semicolon separated list of name=latitude,longitude locations published at /metrics")
+		exporterSecs  = flag.Int("exporterRefreshSeconds", 300, "How often the exporter locations are refreshed from Open Weather")
+		graphqlDepth  = flag.Int("graphqlMaxDepth", maxGraphqlDepth, "The maximum nesting depth of a GraphQL query")
+		graphqlCalls  = flag.Int("graphqlMaxLocations", maxGraphqlLocations, "The maximum number of locations a GraphQL query can request")
+		compareLocs   = flag.Int("compareMaxLocations", maxCompareLocations, "The maximum number of locations /api/compare can compare")
+		gridCells     = flag.Int("gridMaxCells", maxGridCells, "The maximum number of points an /api/grid request can have")
+		routeSegments = flag.Int("routeMaxSegments", maxRouteSegments, "The maximum number of segments an /api/route request can be split into")
+		conditionFile = flag.String("conditionRules", "", "Json file of condition rules that tag the weather (e.g. muggy) (empty=built in rules)")
+		recommendFile = flag.String("recommendationRules", "", "Json file of the activity and clothing rules used by /api/recommendations (empty=built in rules)")
+		compactMins   = flag.Int("historyCompactMinutes", 60, "How often history older than its retention is removed")
+		historyKeep   = flag.String("historyRetention", "raw=7d,hour=90d", "Comma separated list of how long each history tier (raw, hour, day, or week) is kept (missing tiers are kept forever)")
+		historyFile   = flag.String("historyFile", "", "The file the fetched observations are recorded in for /api/history (empty=disabled)")
+		summaryDir    = flag.String("summaryTemplateDir", "", "Directory of *.tmpl summary templates selectable with summaryStyle (empty=none)")
 		coldCoolWarmF = flag.String("coldCoolWarmF", "40,60,77", "Comma separated list of cold/cool/warm temperatures in Fahrenheit")
+		tempScale     = flag.String("subjectiveTempScale", "", "Comma separated [feelsLike,]label:max,...,label subjective temperature bands in Celsius unless followed by F or K (replaces coldCoolWarmF)")
+		comfortProfs  = flag.String("comfortProfiles", "", "Semicolon separated list of name=cold,cool,warm or name=label:max,...,label comfort profiles selectable with comfortProfile (e.g. phoenix=60,75,95F)")
 	)
 
 	flag.Parse()
@@ -224,24 +620,66 @@ func main() {
 		os.Exit(1)
 	}
 
-	if *coldCoolWarmF == "" {
+	// -subjectiveTempScale replaces -coldCoolWarmF when it's given
+	defaultScale, scaleUnit := *coldCoolWarmF, data.Fahrenheit
+
+	if *tempScale != "" {
+		defaultScale, scaleUnit = *tempScale, data.Celsius
+	}
+
+	if defaultScale == "" {
 		logging.LogError(0, "No values specified for cold, cool, warm")
 		os.Exit(1)
 	}
 
-	cold, cool, warm, err := data.ParseColdCoolWarmValues(*coldCoolWarmF)
+	defaultComfort, err := data.ParseComfortTemperatures(data.DefaultComfortProfile, defaultScale, scaleUnit)
 	if err != nil {
 		logging.LogError(0, err.Error())
 		os.Exit(1)
 	}
 
-	err = data.SetColdCoolWarmCelsius(data.FahrenheitToCelsius(cold),
-		data.FahrenheitToCelsius(cool),
-		data.FahrenheitToCelsius(warm))
+	data.SetDefaultComfortProfile(defaultComfort)
 
-	if err != nil {
-		logging.LogError(0, err.Error())
-		os.Exit(1)
+	if *comfortProfs != "" {
+		err = data.ParseComfortProfiles(*comfortProfs)
+
+		if err != nil {
+			logging.LogError(0, err.Error())
+			os.Exit(1)
+		}
+
+		logging.LogInfo(0, fmt.Sprintf("Comfort profiles: %v", strings.Join(data.ComfortProfileNames(), ", ")))
+	}
+
+	if *conditionFile != "" {
+		err = data.LoadConditionRules(*conditionFile)
+
+		if err != nil {
+			logging.LogError(0, err.Error())
+			os.Exit(1)
+		}
+	}
+
+	if *recommendFile != "" {
+		err = data.LoadRecommendationRules(*recommendFile)
+
+		if err != nil {
+			logging.LogError(0, err.Error())
+			os.Exit(1)
+		}
+	}
+
+	// The subjective temperature scales and condition rules must be set first because the
+	// summary templates are checked by rendering them over sample weather
+	if *summaryDir != "" {
+		err = data.LoadSummaryTemplates(*summaryDir)
+
+		if err != nil {
+			logging.LogError(0, err.Error())
+			os.Exit(1)
+		}
+
+		logging.LogInfo(0, fmt.Sprintf("Summary styles: %v", strings.Join(data.SummaryStyles(), ", ")))
 	}
 
 	if *apiKey == "" {
@@ -251,6 +689,108 @@ func main() {
 
 	openWeatherApiKey = *apiKey
 
+	if *graphqlDepth < 1 || *graphqlCalls < 1 {
+		logging.LogError(0, "graphqlMaxDepth and graphqlMaxLocations must be at least 1")
+		os.Exit(1)
+	}
+
+	if *streamRefresh < 1 {
+		logging.LogError(0, "streamRefreshSeconds must be at least 1")
+		os.Exit(1)
+	}
+
+	watchRefreshInterval = time.Duration(*streamRefresh) * time.Second
+
+	if *wsMaxSubs < 1 {
+		logging.LogError(0, "wsMaxSubscriptions must be at least 1")
+		os.Exit(1)
+	}
+
+	maxWebSocketSubscriptions = *wsMaxSubs
+
+	if *alertAttempts < 1 {
+		logging.LogError(0, "alertMaxAttempts must be at least 1")
+		os.Exit(1)
+	}
+
+	alertMaxAttempts = *alertAttempts
+	alertWebhookSecret = *alertSecret
+
+	if *alertRules < 1 {
+		logging.LogError(0, "alertMaxRules must be at least 1")
+		os.Exit(1)
+	}
+
+	alertMaxRules = *alertRules
+	alertToken = *alertTok
+	alertAllowPrivateWebhooks = *alertPrivate
+
+	for _, host := range strings.Split(*alertHosts, ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			alertWebhookHosts[host] = true
+		}
+	}
+	exporterLocations, err := parseExporterLocations(*exporterLocs)
+
+	if err != nil {
+		logging.LogError(0, err.Error())
+		os.Exit(1)
+	}
+
+	if *exporterSecs < 1 {
+		logging.LogError(0, "exporterRefreshSeconds must be at least 1")
+		os.Exit(1)
+	}
+
+	maxGraphqlDepth = *graphqlDepth
+	maxGraphqlLocations = *graphqlCalls
+
+	retention, err := history.ParseRetention(*historyKeep)
+
+	if err != nil {
+		logging.LogError(0, err.Error())
+		os.Exit(1)
+	}
+
+	if *compactMins < 1 {
+		logging.LogError(0, "historyCompactMinutes must be at least 1")
+		os.Exit(1)
+	}
+
+	if *historyFile != "" {
+		historyStore, err = history.Open(*historyFile)
+
+		if err != nil {
+			logging.LogError(0, err.Error())
+			os.Exit(1)
+		}
+
+		defer historyStore.Close()
+		logging.LogInfo(0, fmt.Sprintf("Recording observations in %v (retention %v)", *historyFile, retention))
+		startHistoryCompactor(retention, time.Duration(*compactMins)*time.Minute)
+	}
+
+	if *compareLocs < 2 {
+		logging.LogError(0, "compareMaxLocations must be at least 2")
+		os.Exit(1)
+	}
+
+	maxCompareLocations = *compareLocs
+
+	if *gridCells < 1 {
+		logging.LogError(0, "gridMaxCells must be at least 1")
+		os.Exit(1)
+	}
+
+	maxGridCells = *gridCells
+
+	if *routeSegments < 1 {
+		logging.LogError(0, "routeMaxSegments must be at least 1")
+		os.Exit(1)
+	}
+
+	maxRouteSegments = *routeSegments
+
 	if *maxProcessors == 0 {
 		runtime.GOMAXPROCS(runtime.NumCPU())
 		logging.LogInfo(0, fmt.Sprintf("MAX_PROCS=%v", runtime.NumCPU()))
@@ -261,11 +801,34 @@ func main() {
 
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("/", logRequest(versionHandler))
-	mux.HandleFunc("/version", logRequest((versionHandler)))
-	mux.HandleFunc("/getcurrentweather.html", logRequest((getCurrentWeatherForm)))
-	mux.HandleFunc("/displaycurrentweather.html", logRequest((displayCurrentWeatherForm)))
-	mux.HandleFunc("/api/currentweather", logRequest((apiGetCurrentWeather)))
+	routes := apiRoutes()
+
+	err = validateApiRoutes(routes)
+
+	if err != nil {
+		logging.LogError(0, err.Error())
+		os.Exit(1)
+	}
+
+	for _, route := range routes {
+		mux.HandleFunc(route.Path, logRequest(route.Handler))
+	}
+
+	if *grpcPort != "" {
+		err = startGrpcServer(*grpcPort)
+
+		if err != nil {
+			logging.LogError(0, fmt.Sprintf("Error starting gRPC server: %v", err))
+			os.Exit(1)
+		}
+
+		logging.LogInfo(0, fmt.Sprintf("Started gRPC server on port %v", *grpcPort))
+	}
+
+	if len(exporterLocations) > 0 {
+		exporter.start(exporterLocations, time.Duration(*exporterSecs)*time.Second)
+		logging.LogInfo(0, fmt.Sprintf("Exporting weather for %v locations at /metrics", len(exporterLocations)))
+	}
 
 	startMsg := fmt.Sprintf("Starting server on port %v", *port)
 	logging.LogInfo(0, startMsg)
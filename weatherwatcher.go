@@ -0,0 +1,218 @@
+package main
+
+import (
+	"current-weather-server/data"
+	"current-weather-server/logging"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// How often the weather for a watched location is fetched from Open Weather
+var watchRefreshInterval = 60 * time.Second
+
+// weatherUpdate is sent to the subscribers of a location when a new
+// observation (a new upstream Dt) is fetched or when fetching fails.
+// Data and Simplified are in the units of the subscriber's query.
+type weatherUpdate struct {
+	Data       *data.CurrentWeatherData
+	Simplified *data.SimplifiedWeather
+	Err        error
+}
+
+// metricUpdate is the latest fetch of a location in one language before it's
+// converted for each subscriber.  Open Weather translates the descriptions, so
+// each language the subscribers use is fetched.
+type metricUpdate struct {
+	data   *data.CurrentWeatherData // in metric units
+	trends *data.Trends
+	err    error
+}
+
+// forQuery converts the update to the units and options of a subscriber's query
+func (update *metricUpdate) forQuery(query *weatherQuery) *weatherUpdate {
+	if update.err != nil {
+		return &weatherUpdate{Err: update.err}
+	}
+
+	// Converting changes the observation so each subscriber gets its own copy
+	converted := *update.data
+	simplifiedData, err := convertForQuery(query, &converted, update.trends)
+
+	if err != nil {
+		return &weatherUpdate{Err: err}
+	}
+
+	return &weatherUpdate{Data: &converted, Simplified: simplifiedData}
+}
+
+// watchedLocation identifies a locationWatcher
+type watchedLocation struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// locationWatcher fetches the weather for one location on a schedule
+// and sends every new observation to all of its subscribers.
+type locationWatcher struct {
+	location    watchedLocation
+	subscribers map[chan *weatherUpdate]*weatherQuery // each subscriber's options
+	latest      map[string]*metricUpdate              // by language
+	wake        chan struct{}                         // refreshes right away for a new language
+	stop        chan struct{}
+}
+
+// weatherWatcherHub shares one locationWatcher (and so one refresh loop)
+// between all subscribers to the same location, whatever their options.
+type weatherWatcherHub struct {
+	mutex    sync.Mutex
+	watchers map[watchedLocation]*locationWatcher
+}
+
+var weatherWatchers = &weatherWatcherHub{watchers: map[watchedLocation]*locationWatcher{}}
+
+// subscribe returns a channel that receives the updates for the location
+// and a function that must be called to stop receiving them.
+// The most recent update, if there is one, is delivered right away.
+func (hub *weatherWatcherHub) subscribe(query weatherQuery) (<-chan *weatherUpdate, func()) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	location := watchedLocation{Latitude: query.Latitude, Longitude: query.Longitude}
+	watcher, found := hub.watchers[location]
+
+	if !found {
+		watcher = &locationWatcher{
+			location:    location,
+			subscribers: map[chan *weatherUpdate]*weatherQuery{},
+			latest:      map[string]*metricUpdate{},
+			wake:        make(chan struct{}, 1),
+			stop:        make(chan struct{}),
+		}
+		hub.watchers[location] = watcher
+		logging.LogInfo(0, fmt.Sprintf("Watching weather at latitude %v, longitude %v",
+			location.Latitude, location.Longitude))
+		go hub.refreshLoop(watcher)
+	}
+
+	updates := make(chan *weatherUpdate, 1)
+	watcher.subscribers[updates] = &query
+
+	if latest, fetched := watcher.latest[query.Lang]; fetched {
+		updates <- latest.forQuery(&query)
+	} else if found {
+		// The watcher's loop has started, so a new language is fetched without waiting for the next refresh
+		select {
+		case watcher.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() { hub.unsubscribe(watcher, updates) })
+	}
+
+	return updates, unsubscribe
+}
+
+func (hub *weatherWatcherHub) unsubscribe(watcher *locationWatcher, updates chan *weatherUpdate) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	delete(watcher.subscribers, updates)
+
+	if len(watcher.subscribers) == 0 {
+		close(watcher.stop)
+		delete(hub.watchers, watcher.location)
+		logging.LogInfo(0, fmt.Sprintf("Stopped watching weather at latitude %v, longitude %v",
+			watcher.location.Latitude, watcher.location.Longitude))
+	}
+}
+
+func (hub *weatherWatcherHub) refreshLoop(watcher *locationWatcher) {
+	ticker := time.NewTicker(watchRefreshInterval)
+	defer ticker.Stop()
+
+	newLangsOnly := false
+
+	for {
+		hub.refresh(watcher, newLangsOnly)
+
+		select {
+		case <-watcher.stop:
+			return
+		case <-ticker.C:
+			newLangsOnly = false
+		case <-watcher.wake:
+			newLangsOnly = true
+		}
+	}
+}
+
+// refresh fetches the weather once in each language the subscribers use (or only the ones that
+// haven't been fetched yet) and publishes it, converted for each subscriber, if the observation
+// changed.  Errors are only published when the previous update wasn't the same error.
+func (hub *weatherWatcherHub) refresh(watcher *locationWatcher, newLangsOnly bool) {
+	hub.mutex.Lock()
+	langs := map[string]bool{}
+
+	for _, query := range watcher.subscribers {
+		langs[query.Lang] = true
+	}
+
+	// Languages nobody uses anymore are no longer fetched
+	for lang := range watcher.latest {
+		if !langs[lang] {
+			delete(watcher.latest, lang)
+		} else if newLangsOnly {
+			delete(langs, lang)
+		}
+	}
+
+	hub.mutex.Unlock()
+
+	for lang := range langs {
+		query := &weatherQuery{Latitude: watcher.location.Latitude, Longitude: watcher.location.Longitude, Lang: lang}
+		currentWeatherData, trends, err, _ := fetchMetricWeather(query)
+		hub.publish(watcher, lang, &metricUpdate{data: currentWeatherData, trends: trends, err: err})
+	}
+}
+
+// publish sends an update in a language to the subscribers using that language
+func (hub *weatherWatcherHub) publish(watcher *locationWatcher, lang string, update *metricUpdate) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	previous := watcher.latest[lang]
+
+	if update.err != nil {
+		logging.LogError(0, fmt.Sprintf("Error refreshing weather at latitude %v, longitude %v: %v",
+			watcher.location.Latitude, watcher.location.Longitude, update.err))
+
+		if previous != nil && previous.err != nil && previous.err.Error() == update.err.Error() {
+			return
+		}
+	} else if previous != nil && previous.err == nil && previous.data.Dt == update.data.Dt {
+		return
+	}
+
+	watcher.latest[lang] = update
+
+	for subscriber, query := range watcher.subscribers {
+		if query.Lang == lang {
+			publishUpdate(subscriber, update.forQuery(query))
+		}
+	}
+}
+
+// publishUpdate replaces any update the subscriber hasn't read yet so
+// a slow subscriber never blocks the refresh loop.
+func publishUpdate(subscriber chan *weatherUpdate, update *weatherUpdate) {
+	select {
+	case <-subscriber:
+	default:
+	}
+
+	subscriber <- update
+}
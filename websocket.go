@@ -0,0 +1,283 @@
+package main
+
+import (
+	"current-weather-server/data"
+	"current-weather-server/logging"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// The maximum number of locations one WebSocket connection can subscribe to
+var maxWebSocketSubscriptions = 10
+
+const (
+	// How long to wait for a pong before the connection is considered dead
+	webSocketPongWait = 60 * time.Second
+	// How often pings are sent.  Must be less than webSocketPongWait.
+	webSocketPingInterval = webSocketPongWait * 9 / 10
+	// How long a write to the client may take
+	webSocketWriteWait = 10 * time.Second
+	// The largest message accepted from a client
+	webSocketMaxMessageBytes = 4096
+)
+
+var webSocketUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// webSocketClientMessage is a subscribe or unsubscribe message sent by the client.
+// For subscribe messages Latitude and Longitude are required and the query options, Fields
+// and Compact have the same meaning as the /api/currentweather parameters.
+// Lang defaults to the language picked from the Accept-Language header of the upgrade request.
+// Id names the subscription.  It defaults to
+// "latitude|longitude|units|lang|summaryStyle|comfortProfile|coldCoolWarm" where units is the
+// comma separated unit of each quantity.  The values can contain commas but not '|'.
+type webSocketClientMessage struct {
+	Type      string   `json:"type"`
+	Id        string   `json:"id"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	queryOptions
+	Fields  string `json:"fields"`
+	Compact bool   `json:"compact"`
+}
+
+// webSocketServerMessage is an update or error message sent to the client
+type webSocketServerMessage struct {
+	Type    string      `json:"type"`
+	Id      string      `json:"id,omitempty"`
+	Weather interface{} `json:"weather,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// webSocketConnection holds the subscriptions of one client connection
+type webSocketConnection struct {
+	requestNum    uint64
+	lang          string // the default language of the subscriptions
+	conn          *websocket.Conn
+	outgoing      chan *webSocketServerMessage
+	done          chan struct{} // closed when the read loop ends
+	writerDone    chan struct{} // closed when the write loop ends
+	mutex         sync.Mutex
+	subscriptions map[string]func()
+}
+
+// apiWebSocket lets a client subscribe and unsubscribe to the weather at many locations over one connection
+func apiWebSocket(requestNum uint64, writer http.ResponseWriter, request *http.Request) {
+	conn, err := webSocketUpgrader.Upgrade(writer, request, nil)
+
+	if err != nil {
+		// The upgrader has already replied to the client
+		logging.LogError(requestNum, fmt.Sprintf("Error upgrading to WebSocket: %v", err))
+		return
+	}
+
+	connection := &webSocketConnection{
+		requestNum:    requestNum,
+		lang:          acceptLanguage(request.Header.Get("Accept-Language")),
+		conn:          conn,
+		outgoing:      make(chan *webSocketServerMessage, 16),
+		done:          make(chan struct{}),
+		writerDone:    make(chan struct{}),
+		subscriptions: map[string]func(){},
+	}
+
+	go connection.writeLoop()
+	connection.readLoop()
+
+	close(connection.done)
+	connection.unsubscribeAll()
+	conn.Close()
+	logging.LogInfo(requestNum, "WebSocket closed")
+}
+
+func (c *webSocketConnection) readLoop() {
+	c.conn.SetReadLimit(webSocketMaxMessageBytes)
+	c.conn.SetReadDeadline(time.Now().Add(webSocketPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(webSocketPongWait))
+	})
+
+	for {
+		_, messageBytes, err := c.conn.ReadMessage()
+
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				logging.LogError(c.requestNum, fmt.Sprintf("Error reading WebSocket message: %v", err))
+			}
+			return
+		}
+
+		var message webSocketClientMessage
+
+		if err := json.Unmarshal(messageBytes, &message); err != nil {
+			c.sendError("", fmt.Errorf("Invalid message: %v", err))
+			continue
+		}
+
+		switch message.Type {
+		case "subscribe":
+			err = c.subscribe(&message)
+		case "unsubscribe":
+			err = c.unsubscribe(message.Id)
+		default:
+			err = fmt.Errorf("Invalid message type: %v", message.Type)
+		}
+
+		if err != nil {
+			c.sendError(message.Id, err)
+		}
+	}
+}
+
+func (c *webSocketConnection) writeLoop() {
+	ticker := time.NewTicker(webSocketPingInterval)
+	defer ticker.Stop()
+	defer close(c.writerDone)
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case message := <-c.outgoing:
+			c.conn.SetWriteDeadline(time.Now().Add(webSocketWriteWait))
+
+			if err := c.conn.WriteJSON(message); err != nil {
+				logging.LogError(c.requestNum, fmt.Sprintf("Error writing WebSocket message: %v", err))
+				c.conn.Close()
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(webSocketWriteWait))
+
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// send queues a message for the write loop.  It gives up if the connection closes.
+func (c *webSocketConnection) send(message *webSocketServerMessage) {
+	select {
+	case c.outgoing <- message:
+	case <-c.done:
+	case <-c.writerDone:
+	}
+}
+
+func (c *webSocketConnection) sendError(id string, err error) {
+	logging.LogError(c.requestNum, fmt.Sprintf("WebSocket subscription %v: %v", id, err))
+	c.send(&webSocketServerMessage{Type: "error", Id: id, Error: err.Error()})
+}
+
+func (c *webSocketConnection) subscribe(message *webSocketClientMessage) error {
+	if message.Longitude == nil {
+		return errors.New("missing longitude")
+	}
+
+	if message.Latitude == nil {
+		return errors.New("missing latitude")
+	}
+
+	if message.Lang == "" {
+		message.Lang = c.lang
+	}
+
+	query, err, _ := newWeatherQuery(*message.Latitude, *message.Longitude, message.queryOptions)
+
+	if err != nil {
+		return err
+	}
+
+	selection, err := data.ParseFieldSelection(message.Fields, message.Compact)
+
+	if err != nil {
+		return err
+	}
+
+	id := message.Id
+
+	if id == "" {
+		id = fmt.Sprintf("%v|%v|%v|%v|%v|%v|%v", query.Latitude, query.Longitude, query.Units, query.Lang, query.SummaryStyle,
+			query.Comfort.Name, message.ColdCoolWarm)
+		message.Id = id
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, found := c.subscriptions[id]; found {
+		return fmt.Errorf("Already subscribed: %v", id)
+	}
+
+	if len(c.subscriptions) >= maxWebSocketSubscriptions {
+		return fmt.Errorf("Too many subscriptions (maximum is %v)", maxWebSocketSubscriptions)
+	}
+
+	updates, unsubscribeUpdates := weatherWatchers.subscribe(*query)
+	stop := make(chan struct{})
+
+	c.subscriptions[id] = func() {
+		close(stop)
+		unsubscribeUpdates()
+	}
+
+	go c.forwardUpdates(id, selection, updates, stop)
+	logging.LogInfo(c.requestNum, fmt.Sprintf("WebSocket subscribed %v", id))
+	return nil
+}
+
+// forwardUpdates sends the weather updates of one subscription to the client until it's unsubscribed
+func (c *webSocketConnection) forwardUpdates(id string, selection *data.FieldSelection,
+	updates <-chan *weatherUpdate, stop chan struct{}) {
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-c.done:
+			return
+		case update := <-updates:
+			if update.Err != nil {
+				c.send(&webSocketServerMessage{Type: "error", Id: id, Error: update.Err.Error()})
+			} else {
+				c.send(&webSocketServerMessage{Type: "update", Id: id, Weather: selection.Select(update.Simplified)})
+			}
+		}
+	}
+}
+
+func (c *webSocketConnection) unsubscribe(id string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	unsubscribe, found := c.subscriptions[id]
+
+	if !found {
+		return fmt.Errorf("Not subscribed: %v", id)
+	}
+
+	delete(c.subscriptions, id)
+	unsubscribe()
+	logging.LogInfo(c.requestNum, fmt.Sprintf("WebSocket unsubscribed %v", id))
+	return nil
+}
+
+func (c *webSocketConnection) unsubscribeAll() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for id, unsubscribe := range c.subscriptions {
+		delete(c.subscriptions, id)
+		unsubscribe()
+	}
+}